@@ -7,12 +7,42 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// backupFormat is the output format of backup, whose default differs from the
+// one of transfer
+var backupFormat string
+
 // backupCmd represents the backup command
 var backupCmd = &cobra.Command{
 	Use:   "backup",
 	Short: "Backup Elasticsearch data to file",
 	Long: `Backup Elasticsearch data, mappings, or settings to a file.
-This is a convenient wrapper around the transfer command for backup operations.`,
+This is a convenient wrapper around the transfer command for backup operations.
+
+With --all, every index of the cluster is backed up into the output directory,
+one subdirectory per index holding its mapping, settings, aliases and data.
+
+With --type=all, the settings, mappings, aliases and data of the input indices
+are bundled into the single output file.
+
+With --includePipelines, the ingest pipelines named by index.default_pipeline
+and index.final_pipeline are written next to --type=settings, index and all
+backups (name.pipelines.json), or to pipelines.json with --all.
+
+With --opensearch, --type=lifecycle backs up the ISM policies of an OpenSearch
+cluster.
+
+Backup files are compressed with --compress=gzip, or when their name ends in
+.gz; with --all, the data files are compressed and named *.gz. restore detects
+compressed files by their content.
+
+--fileSize and --fileDocs split the data file into numbered parts, e.g.
+logs.part-0001.ndjson, listed in a logs.ndjson.parts.json manifest. restore
+takes the manifest, or a glob matching the parts, and restores the parts
+concurrently.
+
+An output of "-" writes the data to standard output, for piping into other
+tools; progress and logs then go to standard error, and no mapping file is
+written alongside.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if input == "" {
 			return fmt.Errorf("input is required")
@@ -21,17 +51,47 @@ This is a convenient wrapper around the transfer command for backup operations.`
 			return fmt.Errorf("output is required")
 		}
 
+		size, err := parseFileSize(fileSize)
+		if err != nil {
+			return err
+		}
+
 		config := transfer.Config{
 			Input:       input,
 			Output:      output,
 			Type:        dataType,
 			Limit:       limit,
 			Concurrency: concurrency,
-			Format:      format,
+			Format:      backupFormat,
+			Pretty:      pretty,
 			ScrollSize:  scrollSize,
 			Verbose:     verbose,
 			Username:    username,
 			Password:    password,
+
+			Fields:         fields,
+			ArraySeparator: arraySeparator,
+
+			Compress:         compress,
+			CompressionLevel: compressionLevel,
+
+			FileSize: size,
+			FileDocs: fileDocs,
+
+			Version: rootCmd.Version,
+
+			All:              all,
+			Include:          include,
+			Exclude:          exclude,
+			IncludeSystem:    includeSystem,
+			IncludePipelines: includePipelines,
+			OpenSearch:       opensearch,
+		}
+		if cmd.Flags().Changed("shards") {
+			config.Shards = &shards
+		}
+		if cmd.Flags().Changed("replicas") {
+			config.Replicas = &replicas
 		}
 
 		return transfer.Run(config)
@@ -43,14 +103,29 @@ func init() {
 
 	// Backup flags (reuse the same variables from transfer command)
 	backupCmd.Flags().StringVarP(&input, "input", "i", "", "Source Elasticsearch cluster or index (required)")
-	backupCmd.Flags().StringVarP(&output, "output", "o", "", "Output file path (required)")
-	backupCmd.Flags().StringVarP(&dataType, "type", "t", "data", "Type of data to backup (data, mapping, settings)")
+	backupCmd.Flags().StringVarP(&output, "output", "o", "", "Output file path, or - for standard output (required)")
+	backupCmd.Flags().StringVarP(&dataType, "type", "t", "data", "Type of data to backup (data, mapping, settings, index, aliases, templates, pipelines, lifecycle, scripts, security, all)")
 	backupCmd.Flags().IntVarP(&limit, "limit", "l", 0, "Limit the number of records to backup (0 = no limit)")
 	backupCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 4, "Number of concurrent operations")
-	backupCmd.Flags().StringVarP(&format, "format", "f", "ndjson", "Output format (json, ndjson)")
+	backupCmd.Flags().StringVarP(&backupFormat, "format", "f", "ndjson", "Output format (json, ndjson, bulk, csv)")
+	backupCmd.Flags().BoolVar(&pretty, "pretty", false, "Indent the documents of the json format")
+	backupCmd.Flags().StringSliceVar(&fields, "fields", nil, "Columns of the csv format as dot paths into the source, e.g. _id,user.name (default: _index, _id and all mapped fields)")
+	backupCmd.Flags().StringVar(&arraySeparator, "arraySeparator", "|", "Separator joining array values in a csv cell")
+	backupCmd.Flags().StringVar(&compress, "compress", "", "Compression of output files (none, gzip) (default: gzip for names ending in .gz)")
+	backupCmd.Flags().IntVar(&compressionLevel, "compressionLevel", 6, "gzip compression level from 1 (fastest) to 9 (smallest)")
+	backupCmd.Flags().StringVar(&fileSize, "fileSize", "", "Split the output file into parts of about this size before compression, e.g. 1GB or 500MB")
+	backupCmd.Flags().IntVar(&fileDocs, "fileDocs", 0, "Split the output file into parts of this many documents (0 = no limit)")
 	backupCmd.Flags().IntVarP(&scrollSize, "scrollSize", "s", 1000, "Size of the scroll for large datasets")
 	backupCmd.Flags().StringVarP(&username, "username", "u", "", "Elasticsearch username (optional)")
 	backupCmd.Flags().StringVarP(&password, "password", "p", "", "Elasticsearch password (optional)")
+	backupCmd.Flags().IntVar(&shards, "shards", 0, "Number of shards recorded for --type=index and --type=all backups (default: source value)")
+	backupCmd.Flags().IntVar(&replicas, "replicas", 0, "Number of replicas recorded for --type=index and --type=all backups (default: source value)")
+	backupCmd.Flags().BoolVar(&all, "all", false, "Back up every index of the cluster into the output directory")
+	backupCmd.Flags().StringSliceVar(&include, "include", nil, "Index patterns to include with --all (default: all indices)")
+	backupCmd.Flags().StringSliceVar(&exclude, "exclude", nil, "Index patterns to exclude with --all")
+	backupCmd.Flags().BoolVar(&includeSystem, "includeSystem", false, "Include system (.-prefixed) indices with --all, or managed templates, pipelines and lifecycle policies")
+	backupCmd.Flags().BoolVar(&opensearch, "opensearch", false, "Back up the ISM policies of an OpenSearch cluster with --type=lifecycle")
+	backupCmd.Flags().BoolVar(&includePipelines, "includePipelines", false, "Also back up the ingest pipelines referenced by index.default_pipeline and index.final_pipeline with --all and --type=settings, index or all")
 
 	// Mark required flags
 	backupCmd.MarkFlagRequired("input")
@@ -26,7 +26,7 @@ func TestRootCommand(t *testing.T) {
 		commandNames[cmd.Name()] = true
 	}
 
-	expectedCommands := []string{"transfer", "backup", "restore"}
+	expectedCommands := []string{"transfer", "backup", "restore", "migrate"}
 	for _, cmdName := range expectedCommands {
 		if !commandNames[cmdName] {
 			t.Errorf("Expected command '%s' to be registered", cmdName)
@@ -185,9 +185,25 @@ func TestTransferCommandFlags(t *testing.T) {
 		{"limit", "l", 0, false},
 		{"concurrency", "c", 4, false},
 		{"format", "f", "json", false},
+		{"pretty", "", false, false},
+		{"fields", "", "", false},
+		{"arraySeparator", "", "|", false},
+		{"compress", "", "", false},
+		{"compressionLevel", "", 6, false},
+		{"fileSize", "", "", false},
+		{"fileDocs", "", 0, false},
 		{"scrollSize", "s", 1000, false},
 		{"username", "u", "", false},
 		{"password", "p", "", false},
+		{"outputIndexTemplate", "", "", false},
+		{"renameMap", "", "", false},
+		{"shards", "", 0, false},
+		{"replicas", "", 0, false},
+		{"includeSystem", "", false, false},
+		{"existing", "", "fail", false},
+		{"includePipelines", "", false, false},
+		{"opensearch", "", false, false},
+		{"dryRun", "", false, false},
 	}
 
 	for _, tt := range flagTests {
@@ -224,9 +240,22 @@ func TestBackupCommandFlags(t *testing.T) {
 		{"limit", "l", false},
 		{"concurrency", "c", false},
 		{"format", "f", false},
+		{"pretty", "", false},
+		{"fields", "", false},
+		{"arraySeparator", "", false},
+		{"compress", "", false},
+		{"compressionLevel", "", false},
+		{"fileSize", "", false},
+		{"fileDocs", "", false},
 		{"scrollSize", "s", false},
 		{"username", "u", false},
 		{"password", "p", false},
+		{"all", "", false},
+		{"include", "", false},
+		{"exclude", "", false},
+		{"includeSystem", "", false},
+		{"includePipelines", "", false},
+		{"opensearch", "", false},
 	}
 
 	for _, tt := range flagTests {
@@ -258,6 +287,21 @@ func TestRestoreCommandFlags(t *testing.T) {
 		{"concurrency", "c", false},
 		{"username", "u", false},
 		{"password", "p", false},
+		{"outputIndexTemplate", "", false},
+		{"renameMap", "", false},
+		{"all", "", false},
+		{"include", "", false},
+		{"exclude", "", false},
+		{"existing", "", false},
+		{"includePipelines", "", false},
+		{"opensearch", "", false},
+		{"dryRun", "", false},
+		{"inputFormat", "", false},
+		{"idField", "", false},
+		{"columnTypes", "", false},
+		{"parallel", "", false},
+		{"maxDocumentSize", "", false},
+		{"verify", "", false},
 	}
 
 	for _, tt := range flagTests {
@@ -276,6 +320,50 @@ func TestRestoreCommandFlags(t *testing.T) {
 	}
 }
 
+func TestMigrateCommandFlags(t *testing.T) {
+	// Test all migrate command flags
+	flagTests := []struct {
+		flagName     string
+		shortFlag    string
+		defaultValue string
+	}{
+		{"input", "i", ""},
+		{"output", "o", ""},
+		{"concurrency", "c", "4"},
+		{"parallel", "", "2"},
+		{"scrollSize", "s", "1000"},
+		{"username", "u", ""},
+		{"password", "p", ""},
+		{"include", "", "[]"},
+		{"exclude", "", "[]"},
+		{"includeSystem", "", "false"},
+		{"existing", "", "fail"},
+		{"outputIndexTemplate", "", ""},
+		{"renameMap", "", ""},
+		{"shards", "", "0"},
+		{"replicas", "", "0"},
+	}
+
+	for _, tt := range flagTests {
+		t.Run(tt.flagName, func(t *testing.T) {
+			flag := migrateCmd.Flag(tt.flagName)
+			if flag == nil {
+				t.Errorf("Flag '%s' not found on migrate command", tt.flagName)
+				return
+			}
+
+			if flag.Shorthand != tt.shortFlag {
+				t.Errorf("Expected shorthand for '%s' to be '%s', got '%s'",
+					tt.flagName, tt.shortFlag, flag.Shorthand)
+			}
+			if flag.DefValue != tt.defaultValue {
+				t.Errorf("Expected default value for '%s' to be '%s', got '%s'",
+					tt.flagName, tt.defaultValue, flag.DefValue)
+			}
+		})
+	}
+}
+
 func TestCommandExecutionWithMissingFlags(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -514,6 +602,22 @@ func TestFlagDefaults(t *testing.T) {
 	}
 }
 
+func TestBackupFormatDefault(t *testing.T) {
+	// backup writes ndjson by default, whatever the format of transfer
+	if got := backupCmd.Flag("format").Value.String(); got != "ndjson" {
+		t.Errorf("Expected backup format ndjson, got %s", got)
+	}
+
+	if err := transferCmd.Flags().Set("format", "csv"); err != nil {
+		t.Fatalf("Failed to set the transfer format: %v", err)
+	}
+	defer transferCmd.Flags().Set("format", "json")
+
+	if backupFormat != "ndjson" {
+		t.Errorf("Expected backup format ndjson after setting the transfer format, got %s", backupFormat)
+	}
+}
+
 func TestBackupCommandValidation(t *testing.T) {
 	// Test backup command validation logic
 	tests := []struct {
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lilmonk/elasticdump/internal/transfer"
+	"github.com/spf13/cobra"
+)
+
+var parallel int
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate a whole Elasticsearch cluster to another one",
+	Long: `Migrate a whole Elasticsearch cluster to another one in dependency order:
+templates, ingest pipelines, lifecycle policies, indices (settings and
+mappings), data, and finally aliases.
+
+Indices and data streams are selected with --include and --exclude. The data
+of --parallel indices is copied at the same time, each with --concurrency
+workers. The migration ends with a verification report comparing the document
+count of every destination index with its source, and fails if any differs.
+
+--existing decides what happens to templates, pipelines, policies and indices
+that already exist on the destination: fail before changing anything, skip
+them, or overwrite them (existing indices are deleted and recreated).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if input == "" {
+			return fmt.Errorf("input is required")
+		}
+		if output == "" {
+			return fmt.Errorf("output is required")
+		}
+
+		config := transfer.Config{
+			Input:       input,
+			Output:      output,
+			Concurrency: concurrency,
+			ScrollSize:  scrollSize,
+			Verbose:     verbose,
+			Username:    username,
+			Password:    password,
+
+			OutputIndexTemplate: outputIndexTemplate,
+			RenameMap:           renameMap,
+
+			Include:       include,
+			Exclude:       exclude,
+			IncludeSystem: includeSystem,
+			Existing:      existing,
+			Parallel:      parallel,
+		}
+		if cmd.Flags().Changed("shards") {
+			config.Shards = &shards
+		}
+		if cmd.Flags().Changed("replicas") {
+			config.Replicas = &replicas
+		}
+
+		return transfer.Migrate(config)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	// Migrate flags
+	migrateCmd.Flags().StringVarP(&input, "input", "i", "", "Source Elasticsearch cluster (required)")
+	migrateCmd.Flags().StringVarP(&output, "output", "o", "", "Destination Elasticsearch cluster (required)")
+	migrateCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 4, "Number of concurrent operations per index")
+	migrateCmd.Flags().IntVar(&parallel, "parallel", 2, "Number of indices whose data is copied at the same time")
+	migrateCmd.Flags().IntVarP(&scrollSize, "scrollSize", "s", 1000, "Size of the scroll for large datasets")
+	migrateCmd.Flags().StringVarP(&username, "username", "u", "", "Elasticsearch username (optional)")
+	migrateCmd.Flags().StringVarP(&password, "password", "p", "", "Elasticsearch password (optional)")
+	migrateCmd.Flags().StringSliceVar(&include, "include", nil, "Index and data stream patterns to migrate (default: all)")
+	migrateCmd.Flags().StringSliceVar(&exclude, "exclude", nil, "Index and data stream patterns to leave out")
+	migrateCmd.Flags().BoolVar(&includeSystem, "includeSystem", false, "Include system (.-prefixed or managed) indices, templates, pipelines and lifecycle policies")
+	migrateCmd.Flags().StringVar(&existing, "existing", "fail", "What to do with templates, pipelines, lifecycle policies or indices that already exist on the destination (fail, skip, overwrite)")
+	migrateCmd.Flags().StringVar(&outputIndexTemplate, "outputIndexTemplate", "", "Destination index name template, e.g. {index}-v2")
+	migrateCmd.Flags().StringVar(&renameMap, "renameMap", "", "JSON file mapping source to destination index names, e.g. {\"prod-*\": \"staging-*\"}")
+	migrateCmd.Flags().IntVar(&shards, "shards", 0, "Number of shards for the destination indices (default: source value)")
+	migrateCmd.Flags().IntVar(&replicas, "replicas", 0, "Number of replicas for the destination indices (default: source value)")
+
+	// Mark required flags
+	migrateCmd.MarkFlagRequired("input")
+	migrateCmd.MarkFlagRequired("output")
+}
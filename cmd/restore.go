@@ -3,16 +3,68 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/lilmonk/elasticdump/internal/backup"
 	"github.com/lilmonk/elasticdump/internal/restore"
 	"github.com/spf13/cobra"
 )
 
+var (
+	inputFormat string
+	idField     string
+	columnTypes []string
+
+	maxDocumentSize string
+	verify          bool
+)
+
 // restoreCmd represents the restore command
 var restoreCmd = &cobra.Command{
 	Use:   "restore",
 	Short: "Restore Elasticsearch data from file",
 	Long: `Restore Elasticsearch data, mappings, or settings from a backup file.
-This command reads data from a file and imports it into an Elasticsearch cluster.`,
+This command reads data from a file and imports it into an Elasticsearch cluster.
+
+Data files in the json, ndjson and bulk formats are recognized automatically,
+as are gzip-compressed backup files.
+The actions of a bulk file (index, create, update and delete) are replayed
+through the bulk API.
+
+With --inputFormat=csv (the default for .csv files), the header row names the
+document fields and --columnTypes coerces columns, e.g. age:integer. With
+--inputFormat=jsonl, each line is a document body. --idField takes the
+document ids from a column or field; otherwise Elasticsearch generates them.
+
+The input may also be the parts manifest written with --fileSize or --fileDocs
+(name.parts.json), a directory of data files, or a glob matching several data
+files such as "logs.part-*.ndjson". --parallel files are restored at the same
+time, and each file is reported as restored or failed. An input of "-" reads
+a single data file from standard input.
+
+Documents of any size are read up to --maxDocumentSize; a bigger document is
+reported and skipped without stopping the restore.
+
+When the backup has a manifest (name.manifest.json, or manifest.json with
+--all), the files read are checked against the recorded checksums as they are
+restored, and the restored document count against the recorded one; mismatches
+are reported as warnings.
+--verify=false skips the checksums.
+
+With --all, a directory backup written by "backup --all" is restored: every
+index is created with its settings and mappings, its data is loaded, and the
+aliases are applied once all indices exist.
+
+With --type=all, a bundle written by "backup --type=all" is restored the same
+way from a single file.
+
+With --includePipelines, the ingest pipelines backed up with the indices are
+put before the indices are created; pipelines that already exist are kept
+unless --existing=overwrite is given.
+
+With --opensearch, --type=lifecycle puts ISM policies on an OpenSearch
+cluster.
+
+With --type=security, the roles and role mappings of the file are applied;
+--dryRun shows what would be created or updated without changing anything.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if input == "" {
 			return fmt.Errorf("input file is required")
@@ -21,6 +73,11 @@ This command reads data from a file and imports it into an Elasticsearch cluster
 			return fmt.Errorf("output cluster is required")
 		}
 
+		maxSize, err := backup.ParseSize(maxDocumentSize)
+		if err != nil {
+			return fmt.Errorf("invalid --maxDocumentSize: %w", err)
+		}
+
 		config := restore.Config{
 			Input:       input,
 			Output:      output,
@@ -29,6 +86,32 @@ This command reads data from a file and imports it into an Elasticsearch cluster
 			Verbose:     verbose,
 			Username:    username,
 			Password:    password,
+
+			OutputIndexTemplate: outputIndexTemplate,
+			RenameMap:           renameMap,
+
+			All:      all,
+			Include:  include,
+			Exclude:  exclude,
+			Existing: existing,
+			DryRun:   dryRun,
+
+			IncludePipelines: includePipelines,
+			OpenSearch:       opensearch,
+
+			InputFormat: inputFormat,
+			IDField:     idField,
+			ColumnTypes: columnTypes,
+
+			Parallel:        parallel,
+			MaxDocumentSize: maxSize,
+			Verify:          verify,
+		}
+		if cmd.Flags().Changed("shards") {
+			config.Shards = &shards
+		}
+		if cmd.Flags().Changed("replicas") {
+			config.Replicas = &replicas
 		}
 
 		return restore.Run(config)
@@ -39,12 +122,29 @@ func init() {
 	rootCmd.AddCommand(restoreCmd)
 
 	// Restore flags
-	restoreCmd.Flags().StringVarP(&input, "input", "i", "", "Input file path (required)")
+	restoreCmd.Flags().StringVarP(&input, "input", "i", "", "Input file path, parts manifest, directory or glob, or - for standard input (required)")
 	restoreCmd.Flags().StringVarP(&output, "output", "o", "", "Destination Elasticsearch cluster or index (required)")
-	restoreCmd.Flags().StringVarP(&dataType, "type", "t", "data", "Type of data to restore (data, mapping, settings)")
+	restoreCmd.Flags().StringVarP(&dataType, "type", "t", "data", "Type of data to restore (data, mapping, settings, index, aliases, templates, pipelines, lifecycle, scripts, security, all)")
 	restoreCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 4, "Number of concurrent operations")
 	restoreCmd.Flags().StringVarP(&username, "username", "u", "", "Elasticsearch username (optional)")
 	restoreCmd.Flags().StringVarP(&password, "password", "p", "", "Elasticsearch password (optional)")
+	restoreCmd.Flags().StringVar(&outputIndexTemplate, "outputIndexTemplate", "", "Destination index name template, e.g. {index}-v2 (used when output has no index)")
+	restoreCmd.Flags().IntVar(&shards, "shards", 0, "Number of shards for indices created with --type=index or --type=all (default: backed up value)")
+	restoreCmd.Flags().IntVar(&replicas, "replicas", 0, "Number of replicas for indices created with --type=index or --type=all (default: backed up value)")
+	restoreCmd.Flags().BoolVar(&all, "all", false, "Restore a directory backup written by backup --all")
+	restoreCmd.Flags().StringSliceVar(&include, "include", nil, "Index patterns to restore with --all (default: all indices)")
+	restoreCmd.Flags().StringSliceVar(&exclude, "exclude", nil, "Index patterns to leave out with --all")
+	restoreCmd.Flags().StringVar(&existing, "existing", "fail", "What to do with indices, templates, pipelines, lifecycle policies, scripts, roles or role mappings that already exist on the destination (fail, skip, overwrite)")
+	restoreCmd.Flags().BoolVar(&includePipelines, "includePipelines", false, "Also put the ingest pipelines backed up with --includePipelines before creating the indices")
+	restoreCmd.Flags().BoolVar(&opensearch, "opensearch", false, "Put the ISM policies of the file on an OpenSearch cluster with --type=lifecycle")
+	restoreCmd.Flags().BoolVar(&dryRun, "dryRun", false, "Show the changes --type=security would make on the destination without applying them")
+	restoreCmd.Flags().StringVar(&inputFormat, "inputFormat", "auto", "Format of the input data file (auto, csv, jsonl)")
+	restoreCmd.Flags().StringVar(&idField, "idField", "", "Column or field holding the document ids of csv and jsonl input (default: generated ids)")
+	restoreCmd.Flags().StringSliceVar(&columnTypes, "columnTypes", nil, "Types of csv columns as name:type (string, integer, float, boolean, json), e.g. age:integer,active:boolean")
+	restoreCmd.Flags().IntVar(&parallel, "parallel", 2, "Number of data files restored at the same time from a parts manifest, directory or glob")
+	restoreCmd.Flags().StringVar(&maxDocumentSize, "maxDocumentSize", "100MB", "Largest document read from a data file; bigger documents are reported and skipped")
+	restoreCmd.Flags().BoolVar(&verify, "verify", true, "Check the backup files against the checksums of their manifest")
+	restoreCmd.Flags().StringVar(&renameMap, "renameMap", "", "JSON file mapping source to destination index names, e.g. {\"prod-*\": \"staging-*\"}")
 
 	// Mark required flags
 	restoreCmd.MarkFlagRequired("input")
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/lilmonk/elasticdump/internal/backup"
 	"github.com/lilmonk/elasticdump/internal/transfer"
 	"github.com/spf13/cobra"
 )
@@ -14,9 +15,34 @@ var (
 	limit       int
 	concurrency int
 	format      string
+	pretty      bool
 	scrollSize  int
 	username    string
 	password    string
+
+	fields         []string
+	arraySeparator string
+
+	compress         string
+	compressionLevel int
+
+	fileSize string
+	fileDocs int
+
+	outputIndexTemplate string
+	renameMap           string
+
+	all              bool
+	include          []string
+	exclude          []string
+	includeSystem    bool
+	existing         string
+	includePipelines bool
+	dryRun           bool
+	opensearch       bool
+
+	shards   int
+	replicas int
 )
 
 // transferCmd represents the transfer command
@@ -24,7 +50,50 @@ var transferCmd = &cobra.Command{
 	Use:   "transfer",
 	Short: "Transfer data between Elasticsearch clusters",
 	Long: `Transfer data, mappings, or settings between Elasticsearch clusters.
-This command supports various transfer types and can handle large datasets efficiently.`,
+This command supports various transfer types and can handle large datasets efficiently.
+
+Data streams are read and written through their name: a missing destination
+data stream is created from the source index template, and documents are
+written to data streams with create semantics.
+
+The index type creates the destination index from the source settings and
+mappings in one request, which also carries static settings such as
+number_of_shards. It fails if the destination index already exists.
+
+The all type migrates complete indices in one run: it creates the indices,
+copies their data and applies their aliases. With a file as output, all of
+it is bundled into a single file that "restore --type=all" reads back.
+
+The templates type copies component, composable index and legacy templates
+whose names match the input URL path (all templates for a bare cluster URL).
+Component templates used by a copied index template are copied first.
+
+The pipelines type copies the ingest pipelines whose ids match the input URL
+path. With --includePipelines, the settings, index and all types also copy
+the pipelines referenced by index.default_pipeline and index.final_pipeline.
+
+The lifecycle type copies the ILM policies whose names match the input URL
+path. The settings, index and all types always copy the ILM policies named by
+index.lifecycle.name of the transferred indices. With --opensearch, the
+lifecycle type copies the ISM policies of OpenSearch clusters instead.
+
+The scripts type copies the stored scripts and search templates whose ids
+match the input URL path.
+
+With a file as output, the json format writes the documents as a single JSON
+array (indented with --pretty) and the ndjson format writes one document per
+line. restore reads both. The csv format writes one row per document with the
+columns given by --fields (dot paths into the source), or by default _index,
+_id and every field of the mapping; arrays are joined with --arraySeparator.
+Output files are compressed with --compress=gzip, or when their name ends in
+.gz. --fileSize and --fileDocs split the output file into numbered parts,
+listed in a <output>.parts.json manifest that restore accepts as input. An
+output of "-" writes to standard output, with logs going to standard error.
+
+The security type copies the roles and role mappings whose names match the
+input URL path. Reserved roles and role mappings are never copied. Entries
+identical on the destination are left alone, and --dryRun shows what would
+be created or updated without changing anything.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if input == "" {
 			return fmt.Errorf("input is required")
@@ -33,6 +102,11 @@ This command supports various transfer types and can handle large datasets effic
 			return fmt.Errorf("output is required")
 		}
 
+		size, err := parseFileSize(fileSize)
+		if err != nil {
+			return err
+		}
+
 		config := transfer.Config{
 			Input:       input,
 			Output:      output,
@@ -40,10 +114,37 @@ This command supports various transfer types and can handle large datasets effic
 			Limit:       limit,
 			Concurrency: concurrency,
 			Format:      format,
+			Pretty:      pretty,
 			ScrollSize:  scrollSize,
 			Verbose:     verbose,
 			Username:    username,
 			Password:    password,
+
+			Fields:         fields,
+			ArraySeparator: arraySeparator,
+
+			Compress:         compress,
+			CompressionLevel: compressionLevel,
+
+			FileSize: size,
+			FileDocs: fileDocs,
+
+			Version: rootCmd.Version,
+
+			OutputIndexTemplate: outputIndexTemplate,
+			RenameMap:           renameMap,
+
+			IncludeSystem:    includeSystem,
+			Existing:         existing,
+			IncludePipelines: includePipelines,
+			DryRun:           dryRun,
+			OpenSearch:       opensearch,
+		}
+		if cmd.Flags().Changed("shards") {
+			config.Shards = &shards
+		}
+		if cmd.Flags().Changed("replicas") {
+			config.Replicas = &replicas
 		}
 
 		return transfer.Run(config)
@@ -56,15 +157,39 @@ func init() {
 	// Transfer flags
 	transferCmd.Flags().StringVarP(&input, "input", "i", "", "Source Elasticsearch cluster or index (required)")
 	transferCmd.Flags().StringVarP(&output, "output", "o", "", "Destination Elasticsearch cluster or index (required)")
-	transferCmd.Flags().StringVarP(&dataType, "type", "t", "data", "Type of data to transfer (data, mapping, settings)")
+	transferCmd.Flags().StringVarP(&dataType, "type", "t", "data", "Type of data to transfer (data, mapping, settings, index, aliases, templates, pipelines, lifecycle, scripts, security, all)")
 	transferCmd.Flags().IntVarP(&limit, "limit", "l", 0, "Limit the number of records to transfer (0 = no limit)")
 	transferCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 4, "Number of concurrent operations")
-	transferCmd.Flags().StringVarP(&format, "format", "f", "json", "Output format (json, ndjson)")
+	transferCmd.Flags().StringVarP(&format, "format", "f", "json", "Output format (json, ndjson, bulk, csv)")
+	transferCmd.Flags().BoolVar(&pretty, "pretty", false, "Indent the documents of the json format")
+	transferCmd.Flags().StringSliceVar(&fields, "fields", nil, "Columns of the csv format as dot paths into the source, e.g. _id,user.name (default: _index, _id and all mapped fields)")
+	transferCmd.Flags().StringVar(&arraySeparator, "arraySeparator", "|", "Separator joining array values in a csv cell")
+	transferCmd.Flags().StringVar(&compress, "compress", "", "Compression of output files (none, gzip) (default: gzip for names ending in .gz)")
+	transferCmd.Flags().IntVar(&compressionLevel, "compressionLevel", 6, "gzip compression level from 1 (fastest) to 9 (smallest)")
+	transferCmd.Flags().StringVar(&fileSize, "fileSize", "", "Split the output file into parts of about this size before compression, e.g. 1GB or 500MB")
+	transferCmd.Flags().IntVar(&fileDocs, "fileDocs", 0, "Split the output file into parts of this many documents (0 = no limit)")
 	transferCmd.Flags().IntVarP(&scrollSize, "scrollSize", "s", 1000, "Size of the scroll for large datasets")
 	transferCmd.Flags().StringVarP(&username, "username", "u", "", "Elasticsearch username (optional)")
 	transferCmd.Flags().StringVarP(&password, "password", "p", "", "Elasticsearch password (optional)")
+	transferCmd.Flags().IntVar(&shards, "shards", 0, "Number of shards for indices created with --type=index or --type=all (default: source value)")
+	transferCmd.Flags().IntVar(&replicas, "replicas", 0, "Number of replicas for indices created with --type=index or --type=all (default: source value)")
+	transferCmd.Flags().StringVar(&outputIndexTemplate, "outputIndexTemplate", "", "Destination index name template, e.g. {index}-v2 (used when output has no index)")
+	transferCmd.Flags().StringVar(&renameMap, "renameMap", "", "JSON file mapping source to destination index names, e.g. {\"prod-*\": \"staging-*\"}")
+	transferCmd.Flags().BoolVar(&includeSystem, "includeSystem", false, "Include system (.-prefixed or managed) templates, pipelines and lifecycle policies")
+	transferCmd.Flags().StringVar(&existing, "existing", "fail", "What to do with templates, pipelines, lifecycle policies, scripts, roles or role mappings that already exist on the destination (fail, skip, overwrite)")
+	transferCmd.Flags().BoolVar(&includePipelines, "includePipelines", false, "Also copy the ingest pipelines referenced by index.default_pipeline and index.final_pipeline")
+	transferCmd.Flags().BoolVar(&dryRun, "dryRun", false, "Show the changes --type=security would make on the destination without applying them")
+	transferCmd.Flags().BoolVar(&opensearch, "opensearch", false, "Copy the ISM policies of OpenSearch clusters with --type=lifecycle")
 
 	// Mark required flags
 	transferCmd.MarkFlagRequired("input")
 	transferCmd.MarkFlagRequired("output")
 }
+
+// parseFileSize parses the --fileSize flag, empty meaning no bound
+func parseFileSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return backup.ParseSize(s)
+}
@@ -0,0 +1,211 @@
+// Package backup describes the on-disk layout of elasticdump backups shared by
+// the backup and restore commands.
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/templates"
+)
+
+// MappingSuffix is appended to a data backup file name to get the sidecar file
+// holding the mappings of the exported indices
+const MappingSuffix = ".mapping.json"
+
+// MappingFile returns the mapping sidecar file for a data backup file
+func MappingFile(dataFile string) string {
+	return dataFile + MappingSuffix
+}
+
+// DataStreamsSuffix is appended to a data backup file name to get the sidecar
+// file listing the data streams the documents were exported from
+const DataStreamsSuffix = ".datastreams.json"
+
+// DataStreamsFile returns the data streams sidecar file for a data backup file
+func DataStreamsFile(dataFile string) string {
+	return dataFile + DataStreamsSuffix
+}
+
+// PipelinesSuffix is appended to a backup file name to get the sidecar file
+// holding the ingest pipelines referenced by the settings of its indices
+const PipelinesSuffix = ".pipelines.json"
+
+// PipelinesFile returns the ingest pipelines sidecar file for a backup file
+func PipelinesFile(file string) string {
+	return file + PipelinesSuffix
+}
+
+// DataStream describes a data stream of a data backup. Its backing indices
+// are not backed up: the data stream is recreated from its index template,
+// kept in Templates together with the component templates it is composed of.
+type DataStream struct {
+	Name      string         `json:"name"`
+	Template  string         `json:"template"`
+	Templates *templates.Set `json:"templates"`
+}
+
+// CheckDestination returns an error if the data stream cannot be recreated
+// under the name dest, because its index template does not match dest. The
+// template is put as backed up, so renaming only works within its patterns.
+func (s *DataStream) CheckDestination(dest string) error {
+	if dest == s.Name || s.Templates == nil {
+		return nil
+	}
+
+	for _, t := range s.Templates.IndexTemplates {
+		if t.Name != s.Template {
+			continue
+		}
+		patterns := indexPatterns(t.IndexTemplate["index_patterns"])
+		if cluster.MatchesAny(dest, patterns) {
+			return nil
+		}
+		return fmt.Errorf("cannot rename data stream %s to %s: its index template %s only matches %s",
+			s.Name, dest, s.Template, strings.Join(patterns, ", "))
+	}
+
+	return nil
+}
+
+// indexPatterns returns the index_patterns of a template, which are a list
+// or a single pattern
+func indexPatterns(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		patterns := make([]string, 0, len(v))
+		for _, p := range v {
+			if pattern, ok := p.(string); ok {
+				patterns = append(patterns, pattern)
+			}
+		}
+		return patterns
+	}
+	return nil
+}
+
+// File names used in a directory backup. The manifest, the ingest pipelines
+// and the data streams sit at the top of the directory and every index and
+// data stream gets a subdirectory named after it.
+const (
+	ManifestName    = "manifest.json"
+	PipelinesName   = "pipelines.json"
+	DataStreamsName = "datastreams.json"
+	MappingName     = "mapping.json"
+	SettingsName    = "settings.json"
+	AliasesName     = "aliases.json"
+)
+
+// DataName returns the name of the data file of an index directory
+func DataName(format string) string {
+	return "data." + format
+}
+
+// LayoutVersion is the version of the directory backup layout
+const LayoutVersion = 1
+
+// Manifest describes the content of a directory backup. Pipelines names the
+// ingest pipelines file, if the backup holds one, and Files holds the
+// checksums of every file of the backup but the manifest, both relative to
+// the backup directory. Data streams are described in the DataStreamsName
+// file.
+type Manifest struct {
+	LayoutVersion  int               `json:"layout_version"`
+	ToolVersion    string            `json:"tool_version,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Source         string            `json:"source"`
+	ClusterName    string            `json:"cluster_name,omitempty"`
+	ClusterVersion string            `json:"cluster_version,omitempty"`
+	Format         string            `json:"format"`
+	Compression    string            `json:"compression,omitempty"`
+	Indices        []IndexEntry      `json:"indices"`
+	DataStreams    []DataStreamEntry `json:"data_streams,omitempty"`
+	Pipelines      string            `json:"pipelines,omitempty"`
+	Files          []File            `json:"files,omitempty"`
+}
+
+// IndexEntry describes one index of a directory backup. File names are
+// relative to the backup directory.
+type IndexEntry struct {
+	Name      string `json:"name"`
+	Documents int    `json:"documents"`
+	Mapping   string `json:"mapping"`
+	Settings  string `json:"settings"`
+	Aliases   string `json:"aliases"`
+	Data      string `json:"data"`
+}
+
+// DataStreamEntry describes one data stream of a directory backup. Only its
+// documents are kept in its subdirectory: the data stream is recreated from
+// its index template, which the DataStreamsName file holds. File names are
+// relative to the backup directory.
+type DataStreamEntry struct {
+	Name      string `json:"name"`
+	Documents int    `json:"documents"`
+	Data      string `json:"data"`
+}
+
+// WriteManifest writes the manifest to the backup directory
+func WriteManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, ManifestName), append(data, '\n'), 0644)
+}
+
+// ReadManifest reads the manifest of a backup directory
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if manifest.LayoutVersion > LayoutVersion {
+		return nil, fmt.Errorf("unsupported backup layout version %d", manifest.LayoutVersion)
+	}
+
+	return &manifest, nil
+}
+
+// A bundle is a single NDJSON file holding everything needed to recreate a
+// set of indices. Its first line is a BundleLine with a Header, and the
+// documents of every index are preceded by a BundleLine with the index
+// Definition. All other lines are documents in the usual backup format.
+
+// BundleLine is a non-document line of a bundle
+type BundleLine struct {
+	Header     *BundleHeader    `json:"_bundle,omitempty"`
+	Definition *IndexDefinition `json:"_definition,omitempty"`
+}
+
+// BundleHeader is the first line of a bundle
+type BundleHeader struct {
+	LayoutVersion int       `json:"layout_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Source        string    `json:"source"`
+	Indices       []string  `json:"indices"`
+}
+
+// IndexDefinition holds what is needed to create an index and its aliases:
+// Create is a create index request body, Aliases the aliases of the index as
+// returned by the get alias API
+type IndexDefinition struct {
+	Index     string                 `json:"index"`
+	Create    map[string]interface{} `json:"create"`
+	Aliases   map[string]interface{} `json:"aliases,omitempty"`
+	Documents int                    `json:"documents"`
+}
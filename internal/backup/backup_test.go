@@ -0,0 +1,85 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lilmonk/elasticdump/internal/templates"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	manifest := &Manifest{
+		LayoutVersion: LayoutVersion,
+		CreatedAt:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Source:        "http://localhost:9200",
+		Format:        "ndjson",
+		Indices: []IndexEntry{
+			{
+				Name:      "users",
+				Documents: 42,
+				Mapping:   filepath.Join("users", MappingName),
+				Settings:  filepath.Join("users", SettingsName),
+				Aliases:   filepath.Join("users", AliasesName),
+				Data:      filepath.Join("users", DataName("ndjson")),
+			},
+		},
+	}
+
+	if err := WriteManifest(dir, manifest); err != nil {
+		t.Fatalf("WriteManifest failed: %v", err)
+	}
+
+	read, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("ReadManifest failed: %v", err)
+	}
+
+	if read.Source != manifest.Source || !read.CreatedAt.Equal(manifest.CreatedAt) {
+		t.Errorf("Manifest mismatch: got %+v", read)
+	}
+	if len(read.Indices) != 1 || read.Indices[0] != manifest.Indices[0] {
+		t.Errorf("Index entries mismatch: got %+v", read.Indices)
+	}
+}
+
+func TestReadManifestErrors(t *testing.T) {
+	if _, err := ReadManifest(t.TempDir()); err == nil {
+		t.Error("Expected error for missing manifest")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), []byte(`{"layout_version": 99}`), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if _, err := ReadManifest(dir); err == nil {
+		t.Error("Expected error for unsupported layout version")
+	}
+}
+
+func TestFileNames(t *testing.T) {
+	if MappingFile("backup.ndjson") != "backup.ndjson.mapping.json" {
+		t.Errorf("Unexpected mapping file name: %s", MappingFile("backup.ndjson"))
+	}
+	if DataName("ndjson") != "data.ndjson" {
+		t.Errorf("Unexpected data file name: %s", DataName("ndjson"))
+	}
+}
+
+func TestDataStreamCheckDestination(t *testing.T) {
+	stream := &DataStream{
+		Name:     "logs-app",
+		Template: "logs",
+		Templates: &templates.Set{IndexTemplates: []templates.IndexTemplate{
+			{Name: "logs", IndexTemplate: map[string]interface{}{"index_patterns": []interface{}{"logs-*"}}},
+		}},
+	}
+
+	for dest, wantErr := range map[string]bool{"logs-app": false, "logs-copy": false, "archive-app": true} {
+		if err := stream.CheckDestination(dest); (err != nil) != wantErr {
+			t.Errorf("CheckDestination(%s) error = %v, wantErr %v", dest, err, wantErr)
+		}
+	}
+}
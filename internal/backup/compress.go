@@ -0,0 +1,55 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Decompress returns a reader of the content of r, decompressing it if it is
+// gzip, as detected from its magic bytes whatever the file name
+func Decompress(r io.Reader) (io.Reader, error) {
+	reader := bufio.NewReader(r)
+
+	magic, _ := reader.Peek(len(gzipMagic))
+	if !bytes.Equal(magic, gzipMagic) {
+		return reader, nil
+	}
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip header: %w", err)
+	}
+
+	return gz, nil
+}
+
+// Stdio is the file name standing for standard input or standard output
+const Stdio = "-"
+
+// Open opens a backup file for reading, or standard input for Stdio, and
+// returns its size, -1 for standard input
+func Open(path string) (io.ReadCloser, int64, error) {
+	if path == Stdio {
+		return io.NopCloser(os.Stdin), -1, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	return file, info.Size(), nil
+}
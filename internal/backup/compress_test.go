@@ -0,0 +1,76 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(data))
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	for name, input := range map[string][]byte{
+		"plain": []byte(`{"a": 1}`),
+		"gzip":  gzipBytes(t, `{"a": 1}`),
+	} {
+		reader, err := Decompress(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: Decompress failed: %v", name, err)
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("%s: read failed: %v", name, err)
+		}
+		if string(data) != `{"a": 1}` {
+			t.Errorf("%s: expected the original content, got %q", name, data)
+		}
+	}
+}
+
+func TestDecompressShortInput(t *testing.T) {
+	for _, input := range []string{"", "x"} {
+		reader, err := Decompress(bytes.NewReader([]byte(input)))
+		if err != nil {
+			t.Fatalf("Decompress(%q) failed: %v", input, err)
+		}
+		if data, _ := io.ReadAll(reader); string(data) != input {
+			t.Errorf("Expected %q, got %q", input, data)
+		}
+	}
+}
+
+func TestOpenStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	w.Write(gzipBytes(t, `{"a":1}`))
+	w.Close()
+
+	file, size, err := Open(Stdio)
+	if err != nil || size != -1 {
+		t.Fatalf("Expected standard input of unknown size, got %d, %v", size, err)
+	}
+	reader, err := Decompress(file)
+	if err != nil {
+		t.Fatalf("Decompress failed: %v", err)
+	}
+	if data, _ := io.ReadAll(reader); string(data) != `{"a":1}` {
+		t.Errorf("Expected the decompressed standard input, got %q", data)
+	}
+}
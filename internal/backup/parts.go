@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PartsSuffix is appended to a data backup file name to get the manifest
+// listing the parts the data was split into
+const PartsSuffix = ".parts.json"
+
+// PartsFile returns the parts manifest of a data backup file
+func PartsFile(dataFile string) string {
+	return dataFile + PartsSuffix
+}
+
+// IsPartsFile reports whether path is a parts manifest
+func IsPartsFile(path string) bool {
+	return strings.HasSuffix(path, PartsSuffix)
+}
+
+// PartName returns the name of the nth part (from 1) of a data backup file.
+// The part number goes before the extensions so that they keep describing
+// the content: logs.ndjson.gz is split into logs.part-0001.ndjson.gz and so
+// on.
+func PartName(dataFile string, n int) string {
+	dir, base := filepath.Split(dataFile)
+
+	// A leading dot belongs to the name of hidden files
+	name, ext := base, ""
+	if i := strings.Index(base[min(len(base), 1):], "."); i >= 0 {
+		name, ext = base[:i+1], base[i+1:]
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s.part-%04d%s", name, n, ext))
+}
+
+// Parts describes a data backup split into several files. Data is the data
+// file name the parts were derived from, to which the mapping and data
+// streams sidecars belong. File names are relative to the manifest.
+type Parts struct {
+	Data      string `json:"data"`
+	Format    string `json:"format"`
+	Documents int    `json:"documents"`
+	Parts     []Part `json:"parts"`
+}
+
+// Part is one file of a split data backup
+type Part struct {
+	File      string `json:"file"`
+	Documents int    `json:"documents"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// WriteParts writes a parts manifest and returns its checksum, recorded
+// under its file name
+func WriteParts(path string, parts *Parts) (File, error) {
+	data, err := json.MarshalIndent(parts, "", "  ")
+	if err != nil {
+		return File{}, err
+	}
+	data = append(data, '\n')
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return File{}, err
+	}
+
+	hash := NewHash()
+	hash.Write(data)
+	return hash.File(filepath.Base(path)), nil
+}
+
+// ParseParts parses the data of a parts manifest read from path
+func ParseParts(path string, data []byte) (*Parts, error) {
+	var parts Parts
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return nil, fmt.Errorf("failed to parse parts manifest: %w", err)
+	}
+	if len(parts.Parts) == 0 {
+		return nil, fmt.Errorf("parts manifest %s lists no parts", path)
+	}
+
+	return &parts, nil
+}
+
+// PartPaths returns the paths of the parts of a manifest read from path
+func (p *Parts) PartPaths(path string) []string {
+	dir := filepath.Dir(path)
+
+	paths := make([]string, len(p.Parts))
+	for i, part := range p.Parts {
+		paths[i] = filepath.Join(dir, part.File)
+	}
+
+	return paths
+}
+
+// sizeUnits are the multipliers of the size suffixes accepted by ParseSize
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as 1GB, 500MB or 1048576 into bytes. Units
+// are binary (1KB is 1024 bytes) and case insensitive.
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPartName(t *testing.T) {
+	tests := []struct {
+		dataFile string
+		n        int
+		want     string
+	}{
+		{"logs.ndjson", 1, "logs.part-0001.ndjson"},
+		{"logs.ndjson.gz", 12, "logs.part-0012.ndjson.gz"},
+		{filepath.Join("out", "logs.json"), 3, filepath.Join("out", "logs.part-0003.json")},
+		{"logs", 2, "logs.part-0002"},
+		{".hidden.json", 1, ".hidden.part-0001.json"},
+	}
+
+	for _, tt := range tests {
+		if got := PartName(tt.dataFile, tt.n); got != tt.want {
+			t.Errorf("PartName(%s, %d) = %s, want %s", tt.dataFile, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPartsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := PartsFile(filepath.Join(dir, "logs.ndjson"))
+	if !IsPartsFile(path) {
+		t.Errorf("Expected %s to be a parts manifest", path)
+	}
+
+	parts := &Parts{
+		Data:      "logs.ndjson",
+		Format:    "ndjson",
+		Documents: 3,
+		Parts: []Part{
+			{File: "logs.part-0001.ndjson", Documents: 2, Bytes: 100},
+			{File: "logs.part-0002.ndjson", Documents: 1, Bytes: 50},
+		},
+	}
+	sum, err := WriteParts(path, parts)
+	if err != nil {
+		t.Fatalf("WriteParts failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	hash := NewHash()
+	hash.Write(data)
+	if file := hash.File("logs.ndjson.parts.json"); sum != file {
+		t.Errorf("Expected the checksum of the written manifest %+v, got %+v", file, sum)
+	}
+
+	read, err := ParseParts(path, data)
+	if err != nil {
+		t.Fatalf("ParseParts failed: %v", err)
+	}
+	if read.Data != parts.Data || read.Documents != 3 || len(read.Parts) != 2 || read.Parts[1] != parts.Parts[1] {
+		t.Errorf("Parts mismatch: got %+v", read)
+	}
+
+	paths := read.PartPaths(path)
+	if paths[0] != filepath.Join(dir, "logs.part-0001.ndjson") {
+		t.Errorf("Expected part paths relative to the manifest, got %v", paths)
+	}
+}
+
+func TestParsePartsErrors(t *testing.T) {
+	if _, err := ParseParts("invalid.parts.json", []byte(`{"parts":`)); err == nil {
+		t.Error("Expected error for an invalid manifest")
+	}
+
+	if _, err := ParseParts("empty.parts.json", []byte(`{"data": "empty.json", "parts": []}`)); err == nil {
+		t.Error("Expected error for a manifest without parts")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"1048576", 1048576, false},
+		{"512B", 512, false},
+		{"1KB", 1024, false},
+		{"500MB", 500 << 20, false},
+		{"1GB", 1 << 30, false},
+		{"1gb", 1 << 30, false},
+		{"1.5G", 3 << 29, false},
+		{"2 TB", 2 << 40, false},
+		{"", 0, true},
+		{"GB", 0, true},
+		{"-1MB", 0, true},
+		{"ten", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"time"
+)
+
+// ManifestSuffix is appended to a backup file name to get the sidecar
+// manifest recording where the backup came from and the checksums of its
+// files
+const ManifestSuffix = ".manifest.json"
+
+// ManifestFile returns the manifest sidecar file of a backup file
+func ManifestFile(file string) string {
+	return file + ManifestSuffix
+}
+
+// FileManifest describes a backup written to a file rather than a directory:
+// its provenance, what it holds and the checksums of the files making it up.
+// File names are relative to the manifest.
+type FileManifest struct {
+	ToolVersion    string    `json:"tool_version"`
+	CreatedAt      time.Time `json:"created_at"`
+	Source         string    `json:"source"`
+	ClusterName    string    `json:"cluster_name,omitempty"`
+	ClusterVersion string    `json:"cluster_version,omitempty"`
+
+	Type      string          `json:"type"`
+	Indices   []string        `json:"indices,omitempty"`
+	Query     json.RawMessage `json:"query,omitempty"`
+	Limit     int             `json:"limit,omitempty"`
+	Documents int             `json:"documents"`
+
+	Format      string `json:"format,omitempty"`
+	Compression string `json:"compression"`
+	Files       []File `json:"files"`
+}
+
+// File is the checksum of one file of a backup
+type File struct {
+	Name   string `json:"name"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// WriteFileManifest writes a file manifest
+func WriteFileManifest(path string, manifest *FileManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ReadFileManifest reads a file manifest
+func ReadFileManifest(path string) (*FileManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest FileManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse backup manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// Hash computes the checksum of a backup file from the bytes written to or
+// read from it, so that the file does not have to be read again
+type Hash struct {
+	hash  hash.Hash
+	bytes int64
+}
+
+// NewHash returns a hash of no bytes
+func NewHash() *Hash {
+	return &Hash{hash: sha256.New()}
+}
+
+// Write adds p to the hash
+func (h *Hash) Write(p []byte) (int, error) {
+	h.bytes += int64(len(p))
+	return h.hash.Write(p)
+}
+
+// File returns the checksum of the bytes hashed so far, recorded under name
+func (h *Hash) File(name string) File {
+	return File{Name: name, Bytes: h.bytes, SHA256: hex.EncodeToString(h.hash.Sum(nil))}
+}
+
+// Mismatch describes how actual differs from the recorded checksum of a
+// file, or returns an empty string if they match
+func Mismatch(recorded, actual File) string {
+	if actual.Bytes == recorded.Bytes && actual.SHA256 == recorded.SHA256 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s has changed (%d bytes, sha256 %s; recorded %d bytes, sha256 %s)",
+		recorded.Name, actual.Bytes, actual.SHA256, recorded.Bytes, recorded.SHA256)
+}
@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := ManifestFile(filepath.Join(dir, "logs.ndjson"))
+	if path != filepath.Join(dir, "logs.ndjson.manifest.json") {
+		t.Errorf("Unexpected manifest file %s", path)
+	}
+
+	manifest := &FileManifest{
+		ToolVersion:    "1.2.3",
+		CreatedAt:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Source:         "http://localhost:9200",
+		ClusterName:    "prod",
+		ClusterVersion: "8.19.0",
+		Type:           "data",
+		Indices:        []string{"logs"},
+		Query:          json.RawMessage(`{"match_all":{}}`),
+		Documents:      42,
+		Format:         "ndjson",
+		Compression:    "none",
+		Files:          []File{{Name: "logs.ndjson", Bytes: 10, SHA256: "abc"}},
+	}
+	if err := WriteFileManifest(path, manifest); err != nil {
+		t.Fatalf("WriteFileManifest failed: %v", err)
+	}
+
+	read, err := ReadFileManifest(path)
+	if err != nil {
+		t.Fatalf("ReadFileManifest failed: %v", err)
+	}
+	var query bytes.Buffer
+	if err := json.Compact(&query, read.Query); err != nil {
+		t.Fatalf("Failed to read query: %v", err)
+	}
+	if read.ClusterName != "prod" || read.Documents != 42 || query.String() != `{"match_all":{}}` ||
+		!read.CreatedAt.Equal(manifest.CreatedAt) || len(read.Files) != 1 || read.Files[0] != manifest.Files[0] {
+		t.Errorf("Manifest mismatch: got %+v", read)
+	}
+}
+
+func TestHash(t *testing.T) {
+	want := File{
+		Name:   "data.ndjson",
+		Bytes:  5,
+		SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}
+
+	// The bytes may be hashed in pieces as they are written
+	hash := NewHash()
+	hash.Write([]byte("hel"))
+	hash.Write([]byte("lo"))
+	if file := hash.File("data.ndjson"); file != want {
+		t.Errorf("Expected %+v, got %+v", want, file)
+	}
+}
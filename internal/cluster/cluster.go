@@ -0,0 +1,135 @@
+// Package cluster holds the cluster operations shared by the transfer and
+// restore packages: selecting indices and other named objects by wildcard
+// patterns, checking for and creating indices and data streams, updating
+// aliases, and the options of putting definitions such as templates on a
+// destination.
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Client is the part of the Elasticsearch API used by this package
+type Client interface {
+	IndicesExists(index []string, o ...func(*esapi.IndicesExistsRequest)) (*esapi.Response, error)
+	IndicesCreate(index string, o ...func(*esapi.IndicesCreateRequest)) (*esapi.Response, error)
+	IndicesUpdateAliases(body io.Reader, o ...func(*esapi.IndicesUpdateAliasesRequest)) (*esapi.Response, error)
+	IndicesGetDataStream(o ...func(*esapi.IndicesGetDataStreamRequest)) (*esapi.Response, error)
+	IndicesCreateDataStream(name string, o ...func(*esapi.IndicesCreateDataStreamRequest)) (*esapi.Response, error)
+}
+
+// PutOptions control how definitions such as templates are put on a
+// destination
+type PutOptions struct {
+	// Existing decides what happens to definitions that already exist on
+	// the destination: "fail" (the default), "skip" or "overwrite"
+	Existing string
+	Verbose  bool
+
+	// Destination names the destination in messages
+	Destination string
+}
+
+// CheckExisting returns an error for an unknown existing policy; kind names
+// the definitions in the message
+func CheckExisting(existing, kind string) error {
+	switch existing {
+	case "", "fail", "skip", "overwrite":
+		return nil
+	default:
+		return fmt.Errorf("unsupported existing %s policy: %s", kind, existing)
+	}
+}
+
+// MatchesAny reports whether name matches one of the wildcard patterns
+func MatchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSystem reports whether a definition such as a template, an ingest
+// pipeline or a lifecycle policy is managed by the cluster: its name starts
+// with a '.' or its body is marked as managed in _meta
+func IsSystem(name string, body map[string]interface{}) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+
+	meta, _ := body["_meta"].(map[string]interface{})
+	managed, _ := meta["managed"].(bool)
+	return managed
+}
+
+// IndexExists reports whether the index exists on the cluster
+func IndexExists(client Client, index string) (bool, error) {
+	res, err := client.IndicesExists([]string{index})
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	default:
+		return false, fmt.Errorf("index exists check failed: %s", res.String())
+	}
+}
+
+// CreateIndex creates an index with the given create index request body
+func CreateIndex(client Client, index string, body map[string]interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.IndicesCreate(
+		index,
+		func(r *esapi.IndicesCreateRequest) {
+			r.Body = strings.NewReader(string(data))
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("create index %s failed: %s", index, res.String())
+	}
+
+	return nil
+}
+
+// UpdateAliases sends alias actions to the _aliases API, which applies them
+// atomically
+func UpdateAliases(client Client, actions []map[string]interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return err
+	}
+
+	res, err := client.IndicesUpdateAliases(strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("update aliases failed: %s", res.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+func TestMatchesAny(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		want     bool
+	}{
+		{"logs-2024", []string{"logs-*"}, true},
+		{"logs-2024", []string{"metrics-*", "logs-2024"}, true},
+		{"metrics", []string{"logs-*"}, false},
+		{"logs", nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesAny(tt.name, tt.patterns); got != tt.want {
+			t.Errorf("MatchesAny(%s, %v) = %v, want %v", tt.name, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestIsSystem(t *testing.T) {
+	managed := map[string]interface{}{"_meta": map[string]interface{}{"managed": true}}
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want bool
+	}{
+		{".monitoring", nil, true},
+		{"logs", managed, true},
+		{"logs", map[string]interface{}{"_meta": map[string]interface{}{"managed": false}}, false},
+		{"logs", nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSystem(tt.name, tt.body); got != tt.want {
+			t.Errorf("IsSystem(%s, %v) = %v, want %v", tt.name, tt.body, got, tt.want)
+		}
+	}
+}
+
+// fakeAPI records the requests of the cluster package
+type fakeAPI struct {
+	existing map[string]bool
+	created  map[string]string
+	aliases  []string
+
+	// streams maps data stream names to their index template
+	streams map[string]string
+	// streamStatus, when set, is the status of every get data stream request
+	streamStatus int
+}
+
+func response(status int, body string) *esapi.Response {
+	return &esapi.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func (f *fakeAPI) IndicesExists(index []string, o ...func(*esapi.IndicesExistsRequest)) (*esapi.Response, error) {
+	if f.existing[index[0]] {
+		return response(200, ""), nil
+	}
+	return response(404, ""), nil
+}
+
+func (f *fakeAPI) IndicesCreate(index string, o ...func(*esapi.IndicesCreateRequest)) (*esapi.Response, error) {
+	if f.existing[index] {
+		return response(400, `{"error":{"type":"resource_already_exists_exception"}}`), nil
+	}
+
+	req := &esapi.IndicesCreateRequest{Index: index}
+	for _, opt := range o {
+		opt(req)
+	}
+	body, _ := io.ReadAll(req.Body)
+	f.created[index] = string(body)
+
+	return response(200, `{"acknowledged":true}`), nil
+}
+
+func (f *fakeAPI) IndicesUpdateAliases(body io.Reader, o ...func(*esapi.IndicesUpdateAliasesRequest)) (*esapi.Response, error) {
+	data, _ := io.ReadAll(body)
+	f.aliases = append(f.aliases, string(data))
+	return response(200, `{"acknowledged":true}`), nil
+}
+
+func (f *fakeAPI) IndicesGetDataStream(o ...func(*esapi.IndicesGetDataStreamRequest)) (*esapi.Response, error) {
+	req := &esapi.IndicesGetDataStreamRequest{}
+	for _, opt := range o {
+		opt(req)
+	}
+	if f.streamStatus != 0 {
+		return response(f.streamStatus, `{"error":{"type":"security_exception"}}`), nil
+	}
+
+	template, ok := f.streams[req.Name[0]]
+	if !ok {
+		return response(404, `{"error":{"type":"index_not_found_exception"}}`), nil
+	}
+	return response(200, fmt.Sprintf(`{"data_streams":[{"name":%q,"template":%q}]}`, req.Name[0], template)), nil
+}
+
+func (f *fakeAPI) IndicesCreateDataStream(name string, o ...func(*esapi.IndicesCreateDataStreamRequest)) (*esapi.Response, error) {
+	if _, ok := f.streams[name]; ok {
+		return response(400, `{"error":{"type":"resource_already_exists_exception"}}`), nil
+	}
+	f.streams[name] = ""
+	return response(200, `{"acknowledged":true}`), nil
+}
+
+func TestIndexExistsAndCreateIndex(t *testing.T) {
+	api := &fakeAPI{existing: map[string]bool{"logs": true}, created: make(map[string]string)}
+
+	if exists, err := IndexExists(api, "logs"); err != nil || !exists {
+		t.Errorf("IndexExists(logs) = %v, %v; want true, nil", exists, err)
+	}
+	if exists, err := IndexExists(api, "metrics"); err != nil || exists {
+		t.Errorf("IndexExists(metrics) = %v, %v; want false, nil", exists, err)
+	}
+
+	if err := CreateIndex(api, "metrics", map[string]interface{}{"settings": map[string]interface{}{}}); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+	if api.created["metrics"] != `{"settings":{}}` {
+		t.Errorf("Unexpected create body %q", api.created["metrics"])
+	}
+
+	if err := CreateIndex(api, "logs", nil); err == nil {
+		t.Error("Expected error for an existing index")
+	}
+}
+
+func TestUpdateAliases(t *testing.T) {
+	api := &fakeAPI{}
+	actions := []map[string]interface{}{
+		{"add": map[string]interface{}{"index": "logs-v2", "alias": "logs"}},
+		{"remove": map[string]interface{}{"index": "logs-v1", "alias": "logs"}},
+	}
+
+	if err := UpdateAliases(api, actions); err != nil {
+		t.Fatalf("UpdateAliases failed: %v", err)
+	}
+
+	// Both actions go in a single atomic request
+	want := `{"actions":[{"add":{"alias":"logs","index":"logs-v2"}},{"remove":{"alias":"logs","index":"logs-v1"}}]}`
+	if len(api.aliases) != 1 || api.aliases[0] != want {
+		t.Errorf("Expected one request %s, got %v", want, api.aliases)
+	}
+}
+
+func TestDataStreams(t *testing.T) {
+	api := &fakeAPI{streams: map[string]string{"logs-app": "logs"}}
+
+	stream, err := GetDataStream(api, "logs-app")
+	if err != nil || stream == nil || stream.Template != "logs" {
+		t.Fatalf("GetDataStream(logs-app) = %+v, %v; want template logs", stream, err)
+	}
+	if exists, err := IsDataStream(api, "logs-web"); err != nil || exists {
+		t.Errorf("IsDataStream(logs-web) = %v, %v; want false, nil", exists, err)
+	}
+
+	if err := CreateDataStream(api, "logs-web"); err != nil {
+		t.Fatalf("CreateDataStream failed: %v", err)
+	}
+	if exists, err := IsDataStream(api, "logs-web"); err != nil || !exists {
+		t.Errorf("IsDataStream(logs-web) = %v, %v; want true, nil", exists, err)
+	}
+	if err := CreateDataStream(api, "logs-app"); err == nil {
+		t.Error("Expected error for an existing data stream")
+	}
+}
+
+func TestIsDataStreamUnsupported(t *testing.T) {
+	// Forbidden for the user, or no data stream API before 7.9
+	for _, status := range []int{400, 403, 405} {
+		api := &fakeAPI{streamStatus: status}
+		if exists, err := IsDataStream(api, "logs"); err != nil || exists {
+			t.Errorf("IsDataStream with status %d = %v, %v; want false, nil", status, exists, err)
+		}
+	}
+
+	api := &fakeAPI{streamStatus: 500}
+	if _, err := IsDataStream(api, "logs"); err == nil {
+		t.Error("Expected error for a failing cluster")
+	}
+}
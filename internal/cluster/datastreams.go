@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// TimestampField is the field every document of a data stream must have
+const TimestampField = "@timestamp"
+
+// DataStream is an entry of the get data stream API response
+type DataStream struct {
+	Name     string `json:"name"`
+	Template string `json:"template"`
+}
+
+// GetDataStream returns the data stream with the given name, or nil if there
+// is no such data stream. Clusters older than 7.9 have no data stream API and
+// a user without view_index_metadata may not query it; neither can write to
+// a data stream, so a name they cannot look up is not one.
+func GetDataStream(client Client, name string) (*DataStream, error) {
+	res, err := client.IndicesGetDataStream(func(r *esapi.IndicesGetDataStreamRequest) {
+		r.Name = []string{name}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case 400, 403, 404, 405:
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get data stream failed: %s", res.String())
+	}
+
+	var result struct {
+		DataStreams []DataStream `json:"data_streams"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	for _, stream := range result.DataStreams {
+		if stream.Name == name {
+			return &stream, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// IsDataStream reports whether name is a data stream on the cluster
+func IsDataStream(client Client, name string) (bool, error) {
+	stream, err := GetDataStream(client, name)
+	if err != nil {
+		return false, err
+	}
+
+	return stream != nil, nil
+}
+
+// CreateDataStream creates a data stream; a matching index template with
+// data streams enabled must exist
+func CreateDataStream(client Client, name string) error {
+	res, err := client.IndicesCreateDataStream(name)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("create data stream %s failed: %s", name, res.String())
+	}
+
+	return nil
+}
+
+// MissingTimestamps reports the data streams that documents without an
+// @timestamp field were skipped for, once per data stream. The zero value is
+// ready to use.
+type MissingTimestamps struct {
+	mu       sync.Mutex
+	reported map[string]bool
+}
+
+// Report notes that a document of stream has no @timestamp field, printing a
+// warning the first time it is called for stream
+func (m *MissingTimestamps) Report(stream string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reported[stream] {
+		return
+	}
+	if m.reported == nil {
+		m.reported = make(map[string]bool)
+	}
+	m.reported[stream] = true
+
+	fmt.Printf("Warning: skipping documents without an %s field, which data stream %s requires\n", TimestampField, stream)
+}
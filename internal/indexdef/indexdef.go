@@ -0,0 +1,178 @@
+// Package indexdef builds create index request bodies from the responses of
+// the get settings, get mapping and get alias APIs.
+package indexdef
+
+import (
+	"sort"
+	"strings"
+)
+
+// nonTransferableSettings are index settings set by the cluster that must not
+// be sent when creating an index, as paths below the "index" settings object
+var nonTransferableSettings = []string{
+	"uuid",
+	"creation_date",
+	"creation_date_string",
+	"provided_name",
+	"version",
+	"resize",
+	"shrink",
+	"verified_before_close",
+	"routing.allocation.initial_recovery",
+	"history.uuid",
+}
+
+// Entry returns the object stored under key for an index in a per-index
+// response body, e.g. Entry(body, "logs", "mappings") for {"logs": {"mappings": ...}}
+func Entry(body map[string]interface{}, index, key string) map[string]interface{} {
+	indexBody, ok := body[index].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	entry, _ := indexBody[key].(map[string]interface{})
+	return entry
+}
+
+// FilterSettings returns a copy of index settings, as returned by the get
+// settings API for one index, without the settings that are managed by the
+// cluster and rejected by the create index API
+func FilterSettings(settings map[string]interface{}) map[string]interface{} {
+	filtered := deepCopy(settings)
+
+	index, ok := filtered["index"].(map[string]interface{})
+	if !ok {
+		return filtered
+	}
+
+	for _, setting := range nonTransferableSettings {
+		removePath(index, strings.Split(setting, "."))
+	}
+
+	return filtered
+}
+
+// CreateBody builds a create index request body from settings and mappings of
+// a single index; either may be nil
+func CreateBody(settings, mappings map[string]interface{}) map[string]interface{} {
+	body := make(map[string]interface{})
+	if len(settings) > 0 {
+		body["settings"] = FilterSettings(settings)
+	}
+	if len(mappings) > 0 {
+		body["mappings"] = mappings
+	}
+	return body
+}
+
+// removePath deletes a nested key and any parent objects left empty by it
+func removePath(m map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(m, path[0])
+		return
+	}
+
+	child, ok := m[path[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	removePath(child, path[1:])
+	if len(child) == 0 {
+		delete(m, path[0])
+	}
+}
+
+func deepCopy(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if child, ok := v.(map[string]interface{}); ok {
+			result[k] = deepCopy(child)
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// SetIndexSetting sets a setting below "settings.index" of a create index
+// request body, creating the intermediate objects as needed
+func SetIndexSetting(body map[string]interface{}, key string, value interface{}) {
+	settings, ok := body["settings"].(map[string]interface{})
+	if !ok {
+		settings = make(map[string]interface{})
+		body["settings"] = settings
+	}
+
+	index, ok := settings["index"].(map[string]interface{})
+	if !ok {
+		index = make(map[string]interface{})
+		settings["index"] = index
+	}
+
+	index[key] = value
+}
+
+// AliasActions builds _aliases "add" actions for the aliases of an index, as
+// returned by the get alias API, pointing them at index. Alias properties such
+// as filter, routing and is_write_index are kept.
+func AliasActions(aliases map[string]interface{}, index string) []map[string]interface{} {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	actions := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		add := map[string]interface{}{
+			"index": index,
+			"alias": name,
+		}
+		if properties, ok := aliases[name].(map[string]interface{}); ok {
+			for key, value := range properties {
+				add[key] = value
+			}
+		}
+		actions = append(actions, map[string]interface{}{"add": add})
+	}
+
+	return actions
+}
+
+// Pipelines returns the sorted ingest pipelines referenced by the
+// default_pipeline and final_pipeline settings of index settings as returned
+// by the get settings API for one index. The special "_none" value is ignored.
+func Pipelines(settings map[string]interface{}) []string {
+	index, _ := settings["index"].(map[string]interface{})
+
+	var names []string
+	for _, key := range []string{"default_pipeline", "final_pipeline"} {
+		name, _ := index[key].(string)
+		if name == "" {
+			// Flat settings, e.g. {"index.default_pipeline": "..."}
+			name, _ = settings["index."+key].(string)
+		}
+		if name != "" && name != "_none" {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// LifecyclePolicy returns the ILM policy referenced by the lifecycle.name
+// setting of index settings as returned by the get settings API for one
+// index, or "" if there is none
+func LifecyclePolicy(settings map[string]interface{}) string {
+	index, _ := settings["index"].(map[string]interface{})
+	lifecycle, _ := index["lifecycle"].(map[string]interface{})
+	if name, ok := lifecycle["name"].(string); ok {
+		return name
+	}
+
+	// Flat settings, e.g. {"index.lifecycle.name": "..."}
+	name, _ := settings["index.lifecycle.name"].(string)
+	return name
+}
@@ -0,0 +1,191 @@
+package indexdef
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sourceSettings() map[string]interface{} {
+	return map[string]interface{}{
+		"index": map[string]interface{}{
+			"number_of_shards":   "3",
+			"number_of_replicas": "1",
+			"uuid":               "abc123",
+			"creation_date":      "1700000000000",
+			"provided_name":      "logs",
+			"version":            map[string]interface{}{"created": "8110099"},
+			"routing": map[string]interface{}{
+				"allocation": map[string]interface{}{
+					"initial_recovery": map[string]interface{}{"_id": "node"},
+				},
+			},
+			"analysis": map[string]interface{}{
+				"analyzer": map[string]interface{}{"default": map[string]interface{}{"type": "standard"}},
+			},
+		},
+	}
+}
+
+func TestFilterSettings(t *testing.T) {
+	settings := sourceSettings()
+	filtered := FilterSettings(settings)
+
+	expected := map[string]interface{}{
+		"index": map[string]interface{}{
+			"number_of_shards":   "3",
+			"number_of_replicas": "1",
+			"analysis": map[string]interface{}{
+				"analyzer": map[string]interface{}{"default": map[string]interface{}{"type": "standard"}},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("FilterSettings() = %v, want %v", filtered, expected)
+	}
+
+	// The input must not be modified
+	if _, ok := settings["index"].(map[string]interface{})["uuid"]; !ok {
+		t.Error("FilterSettings modified its input")
+	}
+}
+
+func TestCreateBody(t *testing.T) {
+	mappings := map[string]interface{}{
+		"properties": map[string]interface{}{"message": map[string]interface{}{"type": "text"}},
+	}
+
+	body := CreateBody(sourceSettings(), mappings)
+	if _, ok := body["settings"]; !ok {
+		t.Error("Expected settings in create body")
+	}
+	if !reflect.DeepEqual(body["mappings"], mappings) {
+		t.Errorf("Expected mappings in create body, got %v", body["mappings"])
+	}
+
+	if empty := CreateBody(nil, nil); len(empty) != 0 {
+		t.Errorf("Expected empty create body, got %v", empty)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	body := map[string]interface{}{
+		"logs": map[string]interface{}{
+			"mappings": map[string]interface{}{"dynamic": "strict"},
+		},
+	}
+
+	if entry := Entry(body, "logs", "mappings"); entry["dynamic"] != "strict" {
+		t.Errorf("Entry(logs, mappings) = %v", entry)
+	}
+	if entry := Entry(body, "missing", "mappings"); entry != nil {
+		t.Errorf("Expected nil entry for missing index, got %v", entry)
+	}
+}
+
+func TestSetIndexSetting(t *testing.T) {
+	body := CreateBody(sourceSettings(), nil)
+	SetIndexSetting(body, "number_of_replicas", "0")
+
+	index := body["settings"].(map[string]interface{})["index"].(map[string]interface{})
+	if index["number_of_replicas"] != "0" || index["number_of_shards"] != "3" {
+		t.Errorf("Unexpected index settings after override: %v", index)
+	}
+
+	empty := map[string]interface{}{}
+	SetIndexSetting(empty, "number_of_shards", "1")
+	if _, ok := empty["settings"].(map[string]interface{})["index"]; !ok {
+		t.Errorf("Expected settings.index to be created, got %v", empty)
+	}
+}
+
+func TestAliasActions(t *testing.T) {
+	aliases := map[string]interface{}{
+		"logs-write": map[string]interface{}{"is_write_index": true},
+		"errors": map[string]interface{}{
+			"filter":  map[string]interface{}{"term": map[string]interface{}{"level": "error"}},
+			"routing": "1",
+		},
+	}
+
+	actions := AliasActions(aliases, "logs-v2")
+	expected := []map[string]interface{}{
+		{"add": map[string]interface{}{
+			"index":   "logs-v2",
+			"alias":   "errors",
+			"filter":  map[string]interface{}{"term": map[string]interface{}{"level": "error"}},
+			"routing": "1",
+		}},
+		{"add": map[string]interface{}{
+			"index":          "logs-v2",
+			"alias":          "logs-write",
+			"is_write_index": true,
+		}},
+	}
+
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("AliasActions() = %v, want %v", actions, expected)
+	}
+
+	if len(AliasActions(nil, "logs")) != 0 {
+		t.Error("Expected no actions without aliases")
+	}
+}
+
+func TestPipelines(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		expected []string
+	}{
+		{
+			name: "nested settings",
+			settings: map[string]interface{}{"index": map[string]interface{}{
+				"final_pipeline":   "audit",
+				"default_pipeline": "enrich",
+			}},
+			expected: []string{"audit", "enrich"},
+		},
+		{
+			name:     "flat settings",
+			settings: map[string]interface{}{"index.default_pipeline": "enrich"},
+			expected: []string{"enrich"},
+		},
+		{
+			name:     "none pipeline",
+			settings: map[string]interface{}{"index": map[string]interface{}{"default_pipeline": "_none"}},
+			expected: nil,
+		},
+		{
+			name:     "no settings",
+			settings: nil,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Pipelines(tt.settings); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Pipelines() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLifecyclePolicy(t *testing.T) {
+	nested := map[string]interface{}{"index": map[string]interface{}{
+		"lifecycle": map[string]interface{}{"name": "logs-policy", "rollover_alias": "logs"},
+	}}
+	if got := LifecyclePolicy(nested); got != "logs-policy" {
+		t.Errorf("LifecyclePolicy(nested) = %q, want logs-policy", got)
+	}
+
+	flat := map[string]interface{}{"index.lifecycle.name": "logs-policy"}
+	if got := LifecyclePolicy(flat); got != "logs-policy" {
+		t.Errorf("LifecyclePolicy(flat) = %q, want logs-policy", got)
+	}
+
+	if got := LifecyclePolicy(map[string]interface{}{"index": map[string]interface{}{}}); got != "" {
+		t.Errorf("Expected no policy, got %q", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Package ingest reads ingest pipelines from a cluster and puts them on a
+// destination, for the transfer and restore packages.
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// Client is the part of the Elasticsearch API used for ingest pipelines
+type Client interface {
+	IngestGetPipeline(o ...func(*esapi.IngestGetPipelineRequest)) (*esapi.Response, error)
+	IngestPutPipeline(id string, body io.Reader, o ...func(*esapi.IngestPutPipelineRequest)) (*esapi.Response, error)
+}
+
+// IsSystem reports whether a pipeline, as returned by GetPipelines, is
+// managed by the cluster
+func IsSystem(id string, pipeline interface{}) bool {
+	body, _ := pipeline.(map[string]interface{})
+	return cluster.IsSystem(id, body)
+}
+
+// GetPipelines returns the ingest pipelines matching ids (a comma-separated
+// list of ids or wildcard patterns) keyed by id. No match is not an error.
+func GetPipelines(client Client, ids string) (map[string]interface{}, error) {
+	res, err := client.IngestGetPipeline(func(r *esapi.IngestGetPipelineRequest) {
+		r.PipelineID = ids
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	pipelines := make(map[string]interface{})
+	if res.StatusCode == 404 {
+		return pipelines, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get pipeline failed: %s", res.String())
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&pipelines); err != nil {
+		return nil, err
+	}
+
+	return pipelines, nil
+}
+
+// SelectPipelines returns the sorted ids of the pipelines to put on the
+// destination. Pipelines that already exist are handled by opts.Existing;
+// with the default "fail" policy they are reported in an error.
+func SelectPipelines(client Client, pipelines map[string]interface{}, opts cluster.PutOptions) ([]string, error) {
+	if err := cluster.CheckExisting(opts.Existing, "pipeline"); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(pipelines))
+	for id := range pipelines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var selected []string
+	var conflicts []string
+	for _, id := range ids {
+		found, err := GetPipelines(client, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check ingest pipeline %s: %w", id, err)
+		}
+
+		if _, exists := found[id]; exists {
+			switch opts.Existing {
+			case "skip":
+				if opts.Verbose {
+					fmt.Printf("Skipping existing ingest pipeline %s\n", id)
+				}
+				continue
+			case "overwrite":
+			default:
+				conflicts = append(conflicts, id)
+			}
+		}
+
+		selected = append(selected, id)
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("ingest pipelines already exist: %s (use --existing=skip or --existing=overwrite)",
+			strings.Join(conflicts, ", "))
+	}
+
+	return selected, nil
+}
+
+// PutPipelines puts ingest pipelines on the destination. Nothing is put if
+// SelectPipelines fails for any of them.
+func PutPipelines(client Client, pipelines map[string]interface{}, opts cluster.PutOptions) error {
+	// Decide what to do with every pipeline before changing anything
+	selected, err := SelectPipelines(client, pipelines, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range selected {
+		if err := putPipeline(client, id, pipelines[id]); err != nil {
+			return fmt.Errorf("failed to put ingest pipeline %s: %w", id, err)
+		}
+
+		if opts.Verbose {
+			fmt.Printf("Put ingest pipeline %s\n", id)
+		}
+	}
+
+	fmt.Printf("Put %d ingest pipelines to %s\n", len(selected), opts.Destination)
+
+	return nil
+}
+
+// putPipeline creates or replaces an ingest pipeline
+func putPipeline(client Client, id string, pipeline interface{}) error {
+	data, err := json.Marshal(pipeline)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.IngestPutPipeline(id, strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("put pipeline failed: %s", res.String())
+	}
+
+	return nil
+}
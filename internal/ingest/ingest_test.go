@@ -0,0 +1,73 @@
+package ingest
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// fakeAPI serves the pipelines of existing and records the ids put
+type fakeAPI struct {
+	existing map[string]interface{}
+	put      []string
+}
+
+func (f *fakeAPI) IngestGetPipeline(o ...func(*esapi.IngestGetPipelineRequest)) (*esapi.Response, error) {
+	req := &esapi.IngestGetPipelineRequest{}
+	for _, opt := range o {
+		opt(req)
+	}
+
+	found := make(map[string]interface{})
+	for _, id := range strings.Split(req.PipelineID, ",") {
+		if pipeline, ok := f.existing[id]; ok {
+			found[id] = pipeline
+		}
+	}
+	if len(found) == 0 {
+		return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+	}
+
+	data, _ := json.Marshal(found)
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(string(data)))}, nil
+}
+
+func (f *fakeAPI) IngestPutPipeline(id string, body io.Reader, o ...func(*esapi.IngestPutPipelineRequest)) (*esapi.Response, error) {
+	f.put = append(f.put, id)
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"acknowledged":true}`))}, nil
+}
+
+func TestPutPipelines(t *testing.T) {
+	pipelines := map[string]interface{}{
+		"geoip":  map[string]interface{}{"processors": []interface{}{}},
+		"enrich": map[string]interface{}{"processors": []interface{}{}},
+	}
+
+	tests := []struct {
+		existing string
+		want     []string
+		wantErr  bool
+	}{
+		{existing: "fail", wantErr: true},
+		{existing: "skip", want: []string{"enrich"}},
+		{existing: "overwrite", want: []string{"enrich", "geoip"}},
+		{existing: "merge", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		api := &fakeAPI{existing: map[string]interface{}{"geoip": map[string]interface{}{}}}
+
+		err := PutPipelines(api, pipelines, cluster.PutOptions{Existing: tt.existing})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("PutPipelines(%s) error = %v, wantErr %v", tt.existing, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(api.put, tt.want) {
+			t.Errorf("PutPipelines(%s) put %v, want %v", tt.existing, api.put, tt.want)
+		}
+	}
+}
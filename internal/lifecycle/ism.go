@@ -0,0 +1,185 @@
+package lifecycle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// ISMClient talks to the Index State Management plugin of an OpenSearch
+// cluster. The Elasticsearch client refuses to connect to servers that are
+// not Elasticsearch, so ISM requests go through plain HTTP.
+type ISMClient struct {
+	URL      string
+	Username string
+	Password string
+	HTTP     *http.Client
+}
+
+// NewISMClient returns an ISM client for the cluster at url
+func NewISMClient(url, username, password string) *ISMClient {
+	return &ISMClient{
+		URL:      strings.TrimRight(url, "/"),
+		Username: username,
+		Password: password,
+		HTTP:     http.DefaultClient,
+	}
+}
+
+// ISMVersion identifies the revision of an ISM policy, which must be given to
+// replace it
+type ISMVersion struct {
+	SeqNo       int64
+	PrimaryTerm int64
+}
+
+// ismPageSize is the number of policies read per get policies request
+const ismPageSize = 1000
+
+// do sends a request with an optional JSON body and returns the status code
+// and body of the response
+func (c *ISMClient) do(method, path string, body interface{}) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.URL+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Username != "" && c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return res.StatusCode, data, nil
+}
+
+// GetISMPolicies returns all ISM policies of the cluster keyed by policy id,
+// as put policy request bodies: {"policy": {...}}, together with their
+// versions. The policy_id, last_updated_time and schema_version fields set by
+// the cluster are dropped.
+func GetISMPolicies(c *ISMClient) (map[string]interface{}, map[string]ISMVersion, error) {
+	policies := make(map[string]interface{})
+	versions := make(map[string]ISMVersion)
+
+	for from := 0; ; {
+		status, data, err := c.do(http.MethodGet, fmt.Sprintf("/_plugins/_ism/policies?from=%d&size=%d", from, ismPageSize), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if status == http.StatusNotFound {
+			return policies, versions, nil
+		}
+		if status >= 300 {
+			return nil, nil, fmt.Errorf("get ISM policies failed: [%d] %s", status, data)
+		}
+
+		var page struct {
+			Policies []struct {
+				ID          string                 `json:"_id"`
+				SeqNo       int64                  `json:"_seq_no"`
+				PrimaryTerm int64                  `json:"_primary_term"`
+				Policy      map[string]interface{} `json:"policy"`
+			} `json:"policies"`
+			Total int `json:"total_policies"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse ISM policies: %w", err)
+		}
+
+		for _, p := range page.Policies {
+			for _, field := range []string{"policy_id", "last_updated_time", "schema_version"} {
+				delete(p.Policy, field)
+			}
+			policies[p.ID] = map[string]interface{}{"policy": p.Policy}
+			versions[p.ID] = ISMVersion{SeqNo: p.SeqNo, PrimaryTerm: p.PrimaryTerm}
+		}
+
+		from += len(page.Policies)
+		if len(page.Policies) == 0 || from >= page.Total {
+			return policies, versions, nil
+		}
+	}
+}
+
+// PutISMPolicies puts ISM policies on the destination. Policies that already
+// exist are handled by opts.Existing as for ILM policies; nothing is put if
+// any of them conflicts.
+func PutISMPolicies(c *ISMClient, policies map[string]interface{}, opts cluster.PutOptions) error {
+	if err := cluster.CheckExisting(opts.Existing, "lifecycle"); err != nil {
+		return err
+	}
+
+	current, versions, err := GetISMPolicies(c)
+	if err != nil {
+		return fmt.Errorf("failed to get destination ISM policies: %w", err)
+	}
+
+	// Decide what to do with every policy before changing anything
+	selected, err := selectNames(policies, current, "ISM", opts)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range selected {
+		var version *ISMVersion
+		if v, ok := versions[id]; ok {
+			version = &v
+		}
+
+		if err := putISMPolicy(c, id, policies[id], version); err != nil {
+			return fmt.Errorf("failed to put ISM policy %s: %w", id, err)
+		}
+
+		if opts.Verbose {
+			fmt.Printf("Put ISM policy %s\n", id)
+		}
+	}
+
+	fmt.Printf("Put %d ISM policies to %s\n", len(selected), opts.Destination)
+
+	return nil
+}
+
+// putISMPolicy creates an ISM policy, or replaces the given version of it
+func putISMPolicy(c *ISMClient, id string, policy interface{}, version *ISMVersion) error {
+	path := "/_plugins/_ism/policies/" + url.PathEscape(id)
+	if version != nil {
+		path += fmt.Sprintf("?if_seq_no=%d&if_primary_term=%d", version.SeqNo, version.PrimaryTerm)
+	}
+
+	status, data, err := c.do(http.MethodPut, path, policy)
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return fmt.Errorf("put ISM policy failed: [%d] %s", status, data)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package lifecycle
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// fakeISM serves the ISM policies API of an OpenSearch cluster
+type fakeISM struct {
+	policies map[string]map[string]interface{}
+	put      []string
+}
+
+func (f *fakeISM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, password, _ := r.BasicAuth(); user != "admin" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/_plugins/_ism/policies")
+	id = strings.TrimPrefix(id, "/")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		ids := make([]string, 0, len(f.policies))
+		for id := range f.policies {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		// Serve one policy per page to exercise paging
+		from := 0
+		if r.URL.Query().Get("from") != "" {
+			json.Unmarshal([]byte(r.URL.Query().Get("from")), &from)
+		}
+		var page []interface{}
+		for _, id := range ids[min(from, len(ids)):min(from+1, len(ids))] {
+			policy := map[string]interface{}{"policy_id": id, "schema_version": 17, "last_updated_time": 1}
+			for k, v := range f.policies[id] {
+				policy[k] = v
+			}
+			page = append(page, map[string]interface{}{"_id": id, "_seq_no": 7, "_primary_term": 1, "policy": policy})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"policies": page, "total_policies": len(ids)})
+	case r.Method == http.MethodPut && id != "":
+		_, exists := f.policies[id]
+		versioned := r.URL.Query().Get("if_seq_no") == "7" && r.URL.Query().Get("if_primary_term") == "1"
+		if exists != versioned {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		var body map[string]map[string]interface{}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil || body["policy"] == nil || body["policy"]["policy_id"] != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.put = append(f.put, id)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGetISMPolicies(t *testing.T) {
+	fake := &fakeISM{policies: map[string]map[string]interface{}{
+		"hot-warm": {"default_state": "hot"},
+		"delete":   {"default_state": "delete"},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	policies, versions, err := GetISMPolicies(NewISMClient(server.URL, "admin", "secret"))
+	if err != nil {
+		t.Fatalf("GetISMPolicies failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"hot-warm": map[string]interface{}{"policy": map[string]interface{}{"default_state": "hot"}},
+		"delete":   map[string]interface{}{"policy": map[string]interface{}{"default_state": "delete"}},
+	}
+	if !reflect.DeepEqual(policies, want) {
+		t.Errorf("GetISMPolicies() = %v, want %v", policies, want)
+	}
+	if versions["delete"] != (ISMVersion{SeqNo: 7, PrimaryTerm: 1}) {
+		t.Errorf("Expected version 7/1 for delete, got %v", versions["delete"])
+	}
+}
+
+func TestPutISMPolicies(t *testing.T) {
+	policies := map[string]interface{}{
+		"hot-warm": map[string]interface{}{"policy": map[string]interface{}{"default_state": "hot"}},
+		"delete":   map[string]interface{}{"policy": map[string]interface{}{"default_state": "delete"}},
+	}
+
+	tests := []struct {
+		existing string
+		want     []string
+		wantErr  bool
+	}{
+		{existing: "fail", wantErr: true},
+		{existing: "skip", want: []string{"hot-warm"}},
+		{existing: "overwrite", want: []string{"delete", "hot-warm"}},
+	}
+
+	for _, tt := range tests {
+		fake := &fakeISM{policies: map[string]map[string]interface{}{"delete": {}}}
+		server := httptest.NewServer(fake)
+
+		err := PutISMPolicies(NewISMClient(server.URL, "admin", "secret"), policies, cluster.PutOptions{Existing: tt.existing})
+		server.Close()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("PutISMPolicies(%s) error = %v, wantErr %v", tt.existing, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(fake.put, tt.want) {
+			t.Errorf("PutISMPolicies(%s) put %v, want %v", tt.existing, fake.put, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package lifecycle reads ILM policies from a cluster and puts them on a
+// destination, for the transfer and restore packages.
+package lifecycle
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// Client is the part of the Elasticsearch API used for ILM policies
+type Client interface {
+	ILMGetLifecycle(o ...func(*esapi.ILMGetLifecycleRequest)) (*esapi.Response, error)
+	ILMPutLifecycle(policy string, o ...func(*esapi.ILMPutLifecycleRequest)) (*esapi.Response, error)
+}
+
+// IsSystem reports whether a policy, as returned by GetPolicies, is managed
+// by the cluster
+func IsSystem(name string, policy interface{}) bool {
+	body, _ := policy.(map[string]interface{})
+	inner, _ := body["policy"].(map[string]interface{})
+	return cluster.IsSystem(name, inner)
+}
+
+// GetPolicies returns all ILM policies of the cluster keyed by name, as put
+// lifecycle request bodies: {"policy": {...}}. The version, modified_date and
+// in_use_by fields of the get lifecycle API are dropped.
+func GetPolicies(client Client) (map[string]interface{}, error) {
+	res, err := client.ILMGetLifecycle()
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	policies := make(map[string]interface{})
+	if res.StatusCode == 404 {
+		return policies, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get lifecycle failed: %s", res.String())
+	}
+
+	var result map[string]map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	for name, entry := range result {
+		policies[name] = map[string]interface{}{"policy": entry["policy"]}
+	}
+
+	return policies, nil
+}
+
+// SelectPolicies returns the sorted names of the policies to put on the
+// destination. Policies that already exist are handled by opts.Existing;
+// with the default "fail" policy they are reported in an error.
+func SelectPolicies(client Client, policies map[string]interface{}, opts cluster.PutOptions) ([]string, error) {
+	if err := cluster.CheckExisting(opts.Existing, "lifecycle"); err != nil {
+		return nil, err
+	}
+
+	current, err := GetPolicies(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destination lifecycle policies: %w", err)
+	}
+
+	return selectNames(policies, current, "lifecycle", opts)
+}
+
+// PutPolicies puts ILM policies on the destination. Nothing is put if
+// SelectPolicies fails for any of them.
+func PutPolicies(client Client, policies map[string]interface{}, opts cluster.PutOptions) error {
+	// Decide what to do with every policy before changing anything
+	selected, err := SelectPolicies(client, policies, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range selected {
+		if err := putPolicy(client, name, policies[name]); err != nil {
+			return fmt.Errorf("failed to put lifecycle policy %s: %w", name, err)
+		}
+
+		if opts.Verbose {
+			fmt.Printf("Put lifecycle policy %s\n", name)
+		}
+	}
+
+	fmt.Printf("Put %d lifecycle policies to %s\n", len(selected), opts.Destination)
+
+	return nil
+}
+
+// putPolicy creates or replaces an ILM policy
+func putPolicy(client Client, name string, policy interface{}) error {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.ILMPutLifecycle(name, func(r *esapi.ILMPutLifecycleRequest) {
+		r.Body = strings.NewReader(string(data))
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("put lifecycle failed: %s", res.String())
+	}
+
+	return nil
+}
+
+// selectNames returns the sorted names of the policies to put, given the
+// policies of the destination; kind names the policies in messages
+func selectNames(policies, current map[string]interface{}, kind string, opts cluster.PutOptions) ([]string, error) {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var selected []string
+	var conflicts []string
+	for _, name := range names {
+		if _, exists := current[name]; exists {
+			switch opts.Existing {
+			case "skip":
+				if opts.Verbose {
+					fmt.Printf("Skipping existing %s policy %s\n", kind, name)
+				}
+				continue
+			case "overwrite":
+			default:
+				conflicts = append(conflicts, name)
+			}
+		}
+
+		selected = append(selected, name)
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%s policies already exist: %s (use --existing=skip or --existing=overwrite)",
+			kind, strings.Join(conflicts, ", "))
+	}
+
+	return selected, nil
+}
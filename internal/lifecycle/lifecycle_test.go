@@ -0,0 +1,77 @@
+package lifecycle
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// fakeAPI serves the policies of existing and records the names put
+type fakeAPI struct {
+	existing map[string]interface{}
+	put      []string
+}
+
+func (f *fakeAPI) ILMGetLifecycle(o ...func(*esapi.ILMGetLifecycleRequest)) (*esapi.Response, error) {
+	result := make(map[string]interface{})
+	for name, policy := range f.existing {
+		result[name] = map[string]interface{}{"version": 1, "policy": policy, "in_use_by": map[string]interface{}{}}
+	}
+
+	data, _ := json.Marshal(result)
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(string(data)))}, nil
+}
+
+func (f *fakeAPI) ILMPutLifecycle(policy string, o ...func(*esapi.ILMPutLifecycleRequest)) (*esapi.Response, error) {
+	f.put = append(f.put, policy)
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"acknowledged":true}`))}, nil
+}
+
+func TestGetPolicies(t *testing.T) {
+	api := &fakeAPI{existing: map[string]interface{}{"logs": map[string]interface{}{"phases": map[string]interface{}{}}}}
+
+	policies, err := GetPolicies(api)
+	if err != nil {
+		t.Fatalf("GetPolicies failed: %v", err)
+	}
+
+	want := map[string]interface{}{"logs": map[string]interface{}{"policy": map[string]interface{}{"phases": map[string]interface{}{}}}}
+	if !reflect.DeepEqual(policies, want) {
+		t.Errorf("GetPolicies() = %v, want %v", policies, want)
+	}
+}
+
+func TestPutPolicies(t *testing.T) {
+	policies := map[string]interface{}{
+		"logs":    map[string]interface{}{"policy": map[string]interface{}{}},
+		"metrics": map[string]interface{}{"policy": map[string]interface{}{}},
+	}
+
+	tests := []struct {
+		existing string
+		want     []string
+		wantErr  bool
+	}{
+		{existing: "", wantErr: true},
+		{existing: "skip", want: []string{"metrics"}},
+		{existing: "overwrite", want: []string{"logs", "metrics"}},
+		{existing: "merge", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		api := &fakeAPI{existing: map[string]interface{}{"logs": map[string]interface{}{}}}
+
+		err := PutPolicies(api, policies, cluster.PutOptions{Existing: tt.existing})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("PutPolicies(%q) error = %v, wantErr %v", tt.existing, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(api.put, tt.want) {
+			t.Errorf("PutPolicies(%q) put %v, want %v", tt.existing, api.put, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package rename maps source index names to destination index names using
+// an optional rename map file and an optional index name template.
+package rename
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// IndexPlaceholder is replaced with the source index name in templates
+const IndexPlaceholder = "{index}"
+
+// Renamer computes destination index names
+type Renamer struct {
+	template string
+	exact    map[string]string
+	patterns []rule
+}
+
+// rule is a rename map entry whose source contains a single '*' wildcard
+type rule struct {
+	prefix, suffix string
+	target         string
+}
+
+// New creates a Renamer from an index name template such as "{index}-v2" and
+// the path of a JSON rename map file. Both are optional.
+//
+// The rename map is a JSON object from source to destination names, e.g.
+// {"users": "users-v2", "prod-*": "staging-*"}. A '*' in the source matches
+// any sequence of characters, which is substituted for the '*' in the target.
+func New(template, mapFile string) (*Renamer, error) {
+	r := &Renamer{
+		template: template,
+		exact:    make(map[string]string),
+	}
+
+	if mapFile == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(mapFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rename map: %w", err)
+	}
+
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse rename map: %w", err)
+	}
+
+	if err := r.addRules(entries); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// addRules adds the entries of a rename map, splitting them into exact names
+// and wildcard patterns
+func (r *Renamer) addRules(entries map[string]string) error {
+	for source, target := range entries {
+		if source == "" || target == "" {
+			return fmt.Errorf("invalid rename map entry %q: %q", source, target)
+		}
+
+		switch strings.Count(source, "*") {
+		case 0:
+			r.exact[source] = target
+		case 1:
+			if strings.Count(target, "*") > 1 {
+				return fmt.Errorf("invalid rename map target %q: at most one '*' is allowed", target)
+			}
+			prefix, suffix, _ := strings.Cut(source, "*")
+			r.patterns = append(r.patterns, rule{prefix: prefix, suffix: suffix, target: target})
+		default:
+			return fmt.Errorf("invalid rename map source %q: at most one '*' is allowed", source)
+		}
+	}
+
+	// The most specific pattern wins when several match
+	sort.Slice(r.patterns, func(i, j int) bool {
+		li := len(r.patterns[i].prefix) + len(r.patterns[i].suffix)
+		lj := len(r.patterns[j].prefix) + len(r.patterns[j].suffix)
+		if li != lj {
+			return li > lj
+		}
+		return r.patterns[i].prefix+"*"+r.patterns[i].suffix < r.patterns[j].prefix+"*"+r.patterns[j].suffix
+	})
+
+	return nil
+}
+
+// Apply returns the destination name for a source index. Rename map entries
+// take precedence over the template; names matched by neither are returned
+// unchanged.
+func (r *Renamer) Apply(index string) string {
+	if r == nil {
+		return index
+	}
+
+	if target, ok := r.exact[index]; ok {
+		return target
+	}
+
+	for _, p := range r.patterns {
+		if len(index) < len(p.prefix)+len(p.suffix) ||
+			!strings.HasPrefix(index, p.prefix) || !strings.HasSuffix(index, p.suffix) {
+			continue
+		}
+		match := index[len(p.prefix) : len(index)-len(p.suffix)]
+		return strings.Replace(p.target, "*", match, 1)
+	}
+
+	if r.template != "" {
+		return strings.ReplaceAll(r.template, IndexPlaceholder, index)
+	}
+
+	return index
+}
@@ -0,0 +1,85 @@
+package rename
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRenameMap(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rename.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write rename map: %v", err)
+	}
+	return path
+}
+
+func TestApply(t *testing.T) {
+	mapFile := writeRenameMap(t, `{
+		"users": "users-v2",
+		"prod-*": "staging-*",
+		"prod-logs-*": "archive-logs-*",
+		"*-old": "legacy"
+	}`)
+
+	tests := []struct {
+		name     string
+		template string
+		index    string
+		expected string
+	}{
+		{"exact match", "", "users", "users-v2"},
+		{"prefix swap", "", "prod-orders", "staging-orders"},
+		{"most specific pattern wins", "", "prod-logs-2024", "archive-logs-2024"},
+		{"pattern without wildcard target", "", "metrics-old", "legacy"},
+		{"no match without template", "", "events", "events"},
+		{"template for unmatched index", "{index}-v2", "events", "events-v2"},
+		{"map takes precedence over template", "{index}-v2", "prod-orders", "staging-orders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(tt.template, mapFile)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if got := r.Apply(tt.index); got != tt.expected {
+				t.Errorf("Apply(%q) = %q, want %q", tt.index, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid JSON", `not json`},
+		{"multiple wildcards", `{"a-*-*": "b"}`},
+		{"empty target", `{"a": ""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New("", writeRenameMap(t, tt.content)); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+
+	if _, err := New("", "/nonexistent/rename.json"); err == nil {
+		t.Error("Expected error for missing rename map file")
+	}
+}
+
+func TestNoRules(t *testing.T) {
+	r, err := New("", "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if r.Apply("index") != "index" {
+		t.Error("Expected renamer without template or map to leave names unchanged")
+	}
+}
@@ -0,0 +1,72 @@
+package restore
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+// restoreAliasFile applies the aliases of a file written by the "aliases"
+// backup type in a single atomic _aliases request. The output URL may name
+// the index only when the file holds the aliases of a single index.
+func restoreAliasFile(config Config) error {
+	aliases, err := readJSONFile(config.Input, config)
+	if err != nil {
+		return fmt.Errorf("failed to read aliases file: %w", err)
+	}
+
+	index := extractIndex(config.Output)
+	if index != "" && len(aliases) > 1 {
+		return fmt.Errorf("aliases file holds %d indices, output URL must not name an index", len(aliases))
+	}
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	renamer, err := rename.New(config.OutputIndexTemplate, config.RenameMap)
+	if err != nil {
+		return err
+	}
+
+	actions := aliasFileActions(aliases, index, renamer)
+	if len(actions) == 0 {
+		fmt.Printf("No aliases found in %s\n", config.Input)
+		return nil
+	}
+
+	if err := cluster.UpdateAliases(destClient.API, actions); err != nil {
+		return fmt.Errorf("failed to restore aliases: %w", err)
+	}
+
+	fmt.Printf("Restored %d aliases to %s\n", len(actions), config.Output)
+
+	return nil
+}
+
+// aliasFileActions builds the _aliases actions for a get alias API body,
+// pointing the aliases at index, or at the renamed source index when index
+// is empty
+func aliasFileActions(aliases map[string]interface{}, index string, renamer *rename.Renamer) []map[string]interface{} {
+	sources := make([]string, 0, len(aliases))
+	for source := range aliases {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var actions []map[string]interface{}
+	for _, source := range sources {
+		dest := index
+		if dest == "" {
+			dest = renamer.Apply(source)
+		}
+
+		actions = append(actions, indexdef.AliasActions(indexdef.Entry(aliases, source, "aliases"), dest)...)
+	}
+
+	return actions
+}
@@ -0,0 +1,50 @@
+package restore
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+func TestAliasFileActions(t *testing.T) {
+	var aliases map[string]interface{}
+	body := `{
+		"logs-1": {"aliases": {"logs": {"is_write_index": true}, "errors": {"filter": {"term": {"level": "error"}}, "index_routing": "1"}}},
+		"logs-2": {"aliases": {"logs": {"is_write_index": false}}}
+	}`
+	if err := json.Unmarshal([]byte(body), &aliases); err != nil {
+		t.Fatalf("Failed to parse aliases: %v", err)
+	}
+
+	renamer, err := rename.New("{index}-v2", "")
+	if err != nil {
+		t.Fatalf("rename.New failed: %v", err)
+	}
+
+	actions := aliasFileActions(aliases, "", renamer)
+
+	var targets []string
+	for _, action := range actions {
+		add := action["add"].(map[string]interface{})
+		targets = append(targets, add["alias"].(string)+"@"+add["index"].(string))
+	}
+
+	expected := []string{"errors@logs-1-v2", "logs@logs-1-v2", "logs@logs-2-v2"}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Expected actions %v, got %v", expected, targets)
+	}
+
+	errors := actions[0]["add"].(map[string]interface{})
+	if errors["index_routing"] != "1" || errors["filter"] == nil {
+		t.Errorf("Expected filter and routing to be kept, got %v", errors)
+	}
+
+	single := aliasFileActions(map[string]interface{}{"logs-1": aliases["logs-1"]}, "restored", renamer)
+	for _, action := range single {
+		if index := action["add"].(map[string]interface{})["index"]; index != "restored" {
+			t.Errorf("Expected alias on explicit output index, got %v", index)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package restore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+// bulkBatchSize is the number of actions sent in one bulk request
+const bulkBatchSize = 500
+
+// bulkActions are the actions of the bulk API; all but delete are followed
+// by a body line
+var bulkActions = map[string]bool{"index": true, "create": true, "update": true, "delete": true}
+
+// bulkAction is an action line of a bulk file with its optional body line
+type bulkAction struct {
+	op   string
+	meta map[string]interface{}
+	body []byte
+}
+
+// parseBulkAction parses an action line such as {"index":{"_index":"a"}}
+func parseBulkAction(line []byte) (*bulkAction, bool) {
+	var action map[string]map[string]interface{}
+	if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+		return nil, false
+	}
+
+	for op, meta := range action {
+		if !bulkActions[op] || meta == nil {
+			return nil, false
+		}
+		return &bulkAction{op: op, meta: meta}, true
+	}
+
+	return nil, false
+}
+
+// leadingKey returns the first key of the JSON object starting line, or an
+// empty string if there is none. It only needs the start of the line, so it
+// tells the action of an action line too big to be parsed.
+func leadingKey(line []byte) string {
+	rest := bytes.TrimSpace(line)
+	if !bytes.HasPrefix(rest, []byte("{")) {
+		return ""
+	}
+
+	rest = bytes.TrimSpace(rest[1:])
+	if !bytes.HasPrefix(rest, []byte(`"`)) {
+		return ""
+	}
+
+	key, _, ok := bytes.Cut(rest[1:], []byte(`"`))
+	if !ok {
+		return ""
+	}
+	return string(key)
+}
+
+// isBulkFile reports whether the first line of reader is a bulk action line,
+// without consuming it
+func isBulkFile(reader *bufio.Reader) bool {
+	data, _ := reader.Peek(reader.Size())
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+
+	_, ok := parseBulkAction(bytes.TrimSpace(data))
+	return ok
+}
+
+// restoreBulk replays the actions of a bulk file (index, create, update and
+// delete) through the bulk API, config.Concurrency requests at a time. Actions
+// target index, or their renamed _index when index is empty, and index
+// actions on a data stream are sent as create. It returns the number of
+// actions that succeeded; failed ones are reported, and bulk requests that
+// failed as a whole are returned as an error.
+func restoreBulk(destClient *Client, reader *bufio.Reader, index string, renamer *rename.Renamer, config Config) (int, error) {
+	batches := make(chan []*bulkAction, config.Concurrency)
+	var wg sync.WaitGroup
+	var applied atomic.Int64
+	streams := newDataStreamWriter(destClient)
+
+	// Requests that failed as a whole are collected and returned once all
+	// are sent
+	var mu sync.Mutex
+	var requests int
+	var errs []error
+
+	for i := 0; i < config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				count, err := sendBulk(destClient, batch)
+				if err != nil {
+					fmt.Printf("Error sending bulk request: %v\n", err)
+				}
+				applied.Add(int64(count))
+
+				mu.Lock()
+				requests++
+				if err != nil {
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	err := readBulkActions(newLineReader(reader, maxDocumentSize(config)), func(action *bulkAction) (bool, error) {
+		destIndex := index
+		if destIndex == "" {
+			if name, ok := action.meta["_index"].(string); ok {
+				destIndex = renamer.Apply(name)
+			}
+		}
+		if destIndex != "" {
+			action.meta["_index"] = destIndex
+		}
+		delete(action.meta, "_type")
+
+		if (action.op == "index" || action.op == "create") && destIndex != "" {
+			stream, err := streams.isDataStream(destIndex)
+			if err != nil {
+				return false, fmt.Errorf("failed to check data stream %s: %w", destIndex, err)
+			}
+			if stream {
+				if !hasTimestamp(action.body) {
+					streams.missing.Report(destIndex)
+					return false, nil
+				}
+				action.op = "create"
+			}
+		}
+
+		return true, nil
+	}, func(batch []*bulkAction) {
+		batches <- batch
+	})
+	close(batches)
+	wg.Wait()
+
+	if err != nil {
+		return int(applied.Load()), err
+	}
+	if len(errs) > 0 {
+		return int(applied.Load()), fmt.Errorf("%d of %d bulk requests failed: %w", len(errs), requests, errs[0])
+	}
+
+	return int(applied.Load()), nil
+}
+
+// readBulkActions reads the actions of a bulk file, calls prepare on each of
+// them and passes those it keeps to send in batches of bulkBatchSize. Actions
+// whose action or body line is over the size cap of reader are reported and
+// skipped.
+func readBulkActions(reader *lineReader, prepare func(*bulkAction) (bool, error), send func([]*bulkAction)) error {
+	var batch []*bulkAction
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if oversized, ok := err.(*oversizedError); ok {
+			if err := skipBulkAction(reader, oversized); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read line %d: %w", reader.number, err)
+		}
+
+		action, ok := parseBulkAction(line)
+		if !ok {
+			return fmt.Errorf("invalid bulk action on line %d", reader.number)
+		}
+
+		if action.op != "delete" {
+			number := reader.number
+			action.body, err = readLine(reader)
+			if err == io.EOF {
+				return fmt.Errorf("missing body for the %s action on line %d", action.op, number)
+			}
+			if oversized, ok := err.(*oversizedError); ok {
+				fmt.Printf("Skipping the %s action on line %d: %v\n", action.op, number, oversized)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read line %d: %w", reader.number, err)
+			}
+		}
+
+		keep, err := prepare(action)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			continue
+		}
+
+		batch = append(batch, action)
+		if len(batch) == bulkBatchSize {
+			send(batch)
+			batch = nil
+		}
+	}
+
+	if len(batch) > 0 {
+		send(batch)
+	}
+
+	return nil
+}
+
+// skipBulkAction reports an action whose action line is over the size cap
+// and skips it, along with its body line unless it is a delete
+func skipBulkAction(reader *lineReader, oversized *oversizedError) error {
+	number := reader.number
+	op := leadingKey(oversized.head)
+	if !bulkActions[op] {
+		return fmt.Errorf("invalid bulk action on line %d", number)
+	}
+
+	fmt.Printf("Skipping the %s action on line %d: %v\n", op, number, oversized)
+	if op == "delete" {
+		return nil
+	}
+
+	_, err := readLine(reader)
+	if err == io.EOF {
+		return fmt.Errorf("missing body for the %s action on line %d", op, number)
+	}
+	if _, ok := err.(*oversizedError); ok {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read line %d: %w", reader.number, err)
+	}
+
+	return nil
+}
+
+// hasTimestamp reports whether the document body of a bulk action has an
+// @timestamp field
+func hasTimestamp(body []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return false
+	}
+	_, ok := fields[cluster.TimestampField]
+	return ok
+}
+
+// readLine returns the next non-empty line of reader without surrounding
+// whitespace
+func readLine(reader *lineReader) ([]byte, error) {
+	for {
+		line, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			return trimmed, nil
+		}
+	}
+}
+
+// sendBulk sends a batch of actions in one bulk request, reports the actions
+// that failed and returns the number of those that succeeded
+func sendBulk(client *Client, batch []*bulkAction) (int, error) {
+	var body bytes.Buffer
+	for _, action := range batch {
+		meta, err := json.Marshal(map[string]interface{}{action.op: action.meta})
+		if err != nil {
+			return 0, err
+		}
+		body.Write(meta)
+		body.WriteByte('\n')
+
+		if action.body != nil {
+			body.Write(action.body)
+			body.WriteByte('\n')
+		}
+	}
+
+	res, err := client.API.Bulk(&body)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("bulk request failed: %s", res.String())
+	}
+
+	var result struct {
+		Items []map[string]struct {
+			Index  string          `json:"_index"`
+			ID     string          `json:"_id"`
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to parse bulk response: %w", err)
+	}
+
+	succeeded := 0
+	for _, item := range result.Items {
+		for op, outcome := range item {
+			if outcome.Status >= 300 {
+				fmt.Printf("Error in %s of document %s in %s: %s\n", op, outcome.ID, outcome.Index, outcome.Error)
+				continue
+			}
+			succeeded++
+		}
+	}
+
+	return succeeded, nil
+}
@@ -0,0 +1,160 @@
+package restore
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+func TestParseBulkAction(t *testing.T) {
+	tests := []struct {
+		line string
+		op   string
+		ok   bool
+	}{
+		{`{"index": {"_index": "a", "_id": "1"}}`, "index", true},
+		{`{"create": {"_index": "a"}}`, "create", true},
+		{`{"update": {"_id": "1"}}`, "update", true},
+		{`{"delete": {"_index": "a", "_id": "1"}}`, "delete", true},
+		{`{"upsert": {"_index": "a"}}`, "", false},
+		{`{"_index": "a", "_id": "1", "_source": {}}`, "", false},
+		{`{"index": {}, "delete": {}}`, "", false},
+		{`[1, 2]`, "", false},
+	}
+
+	for _, tt := range tests {
+		action, ok := parseBulkAction([]byte(tt.line))
+		if ok != tt.ok {
+			t.Errorf("parseBulkAction(%s) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if ok && action.op != tt.op {
+			t.Errorf("parseBulkAction(%s) op = %s, want %s", tt.line, action.op, tt.op)
+		}
+	}
+}
+
+// writeBulkFile writes content to a bulk file and returns its path
+func writeBulkFile(t *testing.T, content string) string {
+	path := t.TempDir() + "/data.bulk"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write bulk file: %v", err)
+	}
+	return path
+}
+
+func TestRestoreFileBulk(t *testing.T) {
+	input := writeBulkFile(t, `{"index": {"_index": "logs", "_type": "_doc", "_id": "1"}}
+{"message": "one"}
+{"create": {"_index": "logs", "_id": "2"}}
+{"message": "two"}
+
+{"update": {"_index": "logs", "_id": "1"}}
+{"doc": {"message": "uno"}}
+{"delete": {"_index": "logs", "_id": "3"}}
+{"index": {"_index": "logs-app", "_id": "4"}}
+{"@timestamp": "2024-01-01T00:00:00Z"}
+`)
+
+	api := &MockElasticsearchAPI{DataStreams: map[string]string{"logs-app-v2": "logs"}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+	renamer, err := rename.New("{index}-v2", "")
+	if err != nil {
+		t.Fatalf("rename.New failed: %v", err)
+	}
+
+	count, err := restoreFile(client, input, "", renamer, Config{Concurrency: 2, Verbose: true})
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("Expected 5 actions applied, got %d", count)
+	}
+
+	if len(api.BulkRequests) != 1 {
+		t.Fatalf("Expected a single bulk request, got %d", len(api.BulkRequests))
+	}
+
+	expected := `{"index":{"_id":"1","_index":"logs-v2"}}
+{"message": "one"}
+{"create":{"_id":"2","_index":"logs-v2"}}
+{"message": "two"}
+{"update":{"_id":"1","_index":"logs-v2"}}
+{"doc": {"message": "uno"}}
+{"delete":{"_id":"3","_index":"logs-v2"}}
+{"create":{"_id":"4","_index":"logs-app-v2"}}
+{"@timestamp": "2024-01-01T00:00:00Z"}
+`
+	if api.BulkRequests[0] != expected {
+		t.Errorf("Unexpected bulk request:\n%s\nwant:\n%s", api.BulkRequests[0], expected)
+	}
+}
+
+func TestRestoreFileBulkExplicitIndex(t *testing.T) {
+	input := writeBulkFile(t, `{"index": {"_id": "1"}}
+{"message": "one"}
+`)
+
+	api := &MockElasticsearchAPI{}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if _, err := restoreFile(client, input, "target", nil, Config{Concurrency: 1, Verbose: true}); err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+
+	if len(api.BulkRequests) != 1 || !strings.Contains(api.BulkRequests[0], `"_index":"target"`) {
+		t.Errorf("Expected the action to target the output index, got %v", api.BulkRequests)
+	}
+}
+
+func TestRestoreFileBulkFailuresAndBatches(t *testing.T) {
+	var content strings.Builder
+	for i := 0; i < bulkBatchSize+10; i++ {
+		fmt.Fprintf(&content, "{\"index\": {\"_index\": \"logs\", \"_id\": \"%d\"}}\n{\"n\": %d}\n", i, i)
+	}
+	input := writeBulkFile(t, content.String())
+
+	api := &MockElasticsearchAPI{BulkFailures: map[string]bool{"7": true}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	count, err := restoreFile(client, input, "", nil, Config{Concurrency: 2, Verbose: true})
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+
+	if count != bulkBatchSize+9 {
+		t.Errorf("Expected %d actions applied, got %d", bulkBatchSize+9, count)
+	}
+	if len(api.BulkRequests) != 2 {
+		t.Errorf("Expected 2 bulk requests, got %d", len(api.BulkRequests))
+	}
+}
+
+func TestRestoreFileBulkRequestFailure(t *testing.T) {
+	input := writeBulkFile(t, `{"delete": {"_index": "logs", "_id": "1"}}
+{"delete": {"_index": "logs", "_id": "2"}}
+`)
+
+	api := &MockElasticsearchAPI{ShouldFail: true}
+	count, err := restoreFile(&Client{API: api, URL: "http://mock:9200"}, input, "", nil, Config{Concurrency: 1, Verbose: true})
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 bulk requests failed") {
+		t.Errorf("Expected the failed bulk request to be returned, got %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no actions applied, got %d", count)
+	}
+}
+
+func TestRestoreFileBulkMissingBody(t *testing.T) {
+	input := writeBulkFile(t, `{"delete": {"_index": "logs", "_id": "1"}}
+{"index": {"_index": "logs", "_id": "2"}}
+`)
+
+	_, err := restoreFile(createMockClient(), input, "", nil, Config{Concurrency: 1, Verbose: true})
+	if err == nil || !strings.Contains(err.Error(), "missing body") {
+		t.Errorf("Expected a missing body error, got %v", err)
+	}
+}
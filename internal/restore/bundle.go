@@ -0,0 +1,252 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/rename"
+	"github.com/schollz/progressbar/v3"
+)
+
+// maxBundleLine is the longest line always read from a bundle, whatever the
+// document size cap; definition lines carry whole mappings and can be much
+// longer than a document. Document lines are held to the cap once read.
+const maxBundleLine = 64 * 1024 * 1024
+
+// restoreBundle restores a bundle written by the "all" backup type. Every
+// index is created when its definition is read, its documents are indexed as
+// they follow, and the aliases are applied last once every index exists.
+func restoreBundle(config Config) error {
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("type all requires a cluster URL without index as output")
+	}
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	renamer, err := rename.New(config.OutputIndexTemplate, config.RenameMap)
+	if err != nil {
+		return err
+	}
+
+	return loadBundle(destClient, renamer, config)
+}
+
+// loadBundle restores the bundle file config.Input into the destination
+// cluster
+func loadBundle(destClient *Client, renamer *rename.Renamer, config Config) error {
+	file, size, err := openFile(config.Input, config)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	progress := &progressReader{reader: file}
+	decompressed, err := backup.Decompress(progress)
+	if err != nil {
+		return err
+	}
+
+	maxSize := maxDocumentSize(config)
+	reader := newLineReader(decompressed, max(maxSize, maxBundleLine))
+
+	header, err := readBundleHeader(reader)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]backup.IndexEntry, len(header.Indices))
+	for i, index := range header.Indices {
+		entries[i] = backup.IndexEntry{Name: index}
+	}
+
+	// Decide what to do with every index before changing anything
+	plans, err := planRestore(destClient, entries, renamer, config.Existing)
+	if err != nil {
+		return err
+	}
+
+	if err := restoreReferencedPipelines(destClient, backup.PipelinesFile(config.Input), config); err != nil {
+		return err
+	}
+
+	planned := make(map[string]*indexPlan, len(plans))
+	for i := range plans {
+		planned[plans[i].entry.Name] = &plans[i]
+	}
+
+	var bar *progressbar.ProgressBar
+	if !config.Verbose {
+		bar = progressbar.DefaultBytes(size, "Restoring indices")
+	}
+	progress.attach(bar)
+
+	workers := max(config.Concurrency, 1)
+	docChan := make(chan Document, workers*2)
+	counts := make(map[string]*atomic.Int64, len(plans))
+	for _, plan := range plans {
+		counts[plan.entry.Name] = new(atomic.Int64)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for doc := range docChan {
+				if err := indexDocument(destClient, planned[doc.Index].dest, doc); err != nil {
+					fmt.Printf("Error indexing document %s: %v\n", doc.ID, err)
+				} else {
+					counts[doc.Index].Add(1)
+				}
+			}
+		}()
+	}
+
+	var aliases []map[string]interface{}
+	readErr := func() error {
+		defer close(docChan)
+
+		for {
+			line, err := reader.next()
+			if err == io.EOF {
+				return nil
+			}
+			if oversized, ok := err.(*oversizedError); ok {
+				fmt.Printf("Skipping line %d: %v\n", reader.number, oversized)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if len(line) == 0 {
+				continue
+			}
+
+			var bundleLine backup.BundleLine
+			if err := json.Unmarshal(line, &bundleLine); err == nil && bundleLine.Definition != nil {
+				definition := bundleLine.Definition
+				plan, ok := planned[definition.Index]
+				if !ok {
+					continue
+				}
+
+				if err := createFromDefinition(destClient, plan, definition, config); err != nil {
+					return err
+				}
+				aliases = append(aliases, indexdef.AliasActions(definition.Aliases, plan.dest)...)
+				continue
+			}
+
+			if size := int64(len(line)); size > maxSize {
+				fmt.Printf("Skipping line %d: %v\n", reader.number, &oversizedError{size: size, max: maxSize})
+				continue
+			}
+
+			var doc Document
+			if err := json.Unmarshal(line, &doc); err != nil {
+				fmt.Printf("Error parsing document: %v\n", err)
+				continue
+			}
+
+			// Documents of skipped indices are dropped
+			if _, ok := planned[doc.Index]; !ok {
+				continue
+			}
+
+			docChan <- doc
+		}
+	}()
+
+	wg.Wait()
+
+	if bar != nil {
+		bar.Finish()
+	}
+
+	if readErr != nil {
+		return fmt.Errorf("failed to restore bundle: %w", readErr)
+	}
+	file.check()
+
+	if len(aliases) > 0 {
+		if err := cluster.UpdateAliases(destClient.API, aliases); err != nil {
+			return fmt.Errorf("failed to restore aliases: %w", err)
+		}
+	}
+
+	total := 0
+	for _, plan := range plans {
+		count := int(counts[plan.entry.Name].Load())
+		total += count
+
+		fmt.Printf("  %s -> %s: %d documents\n", plan.entry.Name, plan.dest, count)
+	}
+	fmt.Printf("Restored %d indices, %d documents and %d aliases to %s\n",
+		len(plans), total, len(aliases), config.Output)
+
+	return nil
+}
+
+// readBundleHeader reads and checks the first line of a bundle
+func readBundleHeader(reader *lineReader) (*backup.BundleHeader, error) {
+	data, err := reader.next()
+	if err == io.EOF {
+		return nil, fmt.Errorf("bundle is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	var line backup.BundleLine
+	if err := json.Unmarshal(data, &line); err != nil || line.Header == nil {
+		return nil, fmt.Errorf("input is not a bundle written by backup --type=all")
+	}
+
+	if line.Header.LayoutVersion > backup.LayoutVersion {
+		return nil, fmt.Errorf("unsupported bundle layout version %d", line.Header.LayoutVersion)
+	}
+
+	return line.Header, nil
+}
+
+// createFromDefinition creates the destination index of a bundled index,
+// deleting it first when it is being overwritten
+func createFromDefinition(client *Client, plan *indexPlan, definition *backup.IndexDefinition, config Config) error {
+	if plan.exists {
+		if err := deleteIndex(client, plan.dest); err != nil {
+			return err
+		}
+	}
+
+	body := definition.Create
+	if body == nil {
+		body = make(map[string]interface{})
+	}
+
+	if config.Shards != nil {
+		indexdef.SetIndexSetting(body, "number_of_shards", strconv.Itoa(*config.Shards))
+	}
+	if config.Replicas != nil {
+		indexdef.SetIndexSetting(body, "number_of_replicas", strconv.Itoa(*config.Replicas))
+	}
+
+	if err := cluster.CreateIndex(client.API, plan.dest, body); err != nil {
+		return err
+	}
+
+	if config.Verbose {
+		fmt.Printf("Created index %s\n", plan.dest)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package restore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+// writeBundle writes a bundle holding logs and users, one document each
+func writeBundle(t *testing.T) string {
+	t.Helper()
+
+	lines := []string{
+		`{"_bundle": {"layout_version": 1, "created_at": "2024-01-01T00:00:00Z", "source": "http://source:9200", "indices": ["logs", "users"]}}`,
+		`{"_definition": {"index": "logs", "create": {"settings": {"index": {"number_of_shards": "2"}}, "mappings": {"properties": {"message": {"type": "text"}}}}, "aliases": {"current": {}}, "documents": 1}}`,
+		`{"_index": "logs", "_id": "1", "_source": {"message": "hello"}}`,
+		`{"_definition": {"index": "users", "create": {"settings": {}, "mappings": {}}, "documents": 1}}`,
+		`{"_index": "users", "_id": "2", "_source": {"name": "jane"}}`,
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadBundle(t *testing.T) {
+	api := &MockElasticsearchAPI{}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	renamer, err := rename.New("{index}-restored", "")
+	if err != nil {
+		t.Fatalf("rename.New failed: %v", err)
+	}
+
+	replicas := 0
+	config := Config{
+		Input:       writeBundle(t),
+		Output:      "http://mock:9200",
+		Concurrency: 2,
+		Verbose:     true,
+		Replicas:    &replicas,
+	}
+
+	if err := loadBundle(client, renamer, config); err != nil {
+		t.Fatalf("loadBundle failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(api.CreatedIndices, []string{"logs-restored", "users-restored"}) {
+		t.Errorf("Expected logs-restored and users-restored to be created, got %v", api.CreatedIndices)
+	}
+	if !strings.Contains(api.CreatedBodies["logs-restored"], `"number_of_replicas":"0"`) {
+		t.Errorf("Expected replica override in create body, got %s", api.CreatedBodies["logs-restored"])
+	}
+
+	if len(api.AliasRequests) != 1 || !strings.Contains(api.AliasRequests[0], `"index":"logs-restored"`) {
+		t.Errorf("Expected one alias request for logs-restored, got %v", api.AliasRequests)
+	}
+}
+
+func TestLoadBundleDocumentSize(t *testing.T) {
+	api := &MockElasticsearchAPI{}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	// The cap holds for documents but not for the longer definition lines
+	config := Config{Input: writeBundle(t), Output: "http://mock:9200", Concurrency: 1, Verbose: true, MaxDocumentSize: 62}
+	if err := loadBundle(client, nil, config); err != nil {
+		t.Fatalf("loadBundle failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(api.CreatedIndices, []string{"logs", "users"}) {
+		t.Errorf("Expected logs and users to be created, got %v", api.CreatedIndices)
+	}
+	if _, ok := api.OpTypes["logs"]; ok {
+		t.Error("Expected the 63 bytes document of logs to be skipped")
+	}
+	if _, ok := api.OpTypes["users"]; !ok {
+		t.Error("Expected the document of users to be indexed")
+	}
+}
+
+func TestLoadBundleExisting(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		created  []string
+		deleted  []string
+		wantErr  bool
+	}{
+		{name: "fail on existing index", existing: "fail", wantErr: true},
+		{name: "skip existing index", existing: "skip", created: []string{"logs"}},
+		{name: "overwrite existing index", existing: "overwrite", created: []string{"logs", "users"}, deleted: []string{"users"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &MockElasticsearchAPI{ExistingIndices: map[string]bool{"users": true}}
+			client := &Client{API: api, URL: "http://mock:9200"}
+
+			config := Config{
+				Input:       writeBundle(t),
+				Output:      "http://mock:9200",
+				Concurrency: 1,
+				Verbose:     true,
+				Existing:    tt.existing,
+			}
+
+			err := loadBundle(client, nil, config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadBundle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(api.CreatedIndices, tt.created) {
+				t.Errorf("Expected created indices %v, got %v", tt.created, api.CreatedIndices)
+			}
+			if !reflect.DeepEqual(api.DeletedIndices, tt.deleted) {
+				t.Errorf("Expected deleted indices %v, got %v", tt.deleted, api.DeletedIndices)
+			}
+		})
+	}
+}
+
+func TestLoadBundleNotABundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, []byte(`{"_index": "logs", "_id": "1", "_source": {}}`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	client := &Client{API: &MockElasticsearchAPI{}, URL: "http://mock:9200"}
+	if err := loadBundle(client, nil, Config{Input: path, Verbose: true}); err == nil {
+		t.Error("Expected error for a file without bundle header")
+	}
+}
@@ -0,0 +1,268 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+// indexPlan describes how one index of a directory backup is restored
+type indexPlan struct {
+	entry  backup.IndexEntry
+	dest   string
+	exists bool
+}
+
+// restoreCluster restores a directory backup written by "backup --all". All
+// indices are created with their settings and mappings and all data streams
+// are recreated from their templates first, then their data is loaded, and
+// aliases are applied last once every index exists.
+func restoreCluster(config Config) error {
+	manifest, err := backup.ReadManifest(config.Input)
+	if err != nil {
+		return fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("--all requires a cluster URL without index as output")
+	}
+
+	config.verifier.expect(config.Input, manifest.Files)
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	renamer, err := rename.New(config.OutputIndexTemplate, config.RenameMap)
+	if err != nil {
+		return err
+	}
+
+	entries := selectEntries(manifest.Indices, config.Include, config.Exclude)
+	streamEntries := selectDataStreams(manifest.DataStreams, config.Include, config.Exclude)
+	if len(entries) == 0 && len(streamEntries) == 0 {
+		return fmt.Errorf("no indices selected for restore")
+	}
+
+	// Decide what to do with every index and data stream before changing
+	// anything
+	plans, err := planRestore(destClient, entries, renamer, config.Existing)
+	if err != nil {
+		return err
+	}
+
+	var streamPlans []dataStreamPlan
+	if len(streamEntries) > 0 {
+		streams, err := readDataStreams(filepath.Join(config.Input, backup.DataStreamsName), config)
+		if err != nil {
+			return err
+		}
+
+		taken := make(map[string]string)
+		for _, plan := range plans {
+			taken[plan.dest] = plan.entry.Name
+		}
+		streamPlans, err = planDataStreams(destClient, streamEntries, streams, taken, renamer, config.Existing)
+		if err != nil {
+			return err
+		}
+	}
+
+	if manifest.Pipelines != "" {
+		if err := restoreReferencedPipelines(destClient, filepath.Join(config.Input, manifest.Pipelines), config); err != nil {
+			return err
+		}
+	}
+
+	for _, plan := range plans {
+		if err := createFromBackup(destClient, config.Input, plan, config); err != nil {
+			return err
+		}
+		if config.Verbose {
+			fmt.Printf("Created index %s\n", plan.dest)
+		}
+	}
+	for _, plan := range streamPlans {
+		if err := createDataStreamFromBackup(destClient, plan, config); err != nil {
+			return err
+		}
+	}
+
+	total := 0
+	for _, plan := range plans {
+		count, err := restoreFile(destClient, filepath.Join(config.Input, plan.entry.Data), plan.dest, renamer, config)
+		if err != nil {
+			return fmt.Errorf("failed to restore data of %s: %w", plan.entry.Name, err)
+		}
+		total += count
+
+		fmt.Printf("Restored %d of %d documents into %s\n", count, plan.entry.Documents, plan.dest)
+	}
+	for _, plan := range streamPlans {
+		count, err := restoreFile(destClient, filepath.Join(config.Input, plan.entry.Data), plan.dest, renamer, config)
+		if err != nil {
+			return fmt.Errorf("failed to restore data of %s: %w", plan.entry.Name, err)
+		}
+		total += count
+
+		fmt.Printf("Restored %d of %d documents into data stream %s\n", count, plan.entry.Documents, plan.dest)
+	}
+
+	if err := restoreAliases(destClient, config.Input, plans, config); err != nil {
+		return fmt.Errorf("failed to restore aliases: %w", err)
+	}
+
+	fmt.Printf("Restored %d indices and %d data streams (%d documents) to %s\n",
+		len(plans), len(streamPlans), total, config.Output)
+
+	return nil
+}
+
+// selectEntries filters the indices of a backup with include and exclude
+// wildcard patterns matched against the backed up index names
+func selectEntries(entries []backup.IndexEntry, include, exclude []string) []backup.IndexEntry {
+	var selected []backup.IndexEntry
+	for _, entry := range entries {
+		if len(include) > 0 && !cluster.MatchesAny(entry.Name, include) {
+			continue
+		}
+		if cluster.MatchesAny(entry.Name, exclude) {
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+// planRestore resolves destination names and applies the existing index
+// policy. With the default "fail" policy nothing is restored if any
+// destination index already exists.
+func planRestore(client *Client, entries []backup.IndexEntry, renamer *rename.Renamer, existing string) ([]indexPlan, error) {
+	switch existing {
+	case "", "fail", "skip", "overwrite":
+	default:
+		return nil, fmt.Errorf("unsupported existing index policy: %s", existing)
+	}
+
+	var plans []indexPlan
+	var conflicts []string
+	sources := make(map[string]string)
+
+	for _, entry := range entries {
+		dest := renamer.Apply(entry.Name)
+		if other, ok := sources[dest]; ok {
+			return nil, fmt.Errorf("indices %s and %s would both be restored into %s", other, entry.Name, dest)
+		}
+		sources[dest] = entry.Name
+
+		exists, err := cluster.IndexExists(client.API, dest)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			switch existing {
+			case "skip":
+				fmt.Printf("Skipping existing index %s\n", dest)
+				continue
+			case "overwrite":
+			default:
+				conflicts = append(conflicts, dest)
+			}
+		}
+
+		plans = append(plans, indexPlan{entry: entry, dest: dest, exists: exists})
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("indices already exist: %s (use --existing=skip or --existing=overwrite)",
+			strings.Join(conflicts, ", "))
+	}
+
+	return plans, nil
+}
+
+// createFromBackup creates the destination index with the settings and
+// mappings of its backup, deleting it first when it is being overwritten
+func createFromBackup(client *Client, dir string, plan indexPlan, config Config) error {
+	mapping, err := readJSONFile(filepath.Join(dir, plan.entry.Mapping), config)
+	if err != nil {
+		return fmt.Errorf("failed to read mapping of %s: %w", plan.entry.Name, err)
+	}
+
+	settings, err := readJSONFile(filepath.Join(dir, plan.entry.Settings), config)
+	if err != nil {
+		return fmt.Errorf("failed to read settings of %s: %w", plan.entry.Name, err)
+	}
+
+	if plan.exists {
+		if err := deleteIndex(client, plan.dest); err != nil {
+			return err
+		}
+	}
+
+	body := indexdef.CreateBody(
+		indexdef.Entry(settings, plan.entry.Name, "settings"),
+		indexdef.Entry(mapping, plan.entry.Name, "mappings"),
+	)
+
+	return cluster.CreateIndex(client.API, plan.dest, body)
+}
+
+// restoreAliases applies the backed up aliases of all restored indices in a
+// single atomic _aliases request
+func restoreAliases(client *Client, dir string, plans []indexPlan, config Config) error {
+	var actions []map[string]interface{}
+
+	for _, plan := range plans {
+		aliases, err := readJSONFile(filepath.Join(dir, plan.entry.Aliases), config)
+		if err != nil {
+			return fmt.Errorf("failed to read aliases of %s: %w", plan.entry.Name, err)
+		}
+
+		actions = append(actions, indexdef.AliasActions(indexdef.Entry(aliases, plan.entry.Name, "aliases"), plan.dest)...)
+	}
+
+	if len(actions) == 0 {
+		return nil
+	}
+
+	return cluster.UpdateAliases(client.API, actions)
+}
+
+// deleteIndex deletes an index from the cluster
+func deleteIndex(client *Client, index string) error {
+	res, err := client.API.IndicesDelete([]string{index})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("delete index %s failed: %s", index, res.String())
+	}
+
+	return nil
+}
+
+// readJSONFile reads a JSON object from a file
+func readJSONFile(path string, config Config) (map[string]interface{}, error) {
+	data, err := readFile(path, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
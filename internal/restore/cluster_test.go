@@ -0,0 +1,190 @@
+package restore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+// writeIndexBackup writes the mapping, settings and aliases files of an index
+// into a directory backup and returns its manifest entry
+func writeIndexBackup(t *testing.T, dir, index string) backup.IndexEntry {
+	t.Helper()
+
+	entry := backup.IndexEntry{
+		Name:     index,
+		Mapping:  filepath.Join(index, backup.MappingName),
+		Settings: filepath.Join(index, backup.SettingsName),
+		Aliases:  filepath.Join(index, backup.AliasesName),
+		Data:     filepath.Join(index, backup.DataName("ndjson")),
+	}
+
+	files := map[string]string{
+		entry.Mapping:  `{"` + index + `": {"mappings": {"properties": {"message": {"type": "text"}}}}}`,
+		entry.Settings: `{"` + index + `": {"settings": {"index": {"number_of_shards": "2", "uuid": "abc", "provided_name": "` + index + `"}}}}`,
+		entry.Aliases:  `{"` + index + `": {"aliases": {"current": {"is_write_index": true}, "filtered": {"filter": {"term": {"level": "error"}}}}}}`,
+		entry.Data:     `{"_index": "` + index + `", "_id": "1", "_source": {"message": "hello"}}` + "\n",
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, index), 0755); err != nil {
+		t.Fatalf("Failed to create index directory: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	return entry
+}
+
+func TestSelectEntries(t *testing.T) {
+	entries := []backup.IndexEntry{{Name: "logs-1"}, {Name: "logs-2"}, {Name: "users"}}
+
+	selected := selectEntries(entries, []string{"logs-*"}, []string{"*-2"})
+	if len(selected) != 1 || selected[0].Name != "logs-1" {
+		t.Errorf("selectEntries() = %+v, want logs-1 only", selected)
+	}
+
+	if all := selectEntries(entries, nil, nil); len(all) != 3 {
+		t.Errorf("Expected all entries without patterns, got %d", len(all))
+	}
+}
+
+func TestPlanRestore(t *testing.T) {
+	entries := []backup.IndexEntry{{Name: "logs"}, {Name: "users"}}
+
+	tests := []struct {
+		name     string
+		existing string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "fail on existing index", existing: "fail", wantErr: true},
+		{name: "default policy fails", existing: "", wantErr: true},
+		{name: "skip existing index", existing: "skip", expected: []string{"logs"}},
+		{name: "overwrite existing index", existing: "overwrite", expected: []string{"logs", "users"}},
+		{name: "invalid policy", existing: "merge", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				API: &MockElasticsearchAPI{ExistingIndices: map[string]bool{"users": true}},
+				URL: "http://mock:9200",
+			}
+
+			plans, err := planRestore(client, entries, nil, tt.existing)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var dests []string
+			for _, plan := range plans {
+				dests = append(dests, plan.dest)
+			}
+			if !reflect.DeepEqual(dests, tt.expected) {
+				t.Errorf("Planned indices = %v, want %v", dests, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPlanRestoreRenameCollision(t *testing.T) {
+	renameMap := filepath.Join(t.TempDir(), "rename.json")
+	if err := os.WriteFile(renameMap, []byte(`{"logs-*": "logs"}`), 0644); err != nil {
+		t.Fatalf("Failed to write rename map: %v", err)
+	}
+	renamer, err := rename.New("", renameMap)
+	if err != nil {
+		t.Fatalf("Failed to create renamer: %v", err)
+	}
+
+	entries := []backup.IndexEntry{{Name: "logs-a"}, {Name: "logs-b"}}
+	if _, err := planRestore(createMockClient(), entries, renamer, "fail"); err == nil {
+		t.Error("Expected error when two indices are renamed to the same destination")
+	}
+}
+
+func TestCreateFromBackup(t *testing.T) {
+	dir := t.TempDir()
+	entry := writeIndexBackup(t, dir, "logs")
+
+	api := &MockElasticsearchAPI{ExistingIndices: map[string]bool{"logs-v2": true}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	plan := indexPlan{entry: entry, dest: "logs-v2", exists: true}
+	if err := createFromBackup(client, dir, plan, Config{}); err != nil {
+		t.Fatalf("createFromBackup failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(api.DeletedIndices, []string{"logs-v2"}) {
+		t.Errorf("Expected overwritten index to be deleted first, got %v", api.DeletedIndices)
+	}
+
+	var body map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(api.CreatedBodies["logs-v2"]), &body); err != nil {
+		t.Fatalf("Invalid create body: %v", err)
+	}
+	if _, ok := body["mappings"]["properties"]; !ok {
+		t.Errorf("Expected mappings in create body, got %v", body)
+	}
+	indexSettings := body["settings"]["index"].(map[string]interface{})
+	if _, ok := indexSettings["uuid"]; ok {
+		t.Error("Expected uuid to be removed from settings")
+	}
+	if indexSettings["number_of_shards"] != "2" {
+		t.Errorf("Expected number_of_shards to be kept, got %v", indexSettings["number_of_shards"])
+	}
+}
+
+func TestRestoreAliases(t *testing.T) {
+	dir := t.TempDir()
+	plans := []indexPlan{
+		{entry: writeIndexBackup(t, dir, "logs"), dest: "logs-v2"},
+		{entry: writeIndexBackup(t, dir, "users"), dest: "users"},
+	}
+
+	api := &MockElasticsearchAPI{}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := restoreAliases(client, dir, plans, Config{}); err != nil {
+		t.Fatalf("restoreAliases failed: %v", err)
+	}
+
+	if len(api.AliasRequests) != 1 {
+		t.Fatalf("Expected a single atomic aliases request, got %d", len(api.AliasRequests))
+	}
+
+	request := api.AliasRequests[0]
+	for _, expected := range []string{`"index":"logs-v2"`, `"is_write_index":true`, `"filter"`, `"index":"users"`} {
+		if !strings.Contains(request, expected) {
+			t.Errorf("Expected aliases request to contain %s, got %s", expected, request)
+		}
+	}
+}
+
+func TestRestoreFile(t *testing.T) {
+	dir := t.TempDir()
+	entry := writeIndexBackup(t, dir, "logs")
+
+	count, err := restoreFile(createMockClient(), filepath.Join(dir, entry.Data), "logs", nil, Config{Concurrency: 2, Verbose: true})
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 document to be restored, got %d", count)
+	}
+}
@@ -0,0 +1,271 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/rename"
+	"github.com/lilmonk/elasticdump/internal/templates"
+)
+
+// createMissingDataStreams creates every data stream listed in a data streams
+// file (as written next to a data backup) that does not yet exist on the
+// destination. The backed up templates are put first unless they already
+// exist, so that the data stream is created from its own index template. A
+// missing file is not an error: the backup then holds no data streams.
+func createMissingDataStreams(client *Client, streamsFile string, renamer *rename.Renamer, config Config) error {
+	streams, err := readDataStreams(streamsFile, config)
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		dest := renamer.Apply(stream.Name)
+
+		exists, err := cluster.IsDataStream(client.API, dest)
+		if err != nil {
+			return fmt.Errorf("failed to check data stream %s: %w", dest, err)
+		}
+		if exists {
+			continue
+		}
+
+		if err := createDataStream(client, stream, dest, config); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createDataStream puts the backed up templates of a data stream unless they
+// already exist, then creates the data stream as dest from its own index
+// template
+func createDataStream(client *Client, stream backup.DataStream, dest string, config Config) error {
+	if stream.Templates == nil {
+		return fmt.Errorf("data streams file holds no templates for %s", stream.Name)
+	}
+
+	templateOptions := putOptions(config)
+	if templateOptions.Existing != "overwrite" {
+		templateOptions.Existing = "skip"
+	}
+	if err := templates.Put(client.API, stream.Templates, templateOptions); err != nil {
+		return fmt.Errorf("failed to put templates of data stream %s: %w", stream.Name, err)
+	}
+
+	if err := cluster.CreateDataStream(client.API, dest); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created data stream %s from index template %s\n", dest, stream.Template)
+	return nil
+}
+
+// dataStreamPlan describes how one data stream of a directory backup is
+// restored
+type dataStreamPlan struct {
+	entry  backup.DataStreamEntry
+	stream backup.DataStream
+	dest   string
+	exists bool
+}
+
+// selectDataStreams filters the data streams of a backup with include and
+// exclude wildcard patterns matched against the backed up names
+func selectDataStreams(entries []backup.DataStreamEntry, include, exclude []string) []backup.DataStreamEntry {
+	var selected []backup.DataStreamEntry
+	for _, entry := range entries {
+		if len(include) > 0 && !cluster.MatchesAny(entry.Name, include) {
+			continue
+		}
+		if cluster.MatchesAny(entry.Name, exclude) {
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+// planDataStreams resolves destination names of data streams, checks them
+// against their index templates and applies the existing index policy like
+// planRestore does for indices. taken maps the destinations of the restored
+// indices to their backed up names, so that nothing is restored twice into
+// the same name.
+func planDataStreams(client *Client, entries []backup.DataStreamEntry, streams []backup.DataStream, taken map[string]string, renamer *rename.Renamer, existing string) ([]dataStreamPlan, error) {
+	definitions := make(map[string]backup.DataStream)
+	for _, stream := range streams {
+		definitions[stream.Name] = stream
+	}
+
+	var plans []dataStreamPlan
+	var conflicts []string
+
+	for _, entry := range entries {
+		stream, ok := definitions[entry.Name]
+		if !ok {
+			return nil, fmt.Errorf("data streams file holds no definition for %s", entry.Name)
+		}
+
+		dest := renamer.Apply(entry.Name)
+		if err := stream.CheckDestination(dest); err != nil {
+			return nil, err
+		}
+		if other, ok := taken[dest]; ok {
+			return nil, fmt.Errorf("%s and %s would both be restored into %s", other, entry.Name, dest)
+		}
+		taken[dest] = entry.Name
+
+		exists, err := cluster.IsDataStream(client.API, dest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check data stream %s: %w", dest, err)
+		}
+
+		if exists {
+			switch existing {
+			case "skip":
+				fmt.Printf("Skipping existing data stream %s\n", dest)
+				continue
+			case "overwrite":
+			default:
+				conflicts = append(conflicts, dest)
+			}
+		}
+
+		plans = append(plans, dataStreamPlan{entry: entry, stream: stream, dest: dest, exists: exists})
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("data streams already exist: %s (use --existing=skip or --existing=overwrite)",
+			strings.Join(conflicts, ", "))
+	}
+
+	return plans, nil
+}
+
+// createDataStreamFromBackup recreates a backed up data stream from its
+// templates, deleting it first when it is being overwritten
+func createDataStreamFromBackup(client *Client, plan dataStreamPlan, config Config) error {
+	if plan.exists {
+		if err := deleteDataStream(client, plan.dest); err != nil {
+			return err
+		}
+	}
+
+	return createDataStream(client, plan.stream, plan.dest, config)
+}
+
+// deleteDataStream deletes a data stream that is being overwritten, along
+// with its backing indices
+func deleteDataStream(client *Client, name string) error {
+	res, err := client.API.IndicesDeleteDataStream([]string{name})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("delete data stream %s failed: %s", name, res.String())
+	}
+
+	return nil
+}
+
+// readDataStreams reads a data streams file; a missing file holds no data
+// streams
+func readDataStreams(streamsFile string, config Config) ([]backup.DataStream, error) {
+	data, err := readFile(streamsFile, config)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data streams file: %w", err)
+	}
+
+	var streams []backup.DataStream
+	if err := json.Unmarshal(data, &streams); err != nil {
+		return nil, fmt.Errorf("failed to parse data streams file: %w", err)
+	}
+
+	return streams, nil
+}
+
+// checkDataStreamRenames returns an error if a data stream of a data streams
+// file is renamed to a name its backed up index template does not match, so
+// that it is reported before anything is created
+func checkDataStreamRenames(streamsFile string, renamer *rename.Renamer, config Config) error {
+	streams, err := readDataStreams(streamsFile, config)
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		if err := stream.CheckDestination(renamer.Apply(stream.Name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dataStreamWriter writes documents with the op_type their destination
+// requires, looking up once per destination whether it is a data stream.
+// Documents without an @timestamp field are skipped for data streams.
+type dataStreamWriter struct {
+	client  *Client
+	missing cluster.MissingTimestamps
+
+	mu      sync.Mutex
+	streams map[string]bool
+}
+
+// newDataStreamWriter returns a dataStreamWriter for the destination client
+func newDataStreamWriter(client *Client) *dataStreamWriter {
+	return &dataStreamWriter{client: client, streams: make(map[string]bool)}
+}
+
+// write indexes doc into index, with create semantics if index is a data
+// stream, and reports whether doc was written
+func (w *dataStreamWriter) write(index string, doc Document) (bool, error) {
+	stream, err := w.isDataStream(index)
+	if err != nil {
+		return false, fmt.Errorf("failed to check data stream %s: %w", index, err)
+	}
+
+	if !stream {
+		return true, indexDocument(w.client, index, doc)
+	}
+	if _, ok := doc.Source[cluster.TimestampField]; !ok {
+		w.missing.Report(index)
+		return false, nil
+	}
+	return true, createDocument(w.client, index, doc)
+}
+
+// isDataStream reports whether index is a data stream on the destination.
+// The lookup runs without the lock so that other workers are not held up;
+// concurrent misses for the same index may look it up more than once.
+func (w *dataStreamWriter) isDataStream(index string) (bool, error) {
+	w.mu.Lock()
+	stream, ok := w.streams[index]
+	w.mu.Unlock()
+	if ok {
+		return stream, nil
+	}
+
+	stream, err := cluster.IsDataStream(w.client.API, index)
+	if err != nil {
+		return false, err
+	}
+
+	w.mu.Lock()
+	w.streams[index] = stream
+	w.mu.Unlock()
+
+	return stream, nil
+}
@@ -0,0 +1,221 @@
+package restore
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+func TestCreateMissingDataStreams(t *testing.T) {
+	dataFile := t.TempDir() + "/logs.ndjson"
+	streams := `[
+		{"name": "logs-app", "template": "logs", "templates": {
+			"component_templates": [{"name": "logs-settings", "component_template": {"template": {}}}],
+			"index_templates": [{"name": "logs", "index_template": {"index_patterns": ["logs-*"], "data_stream": {}, "composed_of": ["logs-settings"]}}]
+		}},
+		{"name": "logs-web", "template": "logs", "templates": {}}
+	]`
+	if err := os.WriteFile(backup.DataStreamsFile(dataFile), []byte(streams), 0644); err != nil {
+		t.Fatalf("Failed to write data streams file: %v", err)
+	}
+
+	api := &MockElasticsearchAPI{
+		DataStreams:       map[string]string{"logs-web": "logs"},
+		ExistingTemplates: map[string]bool{"component/logs-settings": true},
+	}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	renamer, err := rename.New("", "")
+	if err != nil {
+		t.Fatalf("rename.New failed: %v", err)
+	}
+
+	if err := createMissingDataStreams(client, backup.DataStreamsFile(dataFile), renamer, Config{}); err != nil {
+		t.Fatalf("createMissingDataStreams failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(api.PutTemplates, []string{"index/logs"}) {
+		t.Errorf("Expected only the missing index template to be put, got %v", api.PutTemplates)
+	}
+	if !reflect.DeepEqual(api.CreatedDataStreams, []string{"logs-app"}) {
+		t.Errorf("Expected only logs-app to be created, got %v", api.CreatedDataStreams)
+	}
+}
+
+func TestCreateMissingDataStreamsNoFile(t *testing.T) {
+	renamer, _ := rename.New("", "")
+	if err := createMissingDataStreams(createMockClient(), t.TempDir()+"/missing.json", renamer, Config{}); err != nil {
+		t.Errorf("Expected a missing data streams file to be ignored, got %v", err)
+	}
+}
+
+func TestRestoreFileIntoDataStream(t *testing.T) {
+	input := t.TempDir() + "/logs.ndjson"
+	content := `{"_index": "logs-app", "_id": "1", "_source": {"@timestamp": "2024-01-01T00:00:00Z"}}
+{"_index": "plain", "_id": "2", "_source": {"field": "value"}}
+{"_index": "logs-app", "_id": "3", "_source": {"field": "value"}}
+`
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	api := &MockElasticsearchAPI{DataStreams: map[string]string{"logs-app": "logs"}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+	renamer, _ := rename.New("", "")
+
+	count, err := restoreFile(client, input, "", renamer, Config{Concurrency: 2, Verbose: true})
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+	// The data stream document without @timestamp is skipped
+	if count != 2 {
+		t.Errorf("Expected 2 documents, got %d", count)
+	}
+	if api.OpTypes["logs-app"] != "create" {
+		t.Errorf("Expected op_type create for the data stream, got %q", api.OpTypes["logs-app"])
+	}
+	if api.OpTypes["plain"] != "" {
+		t.Errorf("Expected default op_type for the index, got %q", api.OpTypes["plain"])
+	}
+}
+
+func TestRestoreFileDataStreamLookupForbidden(t *testing.T) {
+	input := t.TempDir() + "/plain.ndjson"
+	content := `{"_index": "plain", "_id": "1", "_source": {"field": "value"}}
+{"_index": "plain", "_id": "2", "_source": {"field": "value"}}
+`
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	// A user without view_index_metadata gets 403 from the data stream API
+	api := &MockElasticsearchAPI{DataStreamStatus: 403}
+	client := &Client{API: api, URL: "http://mock:9200"}
+	renamer, _ := rename.New("", "")
+
+	count, err := restoreFile(client, input, "", renamer, Config{Concurrency: 1, Verbose: true})
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 documents, got %d", count)
+	}
+	if api.OpTypes["plain"] != "" {
+		t.Errorf("Expected default op_type for the index, got %q", api.OpTypes["plain"])
+	}
+}
+
+func TestCheckDataStreamRenames(t *testing.T) {
+	dataFile := t.TempDir() + "/logs.ndjson"
+	streams := `[{"name": "logs-app", "template": "logs", "templates": {
+		"index_templates": [{"name": "logs", "index_template": {"index_patterns": ["logs-*"], "data_stream": {}}}]
+	}}]`
+	if err := os.WriteFile(backup.DataStreamsFile(dataFile), []byte(streams), 0644); err != nil {
+		t.Fatalf("Failed to write data streams file: %v", err)
+	}
+
+	// Only a destination name the index template matches can be created
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{"", false},
+		{"{index}-copy", false},
+		{"archive-{index}", true},
+	}
+
+	for _, tt := range tests {
+		renamer, err := rename.New(tt.template, "")
+		if err != nil {
+			t.Fatalf("rename.New failed: %v", err)
+		}
+		err = checkDataStreamRenames(backup.DataStreamsFile(dataFile), renamer, Config{})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkDataStreamRenames(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPlanDataStreams(t *testing.T) {
+	entries := []backup.DataStreamEntry{{Name: "logs-app"}, {Name: "logs-web"}}
+	streams := []backup.DataStream{
+		{Name: "logs-app", Template: "logs"},
+		{Name: "logs-web", Template: "logs"},
+	}
+
+	tests := []struct {
+		name     string
+		existing string
+		taken    map[string]string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "fail on existing data stream", existing: "fail", wantErr: true},
+		{name: "skip existing data stream", existing: "skip", expected: []string{"logs-app"}},
+		{name: "overwrite existing data stream", existing: "overwrite", expected: []string{"logs-app", "logs-web"}},
+		{name: "collides with an index", existing: "skip", taken: map[string]string{"logs-app": "logs-app"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				API: &MockElasticsearchAPI{DataStreams: map[string]string{"logs-web": "logs"}},
+				URL: "http://mock:9200",
+			}
+			taken := tt.taken
+			if taken == nil {
+				taken = make(map[string]string)
+			}
+
+			plans, err := planDataStreams(client, entries, streams, taken, nil, tt.existing)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var dests []string
+			for _, plan := range plans {
+				dests = append(dests, plan.dest)
+			}
+			if !reflect.DeepEqual(dests, tt.expected) {
+				t.Errorf("Planned data streams = %v, want %v", dests, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCreateDataStreamFromBackup(t *testing.T) {
+	var stream backup.DataStream
+	if err := json.Unmarshal([]byte(`{"name": "logs-app", "template": "logs", "templates": {
+		"index_templates": [{"name": "logs", "index_template": {"index_patterns": ["logs-*"], "data_stream": {}}}]
+	}}`), &stream); err != nil {
+		t.Fatalf("Failed to parse data stream: %v", err)
+	}
+
+	api := &MockElasticsearchAPI{DataStreams: map[string]string{"logs-app": "logs"}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	plan := dataStreamPlan{entry: backup.DataStreamEntry{Name: "logs-app"}, stream: stream, dest: "logs-app", exists: true}
+	if err := createDataStreamFromBackup(client, plan, Config{Existing: "overwrite"}); err != nil {
+		t.Fatalf("createDataStreamFromBackup failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(api.DeletedDataStreams, []string{"logs-app"}) {
+		t.Errorf("Expected the existing data stream to be deleted, got %v", api.DeletedDataStreams)
+	}
+	if !reflect.DeepEqual(api.PutTemplates, []string{"index/logs"}) {
+		t.Errorf("Expected the index template to be put, got %v", api.PutTemplates)
+	}
+	if !reflect.DeepEqual(api.CreatedDataStreams, []string{"logs-app"}) {
+		t.Errorf("Expected logs-app to be recreated, got %v", api.CreatedDataStreams)
+	}
+}
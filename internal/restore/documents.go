@@ -0,0 +1,210 @@
+package restore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// progressReader adds the bytes read from a file to a progress bar, so that
+// progress is measured on the file as stored, compressed or not
+type progressReader struct {
+	reader io.Reader
+	bar    *progressbar.ProgressBar
+	read   int64
+}
+
+// Read implements io.Reader
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.bar != nil {
+		r.bar.Add(n)
+	}
+	return n, err
+}
+
+// attach starts reporting on bar, including the bytes already read
+func (r *progressReader) attach(bar *progressbar.ProgressBar) {
+	r.bar = bar
+	if bar != nil {
+		bar.Add64(r.read)
+	}
+}
+
+// readDocuments reads the documents of a data file and calls fn with each of
+// them. The format is detected
+// from the first character: a file starting with "[" is a JSON array, which
+// is decoded one element at a time, and anything else is NDJSON. Lines that
+// are not valid JSON and documents over maxSize bytes are reported and
+// skipped; a malformed array stops the read with an error.
+func readDocuments(r io.Reader, maxSize int64, fn func(doc Document)) error {
+	reader := bufio.NewReader(r)
+
+	first, err := peekNonSpace(reader)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if first == '[' {
+		return readArray(reader, maxSize, fn)
+	}
+	return readLines(reader, maxSize, fn)
+}
+
+// peekNonSpace skips leading whitespace and returns the next byte without
+// consuming it
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// readArray decodes the elements of a JSON array of documents one at a time,
+// reader being positioned on the opening bracket. Each element is read with
+// the same size cap as a line: a bigger one is consumed without being held in
+// memory, reported and skipped.
+func readArray(reader *bufio.Reader, maxSize int64, fn func(doc Document)) error {
+	reader.ReadByte()
+
+	read := 0
+	for {
+		next, err := peekNonSpace(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read array end: %w", unexpectedEOF(err))
+		}
+		if next == ']' {
+			reader.ReadByte()
+			return nil
+		}
+
+		if read > 0 {
+			if next != ',' {
+				return fmt.Errorf("failed to parse document %d: expected , or ] after a document, found %q", read+1, next)
+			}
+			reader.ReadByte()
+			if next, err = peekNonSpace(reader); err != nil {
+				return fmt.Errorf("failed to parse document %d: %w", read+1, unexpectedEOF(err))
+			}
+		}
+		if next != '{' {
+			return fmt.Errorf("failed to parse document %d: expected an object, found %q", read+1, next)
+		}
+
+		raw, size, err := readObject(reader, maxSize)
+		if err != nil {
+			return fmt.Errorf("failed to parse document %d: %w", read+1, err)
+		}
+		read++
+
+		if size > maxSize {
+			fmt.Printf("Skipping document %d: %v\n", read, &oversizedError{size: size, max: maxSize})
+			continue
+		}
+
+		var doc Document
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return fmt.Errorf("failed to parse document %d: %w", read, err)
+		}
+
+		fn(doc)
+	}
+}
+
+// readObject reads the JSON object starting at the next byte of reader by
+// matching its braces, and returns it with its size. An object over maxSize
+// bytes is consumed without being kept and returned as nil; its content is
+// then not validated.
+func readObject(reader *bufio.Reader, maxSize int64) ([]byte, int64, error) {
+	var object []byte
+	var size int64
+	depth := 0
+	inString, escaped := false, false
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, size, unexpectedEOF(err)
+		}
+		size++
+		if size <= maxSize {
+			object = append(object, b)
+		} else {
+			object = nil
+		}
+
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+		case b == '}' || b == ']':
+			depth--
+			if depth == 0 {
+				return object, size, nil
+			}
+		}
+	}
+}
+
+// unexpectedEOF turns io.EOF in the middle of an array into
+// io.ErrUnexpectedEOF
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readLines parses one document per non-empty line
+func readLines(r io.Reader, maxSize int64, fn func(doc Document)) error {
+	reader := newLineReader(r, maxSize)
+	for {
+		line, err := reader.next()
+		if err == io.EOF {
+			return nil
+		}
+		if oversized, ok := err.(*oversizedError); ok {
+			fmt.Printf("Skipping document on line %d: %v\n", reader.number, oversized)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		var doc Document
+		if err := json.Unmarshal(line, &doc); err != nil {
+			fmt.Printf("Error parsing document on line %d: %v\n", reader.number, err)
+			continue
+		}
+
+		fn(doc)
+	}
+}
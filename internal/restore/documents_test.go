@@ -0,0 +1,204 @@
+package restore
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/schollz/progressbar/v3"
+)
+
+func TestReadDocuments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		ids     []string
+	}{
+		{
+			name:    "ndjson",
+			content: "{\"_index\":\"a\",\"_id\":\"1\",\"_source\":{}}\n\n{\"_index\":\"a\",\"_id\":\"2\",\"_source\":{}}\n",
+			ids:     []string{"1", "2"},
+		},
+		{
+			name:    "json array",
+			content: "[\n{\"_index\":\"a\",\"_id\":\"1\",\"_source\":{}},\n{\"_index\":\"a\",\"_id\":\"2\",\"_source\":{}}\n]\n",
+			ids:     []string{"1", "2"},
+		},
+		{
+			name: "pretty json array",
+			content: `  [
+  {
+    "_index": "a",
+    "_id": "1",
+    "_source": {"field": [1, 2]}
+  }
+]`,
+			ids: []string{"1"},
+		},
+		{
+			name:    "empty array",
+			content: "[]\n",
+		},
+		{
+			name:    "empty file",
+			content: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			err := readDocuments(strings.NewReader(tt.content), defaultMaxDocumentSize, func(doc Document) {
+				ids = append(ids, doc.ID)
+			})
+			if err != nil {
+				t.Fatalf("readDocuments failed: %v", err)
+			}
+
+			if strings.Join(ids, ",") != strings.Join(tt.ids, ",") {
+				t.Errorf("Expected ids %v, got %v", tt.ids, ids)
+			}
+		})
+	}
+}
+
+func TestReadDocumentsMalformedArray(t *testing.T) {
+	content := `[{"_index":"a","_id":"1","_source":{}}, {"_index": ]`
+
+	count := 0
+	err := readDocuments(strings.NewReader(content), defaultMaxDocumentSize, func(doc Document) { count++ })
+	if err == nil || !strings.Contains(err.Error(), "document 2") {
+		t.Errorf("Expected a parse error for document 2, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the first document to be read, got %d", count)
+	}
+}
+
+func TestRestoreFileJSONArray(t *testing.T) {
+	input := t.TempDir() + "/data.json"
+	content := `[
+  {"_index": "test-index", "_id": "1", "_source": {"field": "a"}},
+  {"_index": "test-index", "_id": "2", "_source": {"field": "b"}}
+]
+`
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	count, err := restoreFile(createMockClient(), input, "test-index", nil, Config{Concurrency: 2, Verbose: true})
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 documents restored, got %d", count)
+	}
+}
+
+func TestRestoreFileGzip(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "backup.ndjson.gz")
+	file, err := os.Create(input)
+	if err != nil {
+		t.Fatalf("Failed to create input: %v", err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte(`{"_index": "test-index", "_id": "1", "_source": {"field": "a"}}
+{"_index": "test-index", "_id": "2", "_source": {"field": "b"}}
+`))
+	gz.Close()
+	file.Close()
+
+	// Not verbose, so that the progress bar reads through the compressed file
+	count, err := restoreFile(createMockClient(), input, "test-index", nil, Config{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 documents restored, got %d", count)
+	}
+}
+
+func TestProgressReaderCountsStoredBytes(t *testing.T) {
+	content := strings.Repeat(`{"_index": "a", "_id": "1", "_source": {}}`+"\n", 100)
+	var compressed strings.Builder
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(content))
+	gz.Close()
+
+	progress := &progressReader{reader: strings.NewReader(compressed.String())}
+	reader, err := backup.Decompress(progress)
+	if err != nil {
+		t.Fatalf("Decompress failed: %v", err)
+	}
+
+	// Bytes read before the bar is attached are counted too
+	bar := progressbar.DefaultBytesSilent(int64(compressed.Len()))
+	progress.attach(bar)
+
+	count := 0
+	if err := readDocuments(reader, defaultMaxDocumentSize, func(Document) { count++ }); err != nil {
+		t.Fatalf("readDocuments failed: %v", err)
+	}
+
+	if count != 100 {
+		t.Errorf("Expected 100 documents, got %d", count)
+	}
+	if got := bar.State().CurrentNum; got != int64(compressed.Len()) {
+		t.Errorf("Expected %d compressed bytes on the bar, got %d", compressed.Len(), got)
+	}
+}
+
+func TestRestoreFileStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	go func() {
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"_index": "test-index", "_id": "1", "_source": {"field": "a"}}
+{"_index": "test-index", "_id": "2", "_source": {"field": "b"}}
+`))
+		gz.Close()
+		w.Close()
+	}()
+
+	files, dataFiles, err := inputFiles(backup.Stdio, Config{})
+	if err != nil || len(files) != 1 || len(dataFiles) != 0 {
+		t.Fatalf("Expected standard input without sidecars, got %v, %v, %v", files, dataFiles, err)
+	}
+
+	// Not verbose, so that the progress bar of unknown size is exercised
+	count, err := restoreFile(createMockClient(), files[0], "test-index", nil, Config{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 documents restored, got %d", count)
+	}
+}
+
+func TestReadDocumentsArrayStrings(t *testing.T) {
+	// Brackets and escaped quotes inside strings do not end a document
+	content := `[{"_id": "1", "_source": {"text": "a } \" ] {"}}, {"_id": "2", "_source": {}}]`
+
+	var ids []string
+	if err := readDocuments(strings.NewReader(content), defaultMaxDocumentSize, func(doc Document) {
+		ids = append(ids, doc.ID)
+	}); err != nil {
+		t.Fatalf("readDocuments failed: %v", err)
+	}
+	if strings.Join(ids, ",") != "1,2" {
+		t.Errorf("Expected documents 1 and 2, got %v", ids)
+	}
+
+	if err := readDocuments(strings.NewReader(`[{"_id": "1", "_source": {}}`), defaultMaxDocumentSize, func(Document) {}); err == nil {
+		t.Error("Expected error for an array without its closing bracket")
+	}
+}
@@ -0,0 +1,223 @@
+package restore
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+)
+
+// columnTypes are the types a CSV column can be coerced to; cells are
+// strings otherwise
+var columnTypes = map[string]bool{
+	"string":  true,
+	"integer": true,
+	"float":   true,
+	"boolean": true,
+	"json":    true,
+}
+
+// parseColumnTypes parses column specs such as "age:integer" into a map of
+// column names to types
+func parseColumnTypes(specs []string) (map[string]string, error) {
+	types := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid column type %q (expected name:type)", spec)
+		}
+
+		name, kind := spec[:i], spec[i+1:]
+		if !columnTypes[kind] {
+			return nil, fmt.Errorf("unsupported column type %q for %s (use string, integer, float, boolean or json)", kind, name)
+		}
+		types[name] = kind
+	}
+
+	return types, nil
+}
+
+// coerceCell converts a CSV cell to the type of its column
+func coerceCell(cell, kind string) (interface{}, error) {
+	switch kind {
+	case "integer":
+		return strconv.ParseInt(cell, 10, 64)
+	case "float":
+		return strconv.ParseFloat(cell, 64)
+	case "boolean":
+		return strconv.ParseBool(cell)
+	case "json":
+		var value interface{}
+		if err := json.Unmarshal([]byte(cell), &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	default:
+		return cell, nil
+	}
+}
+
+// readCSV reads a CSV file whose header row names the fields of the
+// documents. The _index and _id columns, as written by a csv export, set the
+// document metadata instead; idField names another column to take the _id
+// from. Empty cells are left out of the document. Rows that cannot be parsed
+// are reported and skipped.
+func readCSV(r io.Reader, types map[string]string, idField string, fn func(doc Document)) error {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read csv header: %w", err)
+	}
+	header = append([]string{}, header...)
+
+	if idField != "" && !slices.Contains(header, idField) {
+		return fmt.Errorf("id field %s is not a column of the csv header", idField)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			fmt.Printf("Error parsing row %d: %v\n", parseErr.Line, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		doc, err := csvDocument(header, record, types, idField)
+		if err != nil {
+			fmt.Printf("Error parsing row %d: %v\n", line, err)
+			continue
+		}
+		fn(doc)
+	}
+}
+
+// readCSVHeader returns the header of a csv file, which may be compressed,
+// or nil for an empty file
+func readCSVHeader(path string) ([]string, error) {
+	file, _, err := backup.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	decompressed, err := backup.Decompress(file)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := csv.NewReader(decompressed).Read()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	return header, nil
+}
+
+// csvDocument builds the document of a CSV row
+func csvDocument(header, record []string, types map[string]string, idField string) (Document, error) {
+	doc := Document{Source: make(map[string]interface{}, len(header))}
+
+	for i, column := range header {
+		cell := record[i]
+		if column == idField {
+			doc.ID = cell
+		}
+
+		switch column {
+		case "_index":
+			doc.Index = cell
+			continue
+		case "_id":
+			if idField == "" {
+				doc.ID = cell
+			}
+			continue
+		}
+
+		if cell == "" {
+			continue
+		}
+
+		value, err := coerceCell(cell, types[column])
+		if err != nil {
+			return doc, fmt.Errorf("column %s: %w", column, err)
+		}
+		doc.Source[column] = value
+	}
+
+	return doc, nil
+}
+
+// readJSONLines reads a file holding one document body per line. idField
+// names a top-level field to take the _id from; without it Elasticsearch
+// generates the ids. Lines that are not JSON objects are reported and
+// skipped, as are lines over maxSize bytes.
+func readJSONLines(r io.Reader, idField string, maxSize int64, fn func(doc Document)) error {
+	reader := newLineReader(r, maxSize)
+	for {
+		line, err := reader.next()
+		if err == io.EOF {
+			return nil
+		}
+		number := reader.number
+		if oversized, ok := err.(*oversizedError); ok {
+			fmt.Printf("Skipping line %d: %v\n", number, oversized)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var source map[string]interface{}
+		if err := json.Unmarshal(line, &source); err != nil {
+			fmt.Printf("Error parsing line %d: %v\n", number, err)
+			continue
+		}
+
+		doc := Document{Source: source}
+		if idField != "" {
+			id, ok := idValue(source[idField])
+			if !ok {
+				fmt.Printf("Error parsing line %d: missing id field %s\n", number, idField)
+				continue
+			}
+			doc.ID = id
+		}
+
+		fn(doc)
+	}
+}
+
+// idValue formats a string or number field as a document id
+func idValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
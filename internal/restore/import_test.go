@@ -0,0 +1,200 @@
+package restore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseColumnTypes(t *testing.T) {
+	types, err := parseColumnTypes([]string{"age:integer", "geo:point:json"})
+	if err != nil {
+		t.Fatalf("parseColumnTypes failed: %v", err)
+	}
+	want := map[string]string{"age": "integer", "geo:point": "json"}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("Expected %v, got %v", want, types)
+	}
+
+	for _, spec := range []string{"age", ":integer", "age:date"} {
+		if _, err := parseColumnTypes([]string{spec}); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	content := `_index,_id,name,age,active,tags,note
+people,1,ann,34,true,"[""a"",""b""]","likes ""quotes"", commas"
+people,2,bob,,false,[],
+people,3,eve,old,true,[],
+people,4,short
+`
+	types := map[string]string{"age": "integer", "active": "boolean", "tags": "json"}
+
+	var docs []Document
+	err := readCSV(strings.NewReader(content), types, "", func(doc Document) {
+		docs = append(docs, doc)
+	})
+	if err != nil {
+		t.Fatalf("readCSV failed: %v", err)
+	}
+
+	// Row 3 has an invalid integer and row 4 too few columns
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d: %+v", len(docs), docs)
+	}
+
+	first := docs[0]
+	if first.Index != "people" || first.ID != "1" {
+		t.Errorf("Expected metadata from the _index and _id columns, got %s/%s", first.Index, first.ID)
+	}
+	wantSource := map[string]interface{}{
+		"name":   "ann",
+		"age":    int64(34),
+		"active": true,
+		"tags":   []interface{}{"a", "b"},
+		"note":   `likes "quotes", commas`,
+	}
+	if !reflect.DeepEqual(first.Source, wantSource) {
+		t.Errorf("Expected source %v, got %v", wantSource, first.Source)
+	}
+
+	if _, ok := docs[1].Source["age"]; ok {
+		t.Error("Expected the empty age cell to be left out")
+	}
+}
+
+func TestReadCSVIDField(t *testing.T) {
+	content := "sku,name\nx1,shirt\nx2,hat\n"
+
+	var ids []string
+	err := readCSV(strings.NewReader(content), nil, "sku", func(doc Document) {
+		ids = append(ids, doc.ID)
+		if doc.Source["sku"] == nil {
+			t.Error("Expected the id column to stay in the source")
+		}
+	})
+	if err != nil {
+		t.Fatalf("readCSV failed: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"x1", "x2"}) {
+		t.Errorf("Expected ids from the sku column, got %v", ids)
+	}
+
+	if err := readCSV(strings.NewReader(content), nil, "missing", func(Document) {}); err == nil {
+		t.Error("Expected an error for an id field missing from the header")
+	}
+}
+
+func TestReadJSONLines(t *testing.T) {
+	content := `{"user": "ann", "n": 1}
+
+not json
+{"user": "bob", "n": 2.5}
+{"n": 3}
+`
+
+	var docs []Document
+	err := readJSONLines(strings.NewReader(content), "user", defaultMaxDocumentSize, func(doc Document) {
+		docs = append(docs, doc)
+	})
+	if err != nil {
+		t.Fatalf("readJSONLines failed: %v", err)
+	}
+
+	if len(docs) != 2 || docs[0].ID != "ann" || docs[1].ID != "bob" {
+		t.Errorf("Expected documents ann and bob, got %+v", docs)
+	}
+	if docs[0].Source["n"] != float64(1) {
+		t.Errorf("Expected the line to be the document body, got %v", docs[0].Source)
+	}
+
+	docs = nil
+	readJSONLines(strings.NewReader(content), "", defaultMaxDocumentSize, func(doc Document) {
+		docs = append(docs, doc)
+	})
+	if len(docs) != 3 || docs[0].ID != "" {
+		t.Errorf("Expected 3 documents with generated ids, got %+v", docs)
+	}
+}
+
+func TestRestoreFileCSV(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "people.csv")
+	if err := os.WriteFile(input, []byte("id,name\n1,ann\n2,bob\n"), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	api := &MockElasticsearchAPI{}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	count, err := restoreFile(client, input, "people", nil, Config{Concurrency: 2, Verbose: true, IDField: "id"})
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 documents restored, got %d", count)
+	}
+
+	// Without an _index column the rows have nowhere to go
+	if _, err := restoreFile(client, input, "", nil, Config{Concurrency: 1, Verbose: true}); err == nil || !strings.Contains(err.Error(), "_index column") {
+		t.Errorf("Expected an error for csv input without _index and output index, got %v", err)
+	}
+	if _, ok := api.OpTypes[""]; ok {
+		t.Error("Expected no document to be indexed without an index")
+	}
+
+	withIndex := filepath.Join(t.TempDir(), "indexed.csv")
+	if err := os.WriteFile(withIndex, []byte("_index,name\npeople,ann\n"), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+	if count, err := restoreFile(client, withIndex, "", nil, Config{Concurrency: 1, Verbose: true}); err != nil || count != 1 {
+		t.Errorf("Expected 1 document restored to its _index, got %d, %v", count, err)
+	}
+}
+
+func TestRestoreFileJSONLines(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "events.json")
+	if err := os.WriteFile(input, []byte("{\"a\": 1}\n{\"a\": 2}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	config := Config{Concurrency: 1, Verbose: true, InputFormat: "jsonl"}
+	count, err := restoreFile(createMockClient(), input, "events", nil, config)
+	if err != nil {
+		t.Fatalf("restoreFile failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 documents restored, got %d", count)
+	}
+
+	if _, err := restoreFile(createMockClient(), input, "", nil, config); err == nil {
+		t.Error("Expected an error for jsonl input without an output index")
+	}
+}
+
+func TestInputFormat(t *testing.T) {
+	tests := []struct {
+		path   string
+		config Config
+		want   string
+	}{
+		{"data.ndjson", Config{}, "auto"},
+		{"export.CSV", Config{}, "csv"},
+		{"export.csv", Config{InputFormat: "jsonl"}, "jsonl"},
+		{"data.txt", Config{InputFormat: "csv"}, "csv"},
+	}
+
+	for _, tt := range tests {
+		got, _, err := inputFormat(tt.path, tt.config)
+		if err != nil || got != tt.want {
+			t.Errorf("inputFormat(%s, %q) = %s, %v, want %s", tt.path, tt.config.InputFormat, got, err, tt.want)
+		}
+	}
+
+	if _, _, err := inputFormat("data.xml", Config{InputFormat: "xml"}); err == nil {
+		t.Error("Expected an error for an unsupported input format")
+	}
+}
@@ -0,0 +1,149 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+// createMissingIndices creates every index listed in a mapping file (in the
+// format written by a mapping backup) that does not yet exist on the
+// destination, using its backed up mappings. A missing mapping file is not an
+// error: Elasticsearch then creates the indices with dynamic mappings.
+func createMissingIndices(client *Client, mappingFile string, renamer *rename.Renamer, config Config) error {
+	data, err := readFile(mappingFile, config)
+	if os.IsNotExist(err) {
+		if config.Verbose {
+			fmt.Printf("No mapping file %s, indices will use dynamic mappings\n", mappingFile)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read mapping file: %w", err)
+	}
+
+	var mappings map[string]interface{}
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return fmt.Errorf("failed to parse mapping file: %w", err)
+	}
+
+	sources := make([]string, 0, len(mappings))
+	for source := range mappings {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		index := renamer.Apply(source)
+
+		exists, err := cluster.IndexExists(client.API, index)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		body := make(map[string]interface{})
+		if entry, ok := mappings[source].(map[string]interface{}); ok {
+			if m, ok := entry["mappings"]; ok {
+				body["mappings"] = m
+			}
+		}
+
+		if err := cluster.CreateIndex(client.API, index, body); err != nil {
+			return err
+		}
+
+		if config.Verbose {
+			fmt.Printf("Created index %s\n", index)
+		}
+	}
+
+	return nil
+}
+
+// restoreIndex creates indices from a file of create index bodies keyed by
+// source index name, as written by the "index" backup type. The output URL
+// may name the index only when the file holds a single index.
+func restoreIndex(config Config) error {
+	bodies, err := readJSONFile(config.Input, config)
+	if err != nil {
+		return fmt.Errorf("failed to read index file: %w", err)
+	}
+
+	index := extractIndex(config.Output)
+	if index != "" && len(bodies) > 1 {
+		return fmt.Errorf("index file holds %d indices, output URL must not name an index", len(bodies))
+	}
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	renamer, err := rename.New(config.OutputIndexTemplate, config.RenameMap)
+	if err != nil {
+		return err
+	}
+
+	sources := make([]string, 0, len(bodies))
+	for source := range bodies {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	// Check every destination before creating anything
+	destIndices := make(map[string]string)
+	for _, source := range sources {
+		dest := index
+		if dest == "" {
+			dest = renamer.Apply(source)
+		}
+
+		exists, err := cluster.IndexExists(destClient.API, dest)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("index %s already exists on the destination", dest)
+		}
+
+		destIndices[source] = dest
+	}
+
+	if err := restoreReferencedPipelines(destClient, backup.PipelinesFile(config.Input), config); err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		body, _ := bodies[source].(map[string]interface{})
+		if body == nil {
+			body = make(map[string]interface{})
+		}
+
+		if config.Shards != nil {
+			indexdef.SetIndexSetting(body, "number_of_shards", strconv.Itoa(*config.Shards))
+		}
+		if config.Replicas != nil {
+			indexdef.SetIndexSetting(body, "number_of_replicas", strconv.Itoa(*config.Replicas))
+		}
+
+		if err := cluster.CreateIndex(destClient.API, destIndices[source], body); err != nil {
+			return err
+		}
+
+		if config.Verbose {
+			fmt.Printf("Created index %s\n", destIndices[source])
+		}
+	}
+
+	return nil
+}
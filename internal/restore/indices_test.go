@@ -0,0 +1,101 @@
+package restore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+func TestCreateMissingIndices(t *testing.T) {
+	mappingFile := filepath.Join(t.TempDir(), "backup.ndjson.mapping.json")
+	content := `{
+		"logs-a": {"mappings": {"properties": {"message": {"type": "text"}}}},
+		"logs-b": {"mappings": {"properties": {"level": {"type": "keyword"}}}},
+		"users": {"mappings": {}}
+	}`
+	if err := os.WriteFile(mappingFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write mapping file: %v", err)
+	}
+
+	renamer, err := rename.New("{index}-restored", "")
+	if err != nil {
+		t.Fatalf("Failed to create renamer: %v", err)
+	}
+
+	api := &MockElasticsearchAPI{
+		ExistingIndices: map[string]bool{"users-restored": true},
+	}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := createMissingIndices(client, mappingFile, renamer, Config{}); err != nil {
+		t.Fatalf("createMissingIndices failed: %v", err)
+	}
+
+	expected := []string{"logs-a-restored", "logs-b-restored"}
+	if !reflect.DeepEqual(api.CreatedIndices, expected) {
+		t.Errorf("Created indices = %v, want %v", api.CreatedIndices, expected)
+	}
+
+	if body := api.CreatedBodies["logs-a-restored"]; !strings.Contains(body, `"message"`) {
+		t.Errorf("Expected create body to contain backed up mappings, got %s", body)
+	}
+}
+
+func TestCreateMissingIndicesWithoutMappingFile(t *testing.T) {
+	api := &MockElasticsearchAPI{}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	err := createMissingIndices(client, filepath.Join(t.TempDir(), "missing.json"), nil, Config{})
+	if err != nil {
+		t.Errorf("Expected missing mapping file to be ignored, got: %v", err)
+	}
+	if len(api.CreatedIndices) != 0 {
+		t.Errorf("Expected no indices to be created, got %v", api.CreatedIndices)
+	}
+}
+
+func TestIndexExists(t *testing.T) {
+	client := &Client{
+		API: &MockElasticsearchAPI{ExistingIndices: map[string]bool{"present": true}},
+		URL: "http://mock:9200",
+	}
+
+	if exists, err := cluster.IndexExists(client.API, "present"); err != nil || !exists {
+		t.Errorf("indexExists(present) = %v, %v; want true, nil", exists, err)
+	}
+	if exists, err := cluster.IndexExists(client.API, "absent"); err != nil || exists {
+		t.Errorf("indexExists(absent) = %v, %v; want false, nil", exists, err)
+	}
+
+	if _, err := cluster.IndexExists(createMockClientWithError().API, "present"); err == nil {
+		t.Error("Expected error for failed exists request")
+	}
+}
+
+func TestRestoreIndexValidation(t *testing.T) {
+	dir := t.TempDir()
+	indexFile := filepath.Join(dir, "index.json")
+	content := `{"logs": {"settings": {}}, "users": {"settings": {}}}`
+	if err := os.WriteFile(indexFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write index file: %v", err)
+	}
+
+	config := Config{
+		Input:  indexFile,
+		Output: "http://localhost:9200/single",
+		Type:   "index",
+	}
+	if err := restoreIndex(config); err == nil || !strings.Contains(err.Error(), "must not name an index") {
+		t.Errorf("Expected error for named output index with several indices, got %v", err)
+	}
+
+	config.Input = filepath.Join(dir, "missing.json")
+	if err := restoreIndex(config); err == nil {
+		t.Error("Expected error for missing index file")
+	}
+}
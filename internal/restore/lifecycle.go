@@ -0,0 +1,32 @@
+package restore
+
+import (
+	"fmt"
+
+	"github.com/lilmonk/elasticdump/internal/lifecycle"
+)
+
+// restoreLifecycle puts the ILM policies of a file written by the
+// "lifecycle" backup type, or the ISM policies with OpenSearch
+func restoreLifecycle(config Config) error {
+	policies, err := readJSONFile(config.Input, config)
+	if err != nil {
+		return fmt.Errorf("failed to read lifecycle file: %w", err)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("lifecycle type requires a cluster URL without path as output")
+	}
+
+	if config.OpenSearch {
+		dest := lifecycle.NewISMClient(getBaseURL(config.Output), config.Username, config.Password)
+		return lifecycle.PutISMPolicies(dest, policies, putOptions(config))
+	}
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return lifecycle.PutPolicies(destClient.API, policies, putOptions(config))
+}
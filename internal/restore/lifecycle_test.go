@@ -0,0 +1,58 @@
+package restore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/lifecycle"
+)
+
+func TestPutPolicies(t *testing.T) {
+	policies := map[string]interface{}{
+		"logs-delete": map[string]interface{}{"policy": map[string]interface{}{}},
+		"metrics":     map[string]interface{}{"policy": map[string]interface{}{}},
+	}
+
+	api := &MockElasticsearchAPI{Policies: map[string]string{"metrics": `{"phases": {}}`}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := lifecycle.PutPolicies(client.API, policies, putOptions(Config{Existing: "skip"})); err != nil {
+		t.Fatalf("PutPolicies failed: %v", err)
+	}
+	if !reflect.DeepEqual(api.PutPolicies, []string{"logs-delete"}) {
+		t.Errorf("Expected only logs-delete to be put, got %v", api.PutPolicies)
+	}
+}
+
+func TestRestoreISMPolicies(t *testing.T) {
+	var put []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"policies": [], "total_policies": 0}`))
+		case http.MethodPut:
+			put = append(put, strings.TrimPrefix(r.URL.Path, "/_plugins/_ism/policies/"))
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "ism.json")
+	content := `{"logs-delete": {"policy": {"default_state": "hot", "states": []}}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write lifecycle file: %v", err)
+	}
+
+	config := Config{Input: path, Output: server.URL, Type: "lifecycle", OpenSearch: true}
+	if err := restoreLifecycle(config); err != nil {
+		t.Fatalf("restoreLifecycle failed: %v", err)
+	}
+	if !reflect.DeepEqual(put, []string{"logs-delete"}) {
+		t.Errorf("Expected logs-delete to be put, got %v", put)
+	}
+}
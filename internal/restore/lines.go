@@ -0,0 +1,100 @@
+package restore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// defaultMaxDocumentSize caps the size of a document line when
+// Config.MaxDocumentSize is not set; it matches the default
+// http.max_content_length of Elasticsearch, which would reject anything
+// bigger anyway
+const defaultMaxDocumentSize = 100 << 20
+
+// maxDocumentSize returns the size cap of a document line
+func maxDocumentSize(config Config) int64 {
+	if config.MaxDocumentSize > 0 {
+		return config.MaxDocumentSize
+	}
+	return defaultMaxDocumentSize
+}
+
+// oversizedHead is the number of bytes kept of the start of an oversized
+// line, enough to tell the action of a bulk action line
+const oversizedHead = 256
+
+// oversizedError reports a document over the size cap, which is skipped
+type oversizedError struct {
+	size int64
+	max  int64
+
+	// head is the start of the line
+	head []byte
+}
+
+func (e *oversizedError) Error() string {
+	return fmt.Sprintf("document of %d bytes exceeds the %d bytes limit", e.size, e.max)
+}
+
+// lineReader reads lines of any length, unlike bufio.Scanner, up to a size
+// cap. Lines over the cap are consumed without being held in memory and
+// reported as an *oversizedError, so that reading can go on with the next
+// line.
+type lineReader struct {
+	reader *bufio.Reader
+	max    int64
+
+	// number is the number of the line last read, from 1
+	number int
+}
+
+// newLineReader returns a lineReader of r capping lines at max bytes
+func newLineReader(r io.Reader, max int64) *lineReader {
+	reader, ok := r.(*bufio.Reader)
+	if !ok {
+		reader = bufio.NewReader(r)
+	}
+	return &lineReader{reader: reader, max: max}
+}
+
+// next returns the next line without its line terminator, io.EOF once all
+// lines have been read, or an *oversizedError for a line over the cap
+func (r *lineReader) next() ([]byte, error) {
+	var line, head []byte
+	var size int64
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		if len(head) < oversizedHead {
+			head = append(head, chunk[:min(len(chunk), oversizedHead-len(head))]...)
+		}
+		size += int64(len(chunk))
+		if size <= r.max+1 {
+			line = append(line, chunk...)
+		} else {
+			line = nil
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, io.EOF
+		}
+		r.number++
+
+		if err == nil {
+			// The newline does not count towards the size
+			size--
+		}
+		if size > r.max {
+			return nil, &oversizedError{size: size, max: r.max, head: head}
+		}
+
+		return bytes.TrimRight(line, "\r\n"), nil
+	}
+}
@@ -0,0 +1,187 @@
+package restore
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineReader(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	content := "first\r\n\n" + long + "\n" + strings.Repeat("y", 300) + "\nlast"
+
+	reader := newLineReader(strings.NewReader(content), 250*1024)
+
+	want := []string{"first", "", long}
+	for i, expected := range want {
+		line, err := reader.next()
+		if err != nil {
+			t.Fatalf("Line %d: unexpected error %v", i+1, err)
+		}
+		if string(line) != expected {
+			t.Errorf("Line %d: got %d bytes, want %d", i+1, len(line), len(expected))
+		}
+		if reader.number != i+1 {
+			t.Errorf("Expected line number %d, got %d", i+1, reader.number)
+		}
+	}
+
+	line, err := reader.next()
+	if err != nil || string(line) != strings.Repeat("y", 300) {
+		t.Fatalf("Expected the line after the long one, got %d bytes, %v", len(line), err)
+	}
+
+	// The last line has no terminator
+	line, err = reader.next()
+	if err != nil || string(line) != "last" || reader.number != 5 {
+		t.Errorf("Expected the last line, got %q, %v at line %d", line, err, reader.number)
+	}
+	if _, err := reader.next(); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+}
+
+func TestLineReaderOversized(t *testing.T) {
+	content := "short\n" + strings.Repeat("x", 10000) + "\nafter\n" + strings.Repeat("z", 101)
+
+	reader := newLineReader(strings.NewReader(content), 100)
+
+	if line, err := reader.next(); err != nil || string(line) != "short" {
+		t.Fatalf("Expected the first line, got %q, %v", line, err)
+	}
+
+	_, err := reader.next()
+	oversized, ok := err.(*oversizedError)
+	if !ok || oversized.size != 10000 || reader.number != 2 {
+		t.Fatalf("Expected line 2 to be reported as oversized, got %v at line %d", err, reader.number)
+	}
+
+	// Reading goes on after an oversized line
+	if line, err := reader.next(); err != nil || string(line) != "after" {
+		t.Fatalf("Expected the line after the oversized one, got %q, %v", line, err)
+	}
+
+	if _, err := reader.next(); err == nil {
+		t.Error("Expected an unterminated last line over the cap to be reported")
+	}
+	if _, err := reader.next(); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+
+	// A line of exactly the cap is accepted
+	reader = newLineReader(strings.NewReader(strings.Repeat("a", 100)+"\n"), 100)
+	if line, err := reader.next(); err != nil || len(line) != 100 {
+		t.Errorf("Expected a line of the cap size, got %d bytes, %v", len(line), err)
+	}
+}
+
+func TestReadDocumentsLargeDocuments(t *testing.T) {
+	// Documents over the 64KB bufio.Scanner limit are read whole
+	large := strings.Repeat("a", 1<<20)
+	lines := fmt.Sprintf(`{"_index": "i", "_id": "1", "_source": {"text": %q}}
+{"_index": "i", "_id": "2", "_source": {"text": "%s"}}
+{"_index": "i", "_id": "3", "_source": {}}
+`, large, strings.Repeat("b", 4<<20))
+
+	var ids []string
+	if err := readDocuments(strings.NewReader(lines), 2<<20, func(doc Document) {
+		ids = append(ids, doc.ID)
+	}); err != nil {
+		t.Fatalf("readDocuments failed: %v", err)
+	}
+
+	// The document over the cap is skipped, not the rest of the file
+	if strings.Join(ids, ",") != "1,3" {
+		t.Errorf("Expected documents 1 and 3, got %v", ids)
+	}
+
+	array := fmt.Sprintf(`[{"_index": "i", "_id": "1", "_source": {"text": %q}}, {"_index": "i", "_id": "2", "_source": {}}]`,
+		strings.Repeat("c", 1000))
+	ids = nil
+	if err := readDocuments(strings.NewReader(array), 500, func(doc Document) {
+		ids = append(ids, doc.ID)
+	}); err != nil {
+		t.Fatalf("readDocuments failed: %v", err)
+	}
+	if strings.Join(ids, ",") != "2" {
+		t.Errorf("Expected only document 2 of the array, got %v", ids)
+	}
+}
+
+func TestReadJSONLinesOversized(t *testing.T) {
+	content := fmt.Sprintf("{\"id\": 1, \"text\": %q}\n{\"id\": 2}\n", strings.Repeat("a", 500))
+
+	var ids []string
+	if err := readJSONLines(strings.NewReader(content), "id", 100, func(doc Document) {
+		ids = append(ids, doc.ID)
+	}); err != nil {
+		t.Fatalf("readJSONLines failed: %v", err)
+	}
+	if strings.Join(ids, ",") != "2" {
+		t.Errorf("Expected only document 2, got %v", ids)
+	}
+}
+
+func TestReadBulkActionsOversized(t *testing.T) {
+	content := fmt.Sprintf(`{"index": {"_index": "i", "_id": "1"}}
+{"text": %q}
+{"delete": {"_index": "i", "_id": "2"}}
+{"index": {"_index": "i", "_id": "3"}}
+{"text": "small"}
+`, strings.Repeat("a", 500))
+
+	var ids []interface{}
+	err := readBulkActions(newLineReader(strings.NewReader(content), 100), func(action *bulkAction) (bool, error) {
+		ids = append(ids, action.meta["_id"])
+		return true, nil
+	}, func([]*bulkAction) {})
+	if err != nil {
+		t.Fatalf("readBulkActions failed: %v", err)
+	}
+
+	// The action with the oversized body is skipped with its body
+	if fmt.Sprint(ids) != "[2 3]" {
+		t.Errorf("Expected actions 2 and 3, got %v", ids)
+	}
+}
+
+func TestReadBulkActionsOversizedAction(t *testing.T) {
+	id := strings.Repeat("a", 500)
+	content := fmt.Sprintf(`{"index": {"_index": "i", "_id": %q}}
+{"text": "small"}
+{"delete": {"_index": "i", "_id": %q}}
+{"index": {"_index": "i", "_id": "3"}}
+{"text": "small"}
+`, id, id)
+
+	var ids []interface{}
+	err := readBulkActions(newLineReader(strings.NewReader(content), 100), func(action *bulkAction) (bool, error) {
+		ids = append(ids, action.meta["_id"])
+		return true, nil
+	}, func([]*bulkAction) {})
+	if err != nil {
+		t.Fatalf("readBulkActions failed: %v", err)
+	}
+
+	// The oversized index action is skipped with its body, the oversized
+	// delete action alone
+	if fmt.Sprint(ids) != "[3]" {
+		t.Errorf("Expected action 3 only, got %v", ids)
+	}
+}
+
+func TestLeadingKey(t *testing.T) {
+	tests := map[string]string{
+		`{"index": {"_id": "1"}}`: "index",
+		` { "delete":`:            "delete",
+		`["index"]`:               "",
+		`{"unterminated`:          "",
+	}
+
+	for line, want := range tests {
+		if got := leadingKey([]byte(line)); got != want {
+			t.Errorf("leadingKey(%s) = %q, want %q", line, got, want)
+		}
+	}
+}
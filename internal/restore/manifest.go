@@ -0,0 +1,197 @@
+package restore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+)
+
+// checkManifest reads the manifest written next to a backup file, if any,
+// and warns about everything in the backup that does not match it: another
+// type of backup, or files missing or changed since the backup when
+// config.Verify is set. Files are checked as they are read. It returns nil
+// when the file has no manifest.
+func checkManifest(file string, config Config) *backup.FileManifest {
+	if file == backup.Stdio {
+		return nil
+	}
+
+	path := backup.ManifestFile(file)
+	manifest, err := backup.ReadFileManifest(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to read backup manifest %s: %v\n", path, err)
+		return nil
+	}
+
+	if config.Verbose {
+		fmt.Printf("Backup of %s (cluster %s, version %s) created %s by elasticdump %s\n",
+			manifest.Source, manifest.ClusterName, manifest.ClusterVersion,
+			manifest.CreatedAt.Format("2006-01-02 15:04:05 UTC"), manifest.ToolVersion)
+	}
+
+	if manifest.Type != "" && manifest.Type != config.Type {
+		fmt.Printf("Warning: %s is a %s backup, restoring it as %s\n", file, manifest.Type, config.Type)
+	}
+
+	config.verifier.expect(filepath.Dir(path), manifest.Files)
+
+	return manifest
+}
+
+// checkDocuments warns when the number of documents restored from a backup
+// file differs from the number its manifest records
+func checkDocuments(file string, manifest *backup.FileManifest, restored int) {
+	if manifest == nil || manifest.Documents == restored {
+		return
+	}
+
+	fmt.Printf("Warning: restored %d documents from %s, its manifest records %d\n", restored, file, manifest.Documents)
+}
+
+// verifier checks backup files against the checksums their manifests
+// record, computed from the bytes read while restoring them rather than by
+// reading the files again. A file is checked once both its checksum and the
+// recorded one are known, whichever comes first; files that are not read are
+// not checked.
+type verifier struct {
+	mu       sync.Mutex
+	recorded map[string]backup.File
+	read     map[string]backup.File
+}
+
+// newVerifier returns a verifier that knows no checksums yet
+func newVerifier() *verifier {
+	return &verifier{recorded: map[string]backup.File{}, read: map[string]backup.File{}}
+}
+
+// expect takes the checksums of a manifest, whose file names are relative to
+// dir, checking the files already read and warning about missing ones. A nil
+// verifier checks nothing.
+func (v *verifier) expect(dir string, files []backup.File) {
+	if v == nil {
+		return
+	}
+
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			fmt.Printf("Warning: %s is missing\n", file.Name)
+			continue
+		}
+
+		v.mu.Lock()
+		v.recorded[path] = file
+		actual, ok := v.read[path]
+		v.mu.Unlock()
+
+		if ok {
+			warnMismatch(file, actual)
+		}
+	}
+}
+
+// done takes the checksum of a file read in full. A file read several
+// times is only checked once.
+func (v *verifier) done(path string, hash *backup.Hash) {
+	path = filepath.Clean(path)
+
+	v.mu.Lock()
+	if _, ok := v.read[path]; ok {
+		v.mu.Unlock()
+		return
+	}
+	v.read[path] = hash.File(filepath.Base(path))
+	recorded, ok := v.recorded[path]
+	v.mu.Unlock()
+
+	if ok {
+		warnMismatch(recorded, hash.File(recorded.Name))
+	}
+}
+
+// warnMismatch warns when a file does not match its recorded checksum
+func warnMismatch(recorded, actual backup.File) {
+	if mismatch := backup.Mismatch(recorded, actual); mismatch != "" {
+		fmt.Printf("Warning: %s\n", mismatch)
+	}
+}
+
+// checkedFile is a backup file hashed as it is read, so that check can hand
+// its checksum to the verifier
+type checkedFile struct {
+	io.ReadCloser
+	path     string
+	hash     *backup.Hash
+	verifier *verifier
+}
+
+// openFile opens a backup file as backup.Open does, hashing it as it is read
+// when config verifies backups
+func openFile(path string, config Config) (*checkedFile, int64, error) {
+	file, size, err := backup.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	checked := &checkedFile{ReadCloser: file, path: path}
+	if config.verifier != nil && path != backup.Stdio {
+		checked.hash = backup.NewHash()
+		checked.verifier = config.verifier
+	}
+
+	return checked, size, nil
+}
+
+func (f *checkedFile) Read(p []byte) (int, error) {
+	n, err := f.ReadCloser.Read(p)
+	if f.hash != nil {
+		f.hash.Write(p[:n])
+	}
+	return n, err
+}
+
+// check reads what is left of the file, such as the whitespace after a
+// JSON array, and hands its checksum to the verifier. It is called once the
+// file has been restored.
+func (f *checkedFile) check() {
+	if f.verifier == nil {
+		return
+	}
+
+	if _, err := io.Copy(io.Discard, f); err != nil {
+		fmt.Printf("Warning: failed to verify %s: %v\n", f.path, err)
+		return
+	}
+
+	f.verifier.done(f.path, f.hash)
+}
+
+// readFile reads a whole backup file, decompressing it if it is gzip, and
+// checks it when config verifies backups
+func readFile(path string, config Config) ([]byte, error) {
+	file, _, err := openFile(path, config)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := backup.Decompress(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	file.check()
+	return data, nil
+}
@@ -0,0 +1,174 @@
+package restore
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+)
+
+// captureStdout runs fn with standard output redirected and returns what was
+// written to it
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	stdout := os.Stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+
+	os.Stdout = writer
+	func() {
+		defer func() { os.Stdout = stdout }()
+		fn()
+	}()
+	writer.Close()
+
+	out, _ := io.ReadAll(reader)
+	return string(out)
+}
+
+// writeManifestBackup writes a data file and its manifest and returns the
+// path of the data file
+func writeManifestBackup(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "data.ndjson")
+	data := []byte(`{"_index":"logs","_id":"1","_source":{}}` + "\n")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+
+	hash := backup.NewHash()
+	hash.Write(data)
+	manifest := &backup.FileManifest{Type: "data", Documents: 1, Files: []backup.File{hash.File("data.ndjson")}}
+	if err := backup.WriteFileManifest(backup.ManifestFile(path), manifest); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	return path
+}
+
+func TestCheckManifest(t *testing.T) {
+	path := writeManifestBackup(t)
+	config := Config{Type: "data", Concurrency: 1, Verbose: true, Verify: true, verifier: newVerifier()}
+
+	var manifest *backup.FileManifest
+	out := captureStdout(t, func() {
+		manifest = checkManifest(path, config)
+		if _, err := restoreFile(createMockClient(), path, "", nil, config); err != nil {
+			t.Errorf("restoreFile failed: %v", err)
+		}
+	})
+	if manifest == nil || manifest.Documents != 1 {
+		t.Fatalf("Expected the manifest, got %+v", manifest)
+	}
+	if strings.Contains(out, "Warning") {
+		t.Errorf("Expected no warnings for an intact backup, got %q", out)
+	}
+}
+
+func TestCheckManifestMismatches(t *testing.T) {
+	path := writeManifestBackup(t)
+	if err := os.WriteFile(path, []byte("changed\n"), 0644); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+
+	// Files are checked once they are read
+	config := Config{Type: "mapping", Verify: true, verifier: newVerifier()}
+	out := captureStdout(t, func() {
+		checkManifest(path, config)
+	})
+	if !strings.Contains(out, "Warning: "+path+" is a data backup, restoring it as mapping") {
+		t.Errorf("Expected a type warning, got %q", out)
+	}
+	if strings.Contains(out, "has changed") {
+		t.Errorf("Expected no checksum warning before the file is read, got %q", out)
+	}
+
+	out = captureStdout(t, func() {
+		if _, err := readFile(path, config); err != nil {
+			t.Errorf("readFile failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "Warning: data.ndjson has changed") {
+		t.Errorf("Expected a checksum warning, got %q", out)
+	}
+
+	// Files read before their manifest are checked against it
+	config = Config{Type: "data", Verify: true, verifier: newVerifier()}
+	out = captureStdout(t, func() {
+		if _, err := readFile(path, config); err != nil {
+			t.Errorf("readFile failed: %v", err)
+		}
+		checkManifest(path, config)
+	})
+	if strings.Count(out, "Warning: data.ndjson has changed") != 1 {
+		t.Errorf("Expected one checksum warning, got %q", out)
+	}
+
+	// Checksums are only verified on request
+	out = captureStdout(t, func() {
+		config := Config{Type: "data"}
+		checkManifest(path, config)
+		if _, err := readFile(path, config); err != nil {
+			t.Errorf("readFile failed: %v", err)
+		}
+	})
+	if out != "" {
+		t.Errorf("Expected no warnings without verification, got %q", out)
+	}
+}
+
+func TestCheckManifestMissingFile(t *testing.T) {
+	path := writeManifestBackup(t)
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove data file: %v", err)
+	}
+
+	out := captureStdout(t, func() {
+		checkManifest(path, Config{Type: "data", Verify: true, verifier: newVerifier()})
+	})
+	if !strings.Contains(out, "Warning: data.ndjson is missing") {
+		t.Errorf("Expected a missing file warning, got %q", out)
+	}
+}
+
+func TestCheckManifestMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.ndjson")
+
+	out := captureStdout(t, func() {
+		if manifest := checkManifest(path, Config{Type: "data", Verify: true}); manifest != nil {
+			t.Errorf("Expected no manifest, got %+v", manifest)
+		}
+		if manifest := checkManifest(backup.Stdio, Config{Type: "data", Verify: true}); manifest != nil {
+			t.Errorf("Expected no manifest for standard input, got %+v", manifest)
+		}
+	})
+	if out != "" {
+		t.Errorf("Expected no warnings without a manifest, got %q", out)
+	}
+}
+
+func TestCheckDocuments(t *testing.T) {
+	manifest := &backup.FileManifest{Documents: 3}
+
+	out := captureStdout(t, func() {
+		checkDocuments("data.ndjson", manifest, 3)
+		checkDocuments("data.ndjson", nil, 2)
+	})
+	if out != "" {
+		t.Errorf("Expected no warnings, got %q", out)
+	}
+
+	out = captureStdout(t, func() {
+		checkDocuments("data.ndjson", manifest, 2)
+	})
+	if !strings.Contains(out, "Warning: restored 2 documents from data.ndjson, its manifest records 3") {
+		t.Errorf("Expected a document count warning, got %q", out)
+	}
+}
@@ -0,0 +1,64 @@
+package restore
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/ingest"
+)
+
+// restorePipelines puts the ingest pipelines of a file written by the
+// "pipelines" backup type
+func restorePipelines(config Config) error {
+	pipelines, err := readJSONFile(config.Input, config)
+	if err != nil {
+		return fmt.Errorf("failed to read pipelines file: %w", err)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("pipelines type requires a cluster URL without path as output")
+	}
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return ingest.PutPipelines(destClient.API, pipelines, putOptions(config))
+}
+
+// restoreReferencedPipelines puts the ingest pipelines of a file written next
+// to backed up indices, if there is one. Unless IncludePipelines is set, it
+// only reports them. Pipelines that already exist on the destination are kept
+// unless Existing is "overwrite".
+func restoreReferencedPipelines(client *Client, path string, config Config) error {
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+
+	pipelines, err := readJSONFile(path, config)
+	if err != nil {
+		return fmt.Errorf("failed to read ingest pipelines file: %w", err)
+	}
+
+	if !config.IncludePipelines {
+		ids := make([]string, 0, len(pipelines))
+		for id := range pipelines {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		fmt.Printf("Backup holds ingest pipelines %s; use --includePipelines to restore them\n",
+			strings.Join(ids, ", "))
+		return nil
+	}
+
+	pipelineOptions := putOptions(config)
+	if pipelineOptions.Existing != "overwrite" {
+		pipelineOptions.Existing = "skip"
+	}
+
+	return ingest.PutPipelines(client.API, pipelines, pipelineOptions)
+}
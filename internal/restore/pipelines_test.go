@@ -0,0 +1,69 @@
+package restore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/ingest"
+)
+
+func TestPutPipelines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipelines.json")
+	content := `{"geoip": {"processors": [{"geoip": {"field": "ip"}}]}, "enrich": {"processors": []}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write pipelines file: %v", err)
+	}
+
+	pipelines, err := readJSONFile(path, Config{})
+	if err != nil {
+		t.Fatalf("readJSONFile failed: %v", err)
+	}
+
+	api := &MockElasticsearchAPI{Pipelines: map[string]string{"geoip": `{"processors": []}`}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := ingest.PutPipelines(client.API, pipelines, putOptions(Config{Existing: "skip"})); err != nil {
+		t.Fatalf("PutPipelines failed: %v", err)
+	}
+	if !reflect.DeepEqual(api.PutPipelines, []string{"enrich"}) {
+		t.Errorf("Expected only enrich to be put, got %v", api.PutPipelines)
+	}
+
+	if err := ingest.PutPipelines(client.API, pipelines, putOptions(Config{Existing: "merge"})); err == nil {
+		t.Error("Expected error for invalid policy")
+	}
+}
+
+func TestRestoreReferencedPipelines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.json.pipelines.json")
+	content := `{"geoip": {"processors": []}, "enrich": {"processors": []}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write pipelines file: %v", err)
+	}
+
+	// Without IncludePipelines the pipelines are only reported
+	api := &MockElasticsearchAPI{Pipelines: map[string]string{"geoip": `{"processors": []}`}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+	if err := restoreReferencedPipelines(client, path, Config{}); err != nil {
+		t.Fatalf("restoreReferencedPipelines failed: %v", err)
+	}
+	if len(api.PutPipelines) != 0 {
+		t.Errorf("Expected no pipelines to be put, got %v", api.PutPipelines)
+	}
+
+	// Existing pipelines are kept even with the default fail policy
+	config := Config{IncludePipelines: true, Existing: "fail"}
+	if err := restoreReferencedPipelines(client, path, config); err != nil {
+		t.Fatalf("restoreReferencedPipelines failed: %v", err)
+	}
+	if !reflect.DeepEqual(api.PutPipelines, []string{"enrich"}) {
+		t.Errorf("Expected only enrich to be put, got %v", api.PutPipelines)
+	}
+
+	// A backup without a pipelines file is not an error
+	if err := restoreReferencedPipelines(client, path+".missing", config); err != nil {
+		t.Errorf("restoreReferencedPipelines failed for a missing file: %v", err)
+	}
+}
@@ -6,11 +6,18 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/rename"
 	"github.com/schollz/progressbar/v3"
 )
 
@@ -23,6 +30,63 @@ type Config struct {
 	Verbose     bool
 	Username    string
 	Password    string
+
+	// OutputIndexTemplate and RenameMap derive destination index names from
+	// each document's _index when the output URL has no explicit index
+	OutputIndexTemplate string
+	RenameMap           string
+
+	// All restores a directory backup; Include and Exclude select indices
+	// from it and Existing decides what happens to indices (and with the
+	// metadata types, templates, pipelines, policies, scripts and roles) that
+	// already exist on the destination ("fail", "skip" or "overwrite")
+	All      bool
+	Include  []string
+	Exclude  []string
+	Existing string
+
+	// OpenSearch makes the lifecycle type put ISM policies on an OpenSearch
+	// cluster instead of ILM policies
+	OpenSearch bool
+
+	// IncludePipelines puts the ingest pipelines backed up next to the
+	// settings, index and all types, or in a directory backup, before the
+	// indices are created
+	IncludePipelines bool
+
+	// Shards and Replicas override the backed up values when creating an
+	// index with the "index" type; nil keeps the backed up value
+	Shards   *int
+	Replicas *int
+
+	// DryRun shows the changes the security type would make on the
+	// destination without applying them
+	DryRun bool
+
+	// InputFormat is the format of data files: "auto" (or empty) detects
+	// backups in the json, ndjson and bulk formats and csv exports by their
+	// extension, "csv" reads a CSV file with a header row and "jsonl" reads
+	// one document body per line. IDField names the column or field holding
+	// the document ids of csv and jsonl files, and ColumnTypes coerces csv
+	// columns, as name:type.
+	InputFormat string
+	IDField     string
+	ColumnTypes []string
+
+	// Parallel is the number of data files restored at the same time when
+	// the input is a parts manifest, a directory or a glob
+	Parallel int
+
+	// MaxDocumentSize caps the size in bytes of a document in a data file;
+	// bigger documents are reported and skipped. 0 is 100MB.
+	MaxDocumentSize int64
+
+	// Verify checks the backup files against the checksums of their manifest
+	Verify bool
+
+	// verifier checks the files read against their manifests when Verify
+	// is set
+	verifier *verifier
 }
 
 // Client wraps Elasticsearch client with additional functionality
@@ -42,8 +106,32 @@ type Document struct {
 // ElasticsearchAPI defines the interface for Elasticsearch operations
 type ElasticsearchAPI interface {
 	Index(index string, body io.Reader, o ...func(*esapi.IndexRequest)) (*esapi.Response, error)
+	Bulk(body io.Reader, o ...func(*esapi.BulkRequest)) (*esapi.Response, error)
 	IndicesPutMapping(indices []string, body io.Reader, o ...func(*esapi.IndicesPutMappingRequest)) (*esapi.Response, error)
 	IndicesPutSettings(body io.Reader, o ...func(*esapi.IndicesPutSettingsRequest)) (*esapi.Response, error)
+	IndicesExists(index []string, o ...func(*esapi.IndicesExistsRequest)) (*esapi.Response, error)
+	IndicesCreate(index string, o ...func(*esapi.IndicesCreateRequest)) (*esapi.Response, error)
+	IndicesDelete(index []string, o ...func(*esapi.IndicesDeleteRequest)) (*esapi.Response, error)
+	IndicesUpdateAliases(body io.Reader, o ...func(*esapi.IndicesUpdateAliasesRequest)) (*esapi.Response, error)
+	ClusterPutComponentTemplate(name string, body io.Reader, o ...func(*esapi.ClusterPutComponentTemplateRequest)) (*esapi.Response, error)
+	ClusterExistsComponentTemplate(name string, o ...func(*esapi.ClusterExistsComponentTemplateRequest)) (*esapi.Response, error)
+	IndicesPutIndexTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutIndexTemplateRequest)) (*esapi.Response, error)
+	IndicesExistsIndexTemplate(name string, o ...func(*esapi.IndicesExistsIndexTemplateRequest)) (*esapi.Response, error)
+	IndicesPutTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutTemplateRequest)) (*esapi.Response, error)
+	IndicesExistsTemplate(name []string, o ...func(*esapi.IndicesExistsTemplateRequest)) (*esapi.Response, error)
+	IngestGetPipeline(o ...func(*esapi.IngestGetPipelineRequest)) (*esapi.Response, error)
+	IngestPutPipeline(id string, body io.Reader, o ...func(*esapi.IngestPutPipelineRequest)) (*esapi.Response, error)
+	ILMGetLifecycle(o ...func(*esapi.ILMGetLifecycleRequest)) (*esapi.Response, error)
+	ILMPutLifecycle(policy string, o ...func(*esapi.ILMPutLifecycleRequest)) (*esapi.Response, error)
+	ClusterState(o ...func(*esapi.ClusterStateRequest)) (*esapi.Response, error)
+	PutScript(id string, body io.Reader, o ...func(*esapi.PutScriptRequest)) (*esapi.Response, error)
+	SecurityGetRole(o ...func(*esapi.SecurityGetRoleRequest)) (*esapi.Response, error)
+	SecurityPutRole(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleRequest)) (*esapi.Response, error)
+	SecurityGetRoleMapping(o ...func(*esapi.SecurityGetRoleMappingRequest)) (*esapi.Response, error)
+	SecurityPutRoleMapping(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleMappingRequest)) (*esapi.Response, error)
+	IndicesGetDataStream(o ...func(*esapi.IndicesGetDataStreamRequest)) (*esapi.Response, error)
+	IndicesCreateDataStream(name string, o ...func(*esapi.IndicesCreateDataStreamRequest)) (*esapi.Response, error)
+	IndicesDeleteDataStream(name []string, o ...func(*esapi.IndicesDeleteDataStreamRequest)) (*esapi.Response, error)
 }
 
 // ElasticsearchClientWrapper wraps the actual Elasticsearch client to implement our interface
@@ -61,6 +149,11 @@ func (w *ElasticsearchClientWrapper) Index(index string, body io.Reader, o ...fu
 	return w.client.Index(index, body, o...)
 }
 
+// Bulk implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) Bulk(body io.Reader, o ...func(*esapi.BulkRequest)) (*esapi.Response, error) {
+	return w.client.Bulk(body, o...)
+}
+
 // IndicesPutMapping implements ElasticsearchAPI
 func (w *ElasticsearchClientWrapper) IndicesPutMapping(indices []string, body io.Reader, o ...func(*esapi.IndicesPutMappingRequest)) (*esapi.Response, error) {
 	return w.client.Indices.PutMapping(indices, body, o...)
@@ -71,6 +164,121 @@ func (w *ElasticsearchClientWrapper) IndicesPutSettings(body io.Reader, o ...fun
 	return w.client.Indices.PutSettings(body, o...)
 }
 
+// IndicesExists implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesExists(index []string, o ...func(*esapi.IndicesExistsRequest)) (*esapi.Response, error) {
+	return w.client.Indices.Exists(index, o...)
+}
+
+// IndicesCreate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesCreate(index string, o ...func(*esapi.IndicesCreateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.Create(index, o...)
+}
+
+// IndicesDelete implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesDelete(index []string, o ...func(*esapi.IndicesDeleteRequest)) (*esapi.Response, error) {
+	return w.client.Indices.Delete(index, o...)
+}
+
+// IndicesUpdateAliases implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesUpdateAliases(body io.Reader, o ...func(*esapi.IndicesUpdateAliasesRequest)) (*esapi.Response, error) {
+	return w.client.Indices.UpdateAliases(body, o...)
+}
+
+// ClusterPutComponentTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ClusterPutComponentTemplate(name string, body io.Reader, o ...func(*esapi.ClusterPutComponentTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Cluster.PutComponentTemplate(name, body, o...)
+}
+
+// ClusterExistsComponentTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ClusterExistsComponentTemplate(name string, o ...func(*esapi.ClusterExistsComponentTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Cluster.ExistsComponentTemplate(name, o...)
+}
+
+// IndicesPutIndexTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesPutIndexTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutIndexTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.PutIndexTemplate(name, body, o...)
+}
+
+// IndicesExistsIndexTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesExistsIndexTemplate(name string, o ...func(*esapi.IndicesExistsIndexTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.ExistsIndexTemplate(name, o...)
+}
+
+// IndicesPutTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesPutTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.PutTemplate(name, body, o...)
+}
+
+// IndicesExistsTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesExistsTemplate(name []string, o ...func(*esapi.IndicesExistsTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.ExistsTemplate(name, o...)
+}
+
+// IngestGetPipeline implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IngestGetPipeline(o ...func(*esapi.IngestGetPipelineRequest)) (*esapi.Response, error) {
+	return w.client.Ingest.GetPipeline(o...)
+}
+
+// IngestPutPipeline implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IngestPutPipeline(id string, body io.Reader, o ...func(*esapi.IngestPutPipelineRequest)) (*esapi.Response, error) {
+	return w.client.Ingest.PutPipeline(id, body, o...)
+}
+
+// ILMGetLifecycle implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ILMGetLifecycle(o ...func(*esapi.ILMGetLifecycleRequest)) (*esapi.Response, error) {
+	return w.client.ILM.GetLifecycle(o...)
+}
+
+// ILMPutLifecycle implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ILMPutLifecycle(policy string, o ...func(*esapi.ILMPutLifecycleRequest)) (*esapi.Response, error) {
+	return w.client.ILM.PutLifecycle(policy, o...)
+}
+
+// ClusterState implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ClusterState(o ...func(*esapi.ClusterStateRequest)) (*esapi.Response, error) {
+	return w.client.Cluster.State(o...)
+}
+
+// PutScript implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) PutScript(id string, body io.Reader, o ...func(*esapi.PutScriptRequest)) (*esapi.Response, error) {
+	return w.client.PutScript(id, body, o...)
+}
+
+// SecurityGetRole implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) SecurityGetRole(o ...func(*esapi.SecurityGetRoleRequest)) (*esapi.Response, error) {
+	return w.client.Security.GetRole(o...)
+}
+
+// SecurityPutRole implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) SecurityPutRole(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleRequest)) (*esapi.Response, error) {
+	return w.client.Security.PutRole(name, body, o...)
+}
+
+// SecurityGetRoleMapping implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) SecurityGetRoleMapping(o ...func(*esapi.SecurityGetRoleMappingRequest)) (*esapi.Response, error) {
+	return w.client.Security.GetRoleMapping(o...)
+}
+
+// SecurityPutRoleMapping implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) SecurityPutRoleMapping(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleMappingRequest)) (*esapi.Response, error) {
+	return w.client.Security.PutRoleMapping(name, body, o...)
+}
+
+// IndicesGetDataStream implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesGetDataStream(o ...func(*esapi.IndicesGetDataStreamRequest)) (*esapi.Response, error) {
+	return w.client.Indices.GetDataStream(o...)
+}
+
+// IndicesCreateDataStream implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesCreateDataStream(name string, o ...func(*esapi.IndicesCreateDataStreamRequest)) (*esapi.Response, error) {
+	return w.client.Indices.CreateDataStream(name, o...)
+}
+
+// IndicesDeleteDataStream implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesDeleteDataStream(name []string, o ...func(*esapi.IndicesDeleteDataStreamRequest)) (*esapi.Response, error) {
+	return w.client.Indices.DeleteDataStream(name, o...)
+}
+
 // Run executes the restore operation
 func Run(config Config) error {
 	if config.Verbose {
@@ -78,6 +286,23 @@ func Run(config Config) error {
 		fmt.Printf("Type: %s, Concurrency: %d\n", config.Type, config.Concurrency)
 	}
 
+	if config.OpenSearch && (config.All || config.Type != "lifecycle") {
+		return fmt.Errorf("--opensearch is only supported with --type=lifecycle")
+	}
+
+	if config.Verify {
+		config.verifier = newVerifier()
+	}
+
+	if config.All {
+		return restoreCluster(config)
+	}
+
+	// Data files are checked one by one, as the input may name several
+	if config.Type != "data" {
+		checkManifest(config.Input, config)
+	}
+
 	switch config.Type {
 	case "data":
 		return restoreData(config)
@@ -85,11 +310,33 @@ func Run(config Config) error {
 		return restoreMapping(config)
 	case "settings":
 		return restoreSettings(config)
+	case "index":
+		return restoreIndex(config)
+	case "aliases":
+		return restoreAliasFile(config)
+	case "templates":
+		return restoreTemplates(config)
+	case "pipelines":
+		return restorePipelines(config)
+	case "lifecycle":
+		return restoreLifecycle(config)
+	case "scripts":
+		return restoreScripts(config)
+	case "security":
+		return restoreSecurity(config)
+	case "all":
+		return restoreBundle(config)
 	default:
 		return fmt.Errorf("unsupported restore type: %s", config.Type)
 	}
 }
 
+// putOptions returns the options of putting definitions such as templates on
+// the destination
+func putOptions(config Config) cluster.PutOptions {
+	return cluster.PutOptions{Existing: config.Existing, Verbose: config.Verbose, Destination: config.Output}
+}
+
 // getBaseURL extracts the base URL from the input string
 func getBaseURL(s string) string {
 	// Remove any index name from the URL
@@ -117,7 +364,10 @@ func createClient(url, username, password string) (*Client, error) {
 	return &Client{API: wrapper, URL: url}, nil
 }
 
-// restoreData restores documents from file to Elasticsearch
+// restoreData restores documents from file to Elasticsearch. The input is a
+// single data file, the parts manifest of a data file split into parts, a
+// directory of data files or a glob matching several of them; several files
+// are restored concurrently.
 func restoreData(config Config) error {
 	destURL := getBaseURL(config.Output)
 	// Create destination client
@@ -126,79 +376,411 @@ func restoreData(config Config) error {
 		return fmt.Errorf("failed to create destination client: %w", err)
 	}
 
-	// Open input file
-	file, err := os.Open(config.Input)
+	renamer, err := rename.New(config.OutputIndexTemplate, config.RenameMap)
 	if err != nil {
-		return fmt.Errorf("failed to open input file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	// Get file info for progress tracking
-	fileInfo, err := file.Stat()
+	paths, dataFiles, err := inputFiles(config.Input, config)
+	if err != nil {
+		return err
+	}
+
+	index := extractIndex(config.Output)
+	if index == "" {
+		// Documents go to their own _index, so make sure those indices exist
+		// with the mappings bundled with the backup
+		for _, dataFile := range dataFiles {
+			if err := checkDataStreamRenames(backup.DataStreamsFile(dataFile), renamer, config); err != nil {
+				return err
+			}
+		}
+		for _, dataFile := range dataFiles {
+			if err := createMissingIndices(destClient, backup.MappingFile(dataFile), renamer, config); err != nil {
+				return fmt.Errorf("failed to create destination indices: %w", err)
+			}
+			if err := createMissingDataStreams(destClient, backup.DataStreamsFile(dataFile), renamer, config); err != nil {
+				return fmt.Errorf("failed to create destination data streams: %w", err)
+			}
+		}
+	}
+
+	manifests := make([]*backup.FileManifest, len(dataFiles))
+	for i, dataFile := range dataFiles {
+		manifests[i] = checkManifest(dataFile, config)
+	}
+
+	var restored int
+	if len(paths) == 1 {
+		restored, err = restoreFile(destClient, paths[0], index, renamer, config)
+	} else {
+		restored, err = restoreFiles(destClient, paths, index, renamer, config)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The documents of several data files are counted together
+	if len(dataFiles) == 1 {
+		checkDocuments(dataFiles[0], manifests[0], restored)
+	}
+
+	if config.Verbose {
+		fmt.Printf("Restore completed to %s\n", config.Output)
+	}
+
+	return nil
+}
+
+// inputFiles returns the data files to restore for input, and the data files
+// whose mapping and data streams sidecars belong to them. A parts manifest
+// lists its parts, which share the sidecars of the file they were split from;
+// a directory holds data files, sidecars and parts manifests; a glob is
+// expanded into the sorted data files it matches.
+func inputFiles(input string, config Config) ([]string, []string, error) {
+	// Standard input carries no sidecars
+	if input == backup.Stdio {
+		return []string{input}, nil, nil
+	}
+
+	if backup.IsPartsFile(input) {
+		parts, err := readParts(input, config)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read parts manifest: %w", err)
+		}
+		return parts.PartPaths(input), []string{filepath.Join(filepath.Dir(input), parts.Data)}, nil
+	}
+
+	if info, err := os.Stat(input); err == nil && info.IsDir() {
+		return directoryFiles(input, config)
+	}
+
+	if !strings.ContainsAny(input, "*?[") {
+		return []string{input}, []string{input}, nil
+	}
+
+	matches, err := filepath.Glob(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid input pattern: %w", err)
+	}
+	sort.Strings(matches)
+
+	var paths []string
+	for _, path := range matches {
+		if !isSidecar(path) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("no data files match %s", input)
+	}
+
+	return paths, paths, nil
+}
+
+// readParts reads a parts manifest, checking it when config verifies
+// backups
+func readParts(path string, config Config) (*backup.Parts, error) {
+	data, err := readFile(path, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return backup.ParseParts(path, data)
+}
+
+// directoryFiles returns the data files of a directory, in name order, and
+// the data files whose sidecars belong to them, including the files the
+// parts manifests of the directory were split from
+func directoryFiles(dir string, config Config) ([]string, []string, error) {
+	if _, err := os.Stat(filepath.Join(dir, backup.ManifestName)); err == nil {
+		return nil, nil, fmt.Errorf("%s is a directory backup, restore it with --all", dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read input directory: %w", err)
+	}
+
+	var paths, dataFiles []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if backup.IsPartsFile(path) {
+			parts, err := readParts(path, config)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read parts manifest: %w", err)
+			}
+			dataFiles = append(dataFiles, filepath.Join(dir, parts.Data))
+			continue
+		}
+		if isSidecar(path) {
+			continue
+		}
+
+		paths = append(paths, path)
+		dataFiles = append(dataFiles, path)
+	}
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("no data files in %s", dir)
+	}
+
+	return paths, dataFiles, nil
+}
+
+// isSidecar reports whether path is a mapping, data streams, pipelines or
+// parts file written next to a data file rather than a data file itself
+func isSidecar(path string) bool {
+	return strings.HasSuffix(path, backup.MappingSuffix) ||
+		strings.HasSuffix(path, backup.DataStreamsSuffix) ||
+		strings.HasSuffix(path, backup.PipelinesSuffix) ||
+		strings.HasSuffix(path, backup.ManifestSuffix) ||
+		backup.IsPartsFile(path)
+}
+
+// restoreFile indexes the documents of a backup file into index, or into each
+// document's renamed _index when index is empty, and returns the number of
+// documents indexed. Bulk files are replayed action by action instead.
+func restoreFile(destClient *Client, path, index string, renamer *rename.Renamer, config Config) (int, error) {
+	format, types, err := inputFormat(path, config)
 	if err != nil {
-		return fmt.Errorf("failed to get file info: %w", err)
+		return 0, err
+	}
+	if err := checkDocumentIndex(path, format, index); err != nil {
+		return 0, err
+	}
+
+	// Open input file, whose size is used for progress tracking, which counts
+	// the bytes read from the file as stored; standard input has no known
+	// size, so progress is shown as a spinner
+	file, size, err := openFile(path, config)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	var bar *progressbar.ProgressBar
+	if !config.Verbose {
+		description := "Restoring documents"
+		if index != "" {
+			description = fmt.Sprintf("Restoring %s", index)
+		}
+		bar = progressbar.DefaultBytes(size, description)
+	}
+
+	count, err := restoreReader(destClient, file, format, types, index, renamer, bar, config)
+	if bar != nil {
+		bar.Finish()
+	}
+	if err == nil {
+		file.check()
+	}
+
+	return count, err
+}
+
+// restoreFiles restores several data files as restoreFile does, config.Parallel
+// of them at a time, with one progress bar over their combined size. It
+// reports the outcome of every file and returns the total number of
+// documents indexed, with an error if any file failed.
+func restoreFiles(destClient *Client, paths []string, index string, renamer *rename.Renamer, config Config) (int, error) {
+	formats := make([]string, len(paths))
+	var types map[string]string
+	var total int64
+	for i, path := range paths {
+		format, columns, err := inputFormat(path, config)
+		if err != nil {
+			return 0, err
+		}
+		if err := checkDocumentIndex(path, format, index); err != nil {
+			return 0, err
+		}
+		formats[i], types = format, columns
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get file info: %w", err)
+		}
+		total += info.Size()
 	}
 
 	var bar *progressbar.ProgressBar
 	if !config.Verbose {
-		bar = progressbar.DefaultBytes(fileInfo.Size(), "Restoring documents")
+		bar = progressbar.DefaultBytes(total, fmt.Sprintf("Restoring %d files", len(paths)))
+	}
+
+	semaphore := make(chan struct{}, max(config.Parallel, 1))
+	var wg sync.WaitGroup
+	counts := make([]int, len(paths))
+	errs := make([]error, len(paths))
+
+	for i, path := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			file, _, err := openFile(path, config)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to open input file: %w", err)
+				return
+			}
+			defer file.Close()
+
+			counts[i], errs[i] = restoreReader(destClient, file, formats[i], types, index, renamer, bar, config)
+			if errs[i] == nil {
+				file.check()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if bar != nil {
+		bar.Finish()
+	}
+
+	fmt.Println("Files:")
+
+	failed := 0
+	indexed := 0
+	for i, path := range paths {
+		indexed += counts[i]
+		if errs[i] != nil {
+			fmt.Printf("  %s: FAILED after %d documents, %v\n", path, counts[i], errs[i])
+			failed++
+			continue
+		}
+		fmt.Printf("  %s: %d documents, OK\n", path, counts[i])
+	}
+
+	fmt.Printf("Restored %d documents from %d files\n", indexed, len(paths)-failed)
+
+	if failed > 0 {
+		return indexed, fmt.Errorf("failed to restore %d of %d files", failed, len(paths))
+	}
+
+	return indexed, nil
+}
+
+// restoreReader indexes the documents read from r, a data file in format,
+// advancing bar (if any) by the bytes read from r
+func restoreReader(destClient *Client, r io.Reader, format string, types map[string]string, index string, renamer *rename.Renamer, bar *progressbar.ProgressBar, config Config) (int, error) {
+	decompressed, err := backup.Decompress(&progressReader{reader: r, bar: bar})
+	if err != nil {
+		return 0, err
+	}
+
+	reader := bufio.NewReader(decompressed)
+	if format == "auto" && isBulkFile(reader) {
+		return restoreBulk(destClient, reader, index, renamer, config)
 	}
 
 	// Create worker pool
 	docChan := make(chan Document, config.Concurrency*2)
 	var wg sync.WaitGroup
+	var indexed atomic.Int64
+	writer := newDataStreamWriter(destClient)
 
-	index := extractIndex(config.Output)
 	// Start workers
 	for i := 0; i < config.Concurrency; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for doc := range docChan {
-				if err := indexDocument(destClient, index, doc); err != nil {
+				destIndex := index
+				if destIndex == "" {
+					destIndex = renamer.Apply(doc.Index)
+				}
+				if written, err := writer.write(destIndex, doc); err != nil {
 					fmt.Printf("Error indexing document %s: %v\n", doc.ID, err)
+				} else if written {
+					indexed.Add(1)
 				}
 			}
 		}()
 	}
 
 	// Read and process documents
+	var readErr error
 	go func() {
 		defer close(docChan)
 
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if line == "" {
-				continue
-			}
-
-			var doc Document
-			if err := json.Unmarshal([]byte(line), &doc); err != nil {
-				fmt.Printf("Error parsing document: %v\n", err)
-				continue
-			}
-
+		fn := func(doc Document) {
 			docChan <- doc
-
-			if bar != nil {
-				bar.Add(len(line))
-			}
 		}
 
-		if err := scanner.Err(); err != nil {
-			fmt.Printf("Error reading file: %v\n", err)
+		switch format {
+		case "csv":
+			readErr = readCSV(reader, types, config.IDField, fn)
+		case "jsonl":
+			readErr = readJSONLines(reader, config.IDField, maxDocumentSize(config), fn)
+		default:
+			readErr = readDocuments(reader, maxDocumentSize(config), fn)
 		}
 	}()
 
 	wg.Wait()
 
-	if bar != nil {
-		bar.Finish()
+	// The reader closed docChan, which the workers waited for, so readErr
+	// is set by now
+	if readErr != nil {
+		return int(indexed.Load()), fmt.Errorf("failed to read file: %w", readErr)
 	}
 
-	if config.Verbose {
-		fmt.Printf("Restore completed to %s\n", config.Output)
+	return int(indexed.Load()), nil
+}
+
+// inputFormat returns the format of a data file ("auto", "csv" or "jsonl")
+// and the parsed csv column types
+func inputFormat(path string, config Config) (string, map[string]string, error) {
+	format := config.InputFormat
+	switch format {
+	case "", "auto":
+		format = "auto"
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			format = "csv"
+		}
+	case "csv", "jsonl":
+	default:
+		return "", nil, fmt.Errorf("unsupported input format: %s", config.InputFormat)
+	}
+
+	types, err := parseColumnTypes(config.ColumnTypes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return format, types, nil
+}
+
+// checkDocumentIndex returns an error if the documents of a data file in
+// format have no _index to be restored to when the output has no index:
+// jsonl documents never have one, and csv rows only with an _index column.
+// Standard input cannot be read twice, so its csv header is not checked.
+func checkDocumentIndex(path, format, index string) error {
+	if index != "" {
+		return nil
+	}
+
+	switch format {
+	case "jsonl":
+		return fmt.Errorf("jsonl input requires an index in the output URL")
+	case "csv":
+		if path == backup.Stdio {
+			return nil
+		}
+		header, err := readCSVHeader(path)
+		if err != nil {
+			return err
+		}
+		if header != nil && !slices.Contains(header, "_index") {
+			return fmt.Errorf("csv input without an _index column requires an index in the output URL")
+		}
 	}
 
 	return nil
@@ -207,7 +789,7 @@ func restoreData(config Config) error {
 // restoreMapping restores index mapping from file
 func restoreMapping(config Config) error {
 	// Read mapping from file
-	data, err := os.ReadFile(config.Input)
+	data, err := readFile(config.Input, config)
 	if err != nil {
 		return fmt.Errorf("failed to read mapping file: %w", err)
 	}
@@ -236,7 +818,7 @@ func restoreMapping(config Config) error {
 // restoreSettings restores index settings from file
 func restoreSettings(config Config) error {
 	// Read settings from file
-	data, err := os.ReadFile(config.Input)
+	data, err := readFile(config.Input, config)
 	if err != nil {
 		return fmt.Errorf("failed to read settings file: %w", err)
 	}
@@ -259,6 +841,10 @@ func restoreSettings(config Config) error {
 		return fmt.Errorf("could not extract index from output URL")
 	}
 
+	if err := restoreReferencedPipelines(destClient, backup.PipelinesFile(config.Input), config); err != nil {
+		return err
+	}
+
 	return putSettings(destClient, index, settings)
 }
 
@@ -273,7 +859,20 @@ func extractIndex(url string) string {
 	return ""
 }
 
+// indexDocument indexes doc into index, replacing a document with the same id
 func indexDocument(client *Client, index string, doc Document) error {
+	return putDocument(client, index, doc, "")
+}
+
+// createDocument indexes doc into index with create semantics, as required
+// by data streams
+func createDocument(client *Client, index string, doc Document) error {
+	return putDocument(client, index, doc, "create")
+}
+
+// putDocument indexes doc into index with the given op_type, or the default
+// one when empty
+func putDocument(client *Client, index string, doc Document, opType string) error {
 	data, err := json.Marshal(doc.Source)
 	if err != nil {
 		return err
@@ -290,6 +889,7 @@ func indexDocument(client *Client, index string, doc Document) error {
 		strings.NewReader(string(data)),
 		func(r *esapi.IndexRequest) {
 			r.DocumentID = doc.ID
+			r.OpType = opType
 			r.Refresh = "false"
 		},
 	)
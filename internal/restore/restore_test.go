@@ -1,11 +1,20 @@
 package restore
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/backup"
 )
 
 // MockElasticsearchAPI implements ElasticsearchAPI for testing
@@ -14,10 +23,69 @@ type MockElasticsearchAPI struct {
 	MappingResponse  *esapi.Response
 	SettingsResponse *esapi.Response
 	ShouldFail       bool
+
+	// ExistingIndices lists the indices reported as existing
+	ExistingIndices map[string]bool
+	// CreatedIndices records the indices created through IndicesCreate
+	CreatedIndices []string
+	// CreatedBodies records the create index request bodies by index
+	CreatedBodies map[string]string
+	// DeletedIndices records the indices deleted through IndicesDelete
+	DeletedIndices []string
+	// AliasRequests records the bodies sent to IndicesUpdateAliases
+	AliasRequests []string
+	// ExistingTemplates lists the templates reported as existing, as kind/name
+	ExistingTemplates map[string]bool
+	// PutTemplates records the templates put, as kind/name, in order
+	PutTemplates []string
+
+	// Pipelines holds the ingest pipeline bodies returned by IngestGetPipeline by id
+	Pipelines map[string]string
+	// PutPipelines records the ids of the ingest pipelines put, in order
+	PutPipelines []string
+
+	// Policies holds the ILM policies returned by ILMGetLifecycle by name
+	Policies map[string]string
+	// PutPolicies records the names of the ILM policies put, in order
+	PutPolicies []string
+
+	// Scripts holds the stored scripts of the cluster state by id
+	Scripts map[string]string
+	// PutScripts records the ids of the stored scripts put, in order
+	PutScripts []string
+
+	// Roles and RoleMappings hold the bodies returned by the get role and get
+	// role mapping APIs by name
+	Roles        map[string]string
+	RoleMappings map[string]string
+	// PutRoles and PutRoleMappings record the names put, in order
+	PutRoles        []string
+	PutRoleMappings []string
+
+	// DataStreams holds the data streams reported by IndicesGetDataStream,
+	// mapped to the name of their index template
+	DataStreams map[string]string
+	// DataStreamStatus, when set, is the status of every IndicesGetDataStream
+	// response
+	DataStreamStatus int
+	// CreatedDataStreams records the data streams created, in order
+	CreatedDataStreams []string
+	// DeletedDataStreams records the data streams deleted, in order
+	DeletedDataStreams []string
+	// OpTypes records the op_type of the last document written to each index
+	OpTypes map[string]string
+
+	// BulkRequests records the bodies sent to Bulk
+	BulkRequests []string
+	// BulkFailures lists the document ids whose bulk actions fail
+	BulkFailures map[string]bool
+
+	mu sync.Mutex
 }
 
 // Index implements ElasticsearchAPI for testing
 func (m *MockElasticsearchAPI) Index(index string, body io.Reader, o ...func(*esapi.IndexRequest)) (*esapi.Response, error) {
+	m.recordOpType(index, o)
 	if m.ShouldFail {
 		return createMockErrorResponse(), nil
 	}
@@ -27,6 +95,20 @@ func (m *MockElasticsearchAPI) Index(index string, body io.Reader, o ...func(*es
 	return createMockIndexResponse(), nil
 }
 
+// Bulk implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) Bulk(body io.Reader, o ...func(*esapi.BulkRequest)) (*esapi.Response, error) {
+	if m.ShouldFail {
+		return createMockErrorResponse(), nil
+	}
+
+	data, _ := io.ReadAll(body)
+	m.mu.Lock()
+	m.BulkRequests = append(m.BulkRequests, string(data))
+	m.mu.Unlock()
+
+	return createMockBulkResponse(string(data), m.BulkFailures), nil
+}
+
 // IndicesPutMapping implements ElasticsearchAPI for testing
 func (m *MockElasticsearchAPI) IndicesPutMapping(indices []string, body io.Reader, o ...func(*esapi.IndicesPutMappingRequest)) (*esapi.Response, error) {
 	if m.ShouldFail {
@@ -49,7 +131,312 @@ func (m *MockElasticsearchAPI) IndicesPutSettings(body io.Reader, o ...func(*esa
 	return createMockSuccessResponse(), nil
 }
 
+// IndicesExists implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesExists(index []string, o ...func(*esapi.IndicesExistsRequest)) (*esapi.Response, error) {
+	if m.ShouldFail {
+		return createMockErrorResponse(), nil
+	}
+	for _, name := range index {
+		if !m.ExistingIndices[name] {
+			return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+	}
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+// IndicesCreate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesCreate(index string, o ...func(*esapi.IndicesCreateRequest)) (*esapi.Response, error) {
+	if m.ShouldFail {
+		return createMockErrorResponse(), nil
+	}
+
+	req := &esapi.IndicesCreateRequest{Index: index}
+	for _, f := range o {
+		f(req)
+	}
+
+	m.CreatedIndices = append(m.CreatedIndices, index)
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		if m.CreatedBodies == nil {
+			m.CreatedBodies = make(map[string]string)
+		}
+		m.CreatedBodies[index] = string(body)
+	}
+	if m.ExistingIndices == nil {
+		m.ExistingIndices = make(map[string]bool)
+	}
+	m.ExistingIndices[index] = true
+
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesDelete implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesDelete(index []string, o ...func(*esapi.IndicesDeleteRequest)) (*esapi.Response, error) {
+	if m.ShouldFail {
+		return createMockErrorResponse(), nil
+	}
+	for _, name := range index {
+		m.DeletedIndices = append(m.DeletedIndices, name)
+		delete(m.ExistingIndices, name)
+	}
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesUpdateAliases implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesUpdateAliases(body io.Reader, o ...func(*esapi.IndicesUpdateAliasesRequest)) (*esapi.Response, error) {
+	if m.ShouldFail {
+		return createMockErrorResponse(), nil
+	}
+	data, _ := io.ReadAll(body)
+	m.AliasRequests = append(m.AliasRequests, string(data))
+	return createMockSuccessResponse(), nil
+}
+
+// ClusterPutComponentTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ClusterPutComponentTemplate(name string, body io.Reader, o ...func(*esapi.ClusterPutComponentTemplateRequest)) (*esapi.Response, error) {
+	return m.putTemplate("component/" + name)
+}
+
+// ClusterExistsComponentTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ClusterExistsComponentTemplate(name string, o ...func(*esapi.ClusterExistsComponentTemplateRequest)) (*esapi.Response, error) {
+	return createMockExistsResponse(m.ExistingTemplates["component/"+name]), nil
+}
+
+// IndicesPutIndexTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesPutIndexTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutIndexTemplateRequest)) (*esapi.Response, error) {
+	return m.putTemplate("index/" + name)
+}
+
+// IndicesExistsIndexTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesExistsIndexTemplate(name string, o ...func(*esapi.IndicesExistsIndexTemplateRequest)) (*esapi.Response, error) {
+	return createMockExistsResponse(m.ExistingTemplates["index/"+name]), nil
+}
+
+// IndicesPutTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesPutTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutTemplateRequest)) (*esapi.Response, error) {
+	return m.putTemplate("legacy/" + name)
+}
+
+// IndicesExistsTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesExistsTemplate(name []string, o ...func(*esapi.IndicesExistsTemplateRequest)) (*esapi.Response, error) {
+	return createMockExistsResponse(m.ExistingTemplates["legacy/"+strings.Join(name, ",")]), nil
+}
+
+func (m *MockElasticsearchAPI) putTemplate(name string) (*esapi.Response, error) {
+	if m.ShouldFail {
+		return createMockErrorResponse(), nil
+	}
+	m.PutTemplates = append(m.PutTemplates, name)
+	return createMockSuccessResponse(), nil
+}
+
+// IngestGetPipeline implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IngestGetPipeline(o ...func(*esapi.IngestGetPipelineRequest)) (*esapi.Response, error) {
+	req := &esapi.IngestGetPipelineRequest{}
+	for _, f := range o {
+		f(req)
+	}
+
+	var entries []string
+	for _, pattern := range strings.Split(req.PipelineID, ",") {
+		for id, body := range m.Pipelines {
+			if matched, _ := path.Match(pattern, id); matched {
+				entries = append(entries, fmt.Sprintf("%q: %s", id, body))
+			}
+		}
+	}
+
+	if len(entries) == 0 && !strings.Contains(req.PipelineID, "*") {
+		return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+	}
+
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader("{" + strings.Join(entries, ",") + "}")),
+	}, nil
+}
+
+// IngestPutPipeline implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IngestPutPipeline(id string, body io.Reader, o ...func(*esapi.IngestPutPipelineRequest)) (*esapi.Response, error) {
+	if m.ShouldFail {
+		return createMockErrorResponse(), nil
+	}
+	m.PutPipelines = append(m.PutPipelines, id)
+	return createMockSuccessResponse(), nil
+}
+
+// ILMGetLifecycle implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ILMGetLifecycle(o ...func(*esapi.ILMGetLifecycleRequest)) (*esapi.Response, error) {
+	var entries []string
+	for name, policy := range m.Policies {
+		entries = append(entries, fmt.Sprintf(`%q: {"version": 1, "modified_date": "2024-01-01T00:00:00Z", "policy": %s, "in_use_by": {"indices": []}}`, name, policy))
+	}
+
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader("{" + strings.Join(entries, ",") + "}")),
+	}, nil
+}
+
+// ILMPutLifecycle implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ILMPutLifecycle(policy string, o ...func(*esapi.ILMPutLifecycleRequest)) (*esapi.Response, error) {
+	if m.ShouldFail {
+		return createMockErrorResponse(), nil
+	}
+	m.PutPolicies = append(m.PutPolicies, policy)
+	return createMockSuccessResponse(), nil
+}
+
+// ClusterState implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ClusterState(o ...func(*esapi.ClusterStateRequest)) (*esapi.Response, error) {
+	body := `{}`
+	if len(m.Scripts) > 0 {
+		var entries []string
+		for id, script := range m.Scripts {
+			entries = append(entries, fmt.Sprintf("%q: %s", id, script))
+		}
+		body = `{"metadata": {"stored_scripts": {` + strings.Join(entries, ",") + `}}}`
+	}
+
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+// PutScript implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) PutScript(id string, body io.Reader, o ...func(*esapi.PutScriptRequest)) (*esapi.Response, error) {
+	if m.ShouldFail {
+		return createMockErrorResponse(), nil
+	}
+	m.PutScripts = append(m.PutScripts, id)
+	return createMockSuccessResponse(), nil
+}
+
+// SecurityGetRole implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) SecurityGetRole(o ...func(*esapi.SecurityGetRoleRequest)) (*esapi.Response, error) {
+	return createMockObjectsResponse(m.Roles), nil
+}
+
+// SecurityPutRole implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) SecurityPutRole(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleRequest)) (*esapi.Response, error) {
+	m.PutRoles = append(m.PutRoles, name)
+	return createMockSuccessResponse(), nil
+}
+
+// SecurityGetRoleMapping implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) SecurityGetRoleMapping(o ...func(*esapi.SecurityGetRoleMappingRequest)) (*esapi.Response, error) {
+	return createMockObjectsResponse(m.RoleMappings), nil
+}
+
+// SecurityPutRoleMapping implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) SecurityPutRoleMapping(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleMappingRequest)) (*esapi.Response, error) {
+	m.PutRoleMappings = append(m.PutRoleMappings, name)
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesGetDataStream implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesGetDataStream(o ...func(*esapi.IndicesGetDataStreamRequest)) (*esapi.Response, error) {
+	req := &esapi.IndicesGetDataStreamRequest{}
+	for _, f := range o {
+		f(req)
+	}
+	if m.DataStreamStatus != 0 {
+		return &esapi.Response{StatusCode: m.DataStreamStatus, Body: io.NopCloser(strings.NewReader(`{"error": "forbidden"}`))}, nil
+	}
+
+	var entries []string
+	for _, name := range req.Name {
+		if template, ok := m.DataStreams[name]; ok {
+			entries = append(entries, fmt.Sprintf(`{"name": %q, "template": %q, "timestamp_field": {"name": "@timestamp"}}`, name, template))
+		}
+	}
+	if len(entries) == 0 {
+		return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(`{"error": "no such index"}`))}, nil
+	}
+
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(`{"data_streams": [` + strings.Join(entries, ",") + `]}`)),
+	}, nil
+}
+
+// IndicesCreateDataStream implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesCreateDataStream(name string, o ...func(*esapi.IndicesCreateDataStreamRequest)) (*esapi.Response, error) {
+	if m.DataStreams == nil {
+		m.DataStreams = make(map[string]string)
+	}
+	m.DataStreams[name] = ""
+	m.CreatedDataStreams = append(m.CreatedDataStreams, name)
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesDeleteDataStream implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesDeleteDataStream(name []string, o ...func(*esapi.IndicesDeleteDataStreamRequest)) (*esapi.Response, error) {
+	for _, stream := range name {
+		m.DeletedDataStreams = append(m.DeletedDataStreams, stream)
+		delete(m.DataStreams, stream)
+	}
+	return createMockSuccessResponse(), nil
+}
+
+// recordOpType records the op_type of an index request
+func (m *MockElasticsearchAPI) recordOpType(index string, o []func(*esapi.IndexRequest)) {
+	req := &esapi.IndexRequest{}
+	for _, f := range o {
+		f(req)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.OpTypes == nil {
+		m.OpTypes = make(map[string]string)
+	}
+	m.OpTypes[index] = req.OpType
+}
+
+// createMockObjectsResponse returns a JSON object of the given bodies by name
+func createMockObjectsResponse(objects map[string]string) *esapi.Response {
+	var entries []string
+	for name, body := range objects {
+		entries = append(entries, fmt.Sprintf("%q: %s", name, body))
+	}
+
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader("{" + strings.Join(entries, ",") + "}")),
+	}
+}
+
 // Helper functions to create mock responses
+// createMockBulkResponse answers every action of a bulk body, failing those
+// on the given document ids
+func createMockBulkResponse(body string, failures map[string]bool) *esapi.Response {
+	var items []map[string]interface{}
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	for i := 0; i < len(lines); i++ {
+		var action map[string]map[string]interface{}
+		json.Unmarshal([]byte(lines[i]), &action)
+
+		for op, meta := range action {
+			outcome := map[string]interface{}{"_index": meta["_index"], "_id": meta["_id"], "status": 200}
+			if id, _ := meta["_id"].(string); failures[id] {
+				outcome["status"] = 400
+				outcome["error"] = map[string]interface{}{"type": "mapper_parsing_exception"}
+			}
+			items = append(items, map[string]interface{}{op: outcome})
+
+			if op != "delete" {
+				i++
+			}
+		}
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{"errors": len(failures) > 0, "items": items})
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+	}
+}
+
 func createMockIndexResponse() *esapi.Response {
 	responseBody := `{
 		"_index": "test-index",
@@ -64,6 +451,13 @@ func createMockIndexResponse() *esapi.Response {
 	}
 }
 
+func createMockExistsResponse(exists bool) *esapi.Response {
+	if exists {
+		return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}
+	}
+	return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(""))}
+}
+
 func createMockSuccessResponse() *esapi.Response {
 	responseBody := `{"acknowledged": true}`
 	return &esapi.Response{
@@ -745,3 +1139,144 @@ func TestMappingAndSettingsFunctions(t *testing.T) {
 		}
 	})
 }
+
+// writePartFiles writes n ndjson data files of two documents each to dir,
+// named like the parts of logs.ndjson
+func writePartFiles(t *testing.T, dir string, n int) []string {
+	var paths []string
+	for i := 1; i <= n; i++ {
+		path := backup.PartName(filepath.Join(dir, "logs.ndjson"), i)
+		data := fmt.Sprintf("{\"_index\": \"logs\", \"_id\": \"%d-a\", \"_source\": {}}\n{\"_index\": \"logs\", \"_id\": \"%d-b\", \"_source\": {}}\n", i, i)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write part: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestInputFilesManifest(t *testing.T) {
+	dir := t.TempDir()
+	paths := writePartFiles(t, dir, 2)
+
+	manifest := backup.PartsFile(filepath.Join(dir, "logs.ndjson"))
+	parts := &backup.Parts{Data: "logs.ndjson", Format: "ndjson", Documents: 4, Parts: []backup.Part{
+		{File: filepath.Base(paths[0]), Documents: 2},
+		{File: filepath.Base(paths[1]), Documents: 2},
+	}}
+	if _, err := backup.WriteParts(manifest, parts); err != nil {
+		t.Fatalf("WriteParts failed: %v", err)
+	}
+
+	files, dataFiles, err := inputFiles(manifest, Config{})
+	if err != nil {
+		t.Fatalf("inputFiles failed: %v", err)
+	}
+	if !reflect.DeepEqual(files, paths) {
+		t.Errorf("Expected %v, got %v", paths, files)
+	}
+	if !reflect.DeepEqual(dataFiles, []string{filepath.Join(dir, "logs.ndjson")}) {
+		t.Errorf("Expected the sidecars of the unsplit file, got %v", dataFiles)
+	}
+}
+
+func TestInputFilesGlob(t *testing.T) {
+	dir := t.TempDir()
+	paths := writePartFiles(t, dir, 3)
+
+	// Sidecars matched by the glob are not data files
+	if err := os.WriteFile(backup.MappingFile(paths[0]), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to write mapping: %v", err)
+	}
+
+	files, dataFiles, err := inputFiles(filepath.Join(dir, "logs.part-*"), Config{})
+	if err != nil {
+		t.Fatalf("inputFiles failed: %v", err)
+	}
+	if !reflect.DeepEqual(files, paths) || !reflect.DeepEqual(dataFiles, paths) {
+		t.Errorf("Expected %v, got %v with sidecars of %v", paths, files, dataFiles)
+	}
+
+	if _, _, err := inputFiles(filepath.Join(dir, "other-*.ndjson"), Config{}); err == nil {
+		t.Error("Expected error for a glob matching nothing")
+	}
+
+	files, dataFiles, err = inputFiles(paths[0], Config{})
+	if err != nil || len(files) != 1 || dataFiles[0] != paths[0] {
+		t.Errorf("Expected a plain file to be restored alone, got %v, %v, %v", files, dataFiles, err)
+	}
+}
+
+func TestInputFilesDirectory(t *testing.T) {
+	dir := t.TempDir()
+	paths := writePartFiles(t, dir, 2)
+
+	// Manifests and sidecars are skipped, hidden files and subdirectories too
+	manifest := backup.PartsFile(filepath.Join(dir, "logs.ndjson"))
+	parts := &backup.Parts{Data: "logs.ndjson", Parts: []backup.Part{{File: filepath.Base(paths[0])}}}
+	if _, err := backup.WriteParts(manifest, parts); err != nil {
+		t.Fatalf("WriteParts failed: %v", err)
+	}
+	for _, name := range []string{"logs.ndjson.mapping.json", "logs.ndjson.datastreams.json", "logs.ndjson.manifest.json", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(`{}`), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	files, dataFiles, err := inputFiles(dir, Config{})
+	if err != nil {
+		t.Fatalf("inputFiles failed: %v", err)
+	}
+	if !reflect.DeepEqual(files, paths) {
+		t.Errorf("Expected %v, got %v", paths, files)
+	}
+	if !slices.Contains(dataFiles, filepath.Join(dir, "logs.ndjson")) {
+		t.Errorf("Expected the sidecars of the manifest's data file, got %v", dataFiles)
+	}
+
+	if _, _, err := inputFiles(t.TempDir(), Config{}); err == nil {
+		t.Error("Expected error for a directory without data files")
+	}
+
+	backupDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(backupDir, backup.ManifestName), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if _, _, err := inputFiles(backupDir, Config{}); err == nil || !strings.Contains(err.Error(), "--all") {
+		t.Errorf("Expected a directory backup to point to --all, got %v", err)
+	}
+}
+
+func TestRestoreFiles(t *testing.T) {
+	paths := writePartFiles(t, t.TempDir(), 3)
+
+	// Not verbose, so that all files advance the shared progress bar
+	count, err := restoreFiles(createMockClient(), paths, "logs", nil, Config{Concurrency: 2, Parallel: 2})
+	if err != nil {
+		t.Fatalf("restoreFiles failed: %v", err)
+	}
+	if count != 6 {
+		t.Errorf("Expected 6 documents restored, got %d", count)
+	}
+
+	// A malformed file fails on its own, the others are still restored
+	broken := filepath.Join(filepath.Dir(paths[0]), "broken.json")
+	if err := os.WriteFile(broken, []byte(`[{"_index": "logs", "_id": "x", "_source": {}}, {`), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	count, err = restoreFiles(createMockClient(), append(paths, broken), "logs", nil, Config{Concurrency: 2, Parallel: 2, Verbose: true})
+	if err == nil || !strings.Contains(err.Error(), "1 of 4 files") {
+		t.Errorf("Expected the broken file to be reported, got %v", err)
+	}
+	if count != 7 {
+		t.Errorf("Expected 7 documents restored, got %d", count)
+	}
+
+	missing := filepath.Join(filepath.Dir(paths[0]), "missing.ndjson")
+	if _, err := restoreFiles(createMockClient(), append(paths, missing), "logs", nil, Config{Concurrency: 2, Parallel: 2}); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
@@ -0,0 +1,27 @@
+package restore
+
+import (
+	"fmt"
+
+	"github.com/lilmonk/elasticdump/internal/storedscripts"
+)
+
+// restoreScripts puts the stored scripts of a file written by the "scripts"
+// backup type
+func restoreScripts(config Config) error {
+	scripts, err := readJSONFile(config.Input, config)
+	if err != nil {
+		return fmt.Errorf("failed to read scripts file: %w", err)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("scripts type requires a cluster URL without path as output")
+	}
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return storedscripts.PutScripts(destClient.API, scripts, putOptions(config))
+}
@@ -0,0 +1,25 @@
+package restore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/storedscripts"
+)
+
+func TestPutScripts(t *testing.T) {
+	scripts := map[string]interface{}{
+		"boost":  map[string]interface{}{"script": map[string]interface{}{"lang": "painless", "source": "1"}},
+		"search": map[string]interface{}{"script": map[string]interface{}{"lang": "mustache", "source": "{}"}},
+	}
+
+	api := &MockElasticsearchAPI{Scripts: map[string]string{"boost": `{"lang": "painless", "source": "2"}`}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := storedscripts.PutScripts(client.API, scripts, putOptions(Config{Existing: "overwrite"})); err != nil {
+		t.Fatalf("PutScripts failed: %v", err)
+	}
+	if !reflect.DeepEqual(api.PutScripts, []string{"boost", "search"}) {
+		t.Errorf("Expected both scripts to be put, got %v", api.PutScripts)
+	}
+}
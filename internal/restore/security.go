@@ -0,0 +1,59 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lilmonk/elasticdump/internal/security"
+)
+
+// restoreSecurity applies the roles and role mappings of a file written by
+// the "security" backup type. Reserved entries found in the file are skipped
+// since the destination has its own.
+func restoreSecurity(config Config) error {
+	data, err := readFile(config.Input, config)
+	if err != nil {
+		return fmt.Errorf("failed to read security file: %w", err)
+	}
+
+	var export security.Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return fmt.Errorf("failed to parse security file: %w", err)
+	}
+
+	for name, role := range export.Roles {
+		if security.IsReserved(role) {
+			fmt.Printf("Skipping reserved role %s\n", name)
+			delete(export.Roles, name)
+			continue
+		}
+		export.Roles[name] = security.RoleBody(role)
+	}
+	for name, mapping := range export.RoleMappings {
+		if security.IsReserved(mapping) {
+			fmt.Printf("Skipping reserved role mapping %s\n", name)
+			delete(export.RoleMappings, name)
+		}
+	}
+
+	if len(export.Roles)+len(export.RoleMappings) == 0 {
+		return fmt.Errorf("security file contains no roles or role mappings")
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("security type requires a cluster URL without path as output")
+	}
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return security.Apply(destClient.API, &export, applyOptions(config))
+}
+
+// applyOptions returns the options of applying roles and role mappings to the
+// destination
+func applyOptions(config Config) security.ApplyOptions {
+	return security.ApplyOptions{PutOptions: putOptions(config), DryRun: config.DryRun}
+}
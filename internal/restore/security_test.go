@@ -0,0 +1,46 @@
+package restore
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/security"
+)
+
+func TestApplySecurity(t *testing.T) {
+	export := &security.Export{
+		Roles: map[string]interface{}{
+			"logs-reader": map[string]interface{}{"cluster": []interface{}{"monitor"}},
+		},
+		RoleMappings: map[string]interface{}{
+			"logs-ldap": map[string]interface{}{"enabled": true, "roles": []interface{}{"logs-reader"}},
+		},
+	}
+
+	api := &MockElasticsearchAPI{}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := security.Apply(client.API, export, applyOptions(Config{})); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !reflect.DeepEqual(api.PutRoles, []string{"logs-reader"}) {
+		t.Errorf("Expected logs-reader to be put, got %v", api.PutRoles)
+	}
+	if !reflect.DeepEqual(api.PutRoleMappings, []string{"logs-ldap"}) {
+		t.Errorf("Expected logs-ldap to be put, got %v", api.PutRoleMappings)
+	}
+}
+
+func TestRestoreSecurityReservedOnly(t *testing.T) {
+	input := t.TempDir() + "/security.json"
+	content := `{"roles": {"superuser": {"cluster": ["all"], "metadata": {"_reserved": true}}}, "role_mappings": {}}`
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	config := Config{Input: input, Output: "http://localhost:9200", Type: "security"}
+	if err := restoreSecurity(config); err == nil {
+		t.Error("Expected error when the file only holds reserved roles")
+	}
+}
@@ -0,0 +1,33 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lilmonk/elasticdump/internal/templates"
+)
+
+// restoreTemplates puts the templates of a file written by the "templates"
+// backup type, component templates first
+func restoreTemplates(config Config) error {
+	data, err := readFile(config.Input, config)
+	if err != nil {
+		return fmt.Errorf("failed to read templates file: %w", err)
+	}
+
+	var set templates.Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to parse templates file: %w", err)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("templates type requires a cluster URL without path as output")
+	}
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return templates.Put(destClient.API, &set, putOptions(config))
+}
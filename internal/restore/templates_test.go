@@ -0,0 +1,35 @@
+package restore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/templates"
+)
+
+func TestPutTemplates(t *testing.T) {
+	set := &templates.Set{
+		ComponentTemplates: []templates.ComponentTemplate{{Name: "base", ComponentTemplate: map[string]interface{}{}}},
+		IndexTemplates: []templates.IndexTemplate{{Name: "logs", IndexTemplate: map[string]interface{}{
+			"index_patterns": []interface{}{"logs-*"},
+			"composed_of":    []interface{}{"base"},
+		}}},
+	}
+
+	api := &MockElasticsearchAPI{ExistingTemplates: map[string]bool{"component/base": true}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := templates.Put(client.API, set, putOptions(Config{Existing: "fail"})); err == nil {
+		t.Fatal("Expected error for existing component template")
+	}
+	if len(api.PutTemplates) != 0 {
+		t.Errorf("Expected nothing to be put, got %v", api.PutTemplates)
+	}
+
+	if err := templates.Put(client.API, set, putOptions(Config{Existing: "overwrite"})); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if expected := []string{"component/base", "index/logs"}; !reflect.DeepEqual(api.PutTemplates, expected) {
+		t.Errorf("Expected put order %v, got %v", expected, api.PutTemplates)
+	}
+}
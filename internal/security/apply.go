@@ -0,0 +1,240 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// Client is the part of the Elasticsearch API used for roles and role
+// mappings
+type Client interface {
+	SecurityGetRole(o ...func(*esapi.SecurityGetRoleRequest)) (*esapi.Response, error)
+	SecurityPutRole(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleRequest)) (*esapi.Response, error)
+	SecurityGetRoleMapping(o ...func(*esapi.SecurityGetRoleMappingRequest)) (*esapi.Response, error)
+	SecurityPutRoleMapping(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleMappingRequest)) (*esapi.Response, error)
+}
+
+// ApplyOptions control how Apply changes the destination
+type ApplyOptions struct {
+	cluster.PutOptions
+
+	// DryRun only shows the changes
+	DryRun bool
+}
+
+// Get returns all roles and role mappings of the cluster, with roles
+// stripped of the fields the put role API rejects
+func Get(client Client) (*Export, error) {
+	roles, err := getRoles(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	mappings, err := getRoleMappings(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role mappings: %w", err)
+	}
+
+	return &Export{Roles: roles, RoleMappings: mappings}, nil
+}
+
+// getRoles returns all roles of the cluster keyed by name as put role request
+// bodies. The API answers 404 when there are none.
+func getRoles(client Client) (map[string]interface{}, error) {
+	res, err := client.SecurityGetRole()
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	roles := make(map[string]interface{})
+	if res.StatusCode == 404 {
+		return roles, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get role failed: %s", res.String())
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&roles); err != nil {
+		return nil, err
+	}
+
+	for name, role := range roles {
+		roles[name] = RoleBody(role)
+	}
+
+	return roles, nil
+}
+
+// getRoleMappings returns all role mappings of the cluster keyed by name. The
+// API answers 404 when there are none.
+func getRoleMappings(client Client) (map[string]interface{}, error) {
+	res, err := client.SecurityGetRoleMapping()
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	mappings := make(map[string]interface{})
+	if res.StatusCode == 404 {
+		return mappings, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get role mapping failed: %s", res.String())
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&mappings); err != nil {
+		return nil, err
+	}
+
+	return mappings, nil
+}
+
+// Apply puts roles and role mappings on the destination, roles first so that
+// the mappings granting them never refer to a missing role. Entries identical
+// to those of the destination are left alone; differing ones are handled by
+// opts.Existing. With opts.DryRun the changes are only shown.
+func Apply(client Client, export *Export, opts ApplyOptions) error {
+	if err := cluster.CheckExisting(opts.Existing, "security"); err != nil {
+		return err
+	}
+
+	current, err := Get(client)
+	if err != nil {
+		return fmt.Errorf("failed to get destination security: %w", err)
+	}
+
+	roleChanges := Diff(export.Roles, current.Roles)
+	mappingChanges := Diff(export.RoleMappings, current.RoleMappings)
+
+	if opts.DryRun {
+		printChanges("role", roleChanges)
+		printChanges("role mapping", mappingChanges)
+		fmt.Printf("Dry run: no roles or role mappings were changed on %s\n", opts.Destination)
+		return nil
+	}
+
+	// Decide what to do with every entry before changing anything
+	roles, conflicts := selectChanges("role", roleChanges, opts)
+	mappings, mappingConflicts := selectChanges("role mapping", mappingChanges, opts)
+	conflicts = append(conflicts, mappingConflicts...)
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("roles or role mappings already exist with a different definition: %s (use --existing=skip or --existing=overwrite)",
+			strings.Join(conflicts, ", "))
+	}
+
+	for _, name := range roles {
+		if err := putRole(client, name, export.Roles[name]); err != nil {
+			return fmt.Errorf("failed to put role %s: %w", name, err)
+		}
+
+		if opts.Verbose {
+			fmt.Printf("Put role %s\n", name)
+		}
+	}
+
+	for _, name := range mappings {
+		if err := putRoleMapping(client, name, export.RoleMappings[name]); err != nil {
+			return fmt.Errorf("failed to put role mapping %s: %w", name, err)
+		}
+
+		if opts.Verbose {
+			fmt.Printf("Put role mapping %s\n", name)
+		}
+	}
+
+	fmt.Printf("Put %d roles and %d role mappings to %s\n", len(roles), len(mappings), opts.Destination)
+
+	return nil
+}
+
+// selectChanges returns the names of the entries to put and, with the
+// fail policy, those that differ on the destination
+func selectChanges(kind string, changes []Change, opts ApplyOptions) (selected, conflicts []string) {
+	for _, change := range changes {
+		switch change.Action {
+		case Unchanged:
+			if opts.Verbose {
+				fmt.Printf("Skipping unchanged %s %s\n", kind, change.Name)
+			}
+			continue
+		case Update:
+			switch opts.Existing {
+			case "skip":
+				if opts.Verbose {
+					fmt.Printf("Skipping existing %s %s\n", kind, change.Name)
+				}
+				continue
+			case "overwrite":
+			default:
+				conflicts = append(conflicts, kind+" "+change.Name)
+			}
+		}
+
+		selected = append(selected, change.Name)
+	}
+
+	return selected, conflicts
+}
+
+// printChanges prints one line per change: "+" for an entry to be
+// created, "~" for one to be updated with the fields that differ and "=" for
+// one that is already up to date
+func printChanges(kind string, changes []Change) {
+	for _, change := range changes {
+		switch change.Action {
+		case Create:
+			fmt.Printf("+ %s %s\n", kind, change.Name)
+		case Update:
+			fmt.Printf("~ %s %s (%s)\n", kind, change.Name, strings.Join(change.Fields, ", "))
+		default:
+			fmt.Printf("= %s %s\n", kind, change.Name)
+		}
+	}
+}
+
+// putRole creates or replaces a role
+func putRole(client Client, name string, role interface{}) error {
+	data, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.SecurityPutRole(name, strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("put role failed: %s", res.String())
+	}
+
+	return nil
+}
+
+// putRoleMapping creates or replaces a role mapping
+func putRoleMapping(client Client, name string, mapping interface{}) error {
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.SecurityPutRoleMapping(name, strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("put role mapping failed: %s", res.String())
+	}
+
+	return nil
+}
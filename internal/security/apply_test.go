@@ -0,0 +1,90 @@
+package security
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// fakeAPI serves the roles and role mappings of a cluster and records the
+// names put
+type fakeAPI struct {
+	roles    map[string]interface{}
+	mappings map[string]interface{}
+	put      []string
+}
+
+func jsonResponse(v interface{}) *esapi.Response {
+	data, _ := json.Marshal(v)
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(string(data)))}
+}
+
+func (f *fakeAPI) SecurityGetRole(o ...func(*esapi.SecurityGetRoleRequest)) (*esapi.Response, error) {
+	return jsonResponse(f.roles), nil
+}
+
+func (f *fakeAPI) SecurityPutRole(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleRequest)) (*esapi.Response, error) {
+	f.put = append(f.put, "role "+name)
+	return jsonResponse(map[string]interface{}{"role": map[string]interface{}{"created": true}}), nil
+}
+
+func (f *fakeAPI) SecurityGetRoleMapping(o ...func(*esapi.SecurityGetRoleMappingRequest)) (*esapi.Response, error) {
+	return jsonResponse(f.mappings), nil
+}
+
+func (f *fakeAPI) SecurityPutRoleMapping(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleMappingRequest)) (*esapi.Response, error) {
+	f.put = append(f.put, "role mapping "+name)
+	return jsonResponse(map[string]interface{}{"role_mapping": map[string]interface{}{"created": true}}), nil
+}
+
+func TestApply(t *testing.T) {
+	export := &Export{
+		Roles: map[string]interface{}{
+			"reader": map[string]interface{}{"cluster": []interface{}{"monitor"}},
+			"writer": map[string]interface{}{"cluster": []interface{}{"all"}},
+			"viewer": map[string]interface{}{"cluster": []interface{}{}},
+		},
+		RoleMappings: map[string]interface{}{
+			"readers": map[string]interface{}{"roles": []interface{}{"reader"}, "enabled": true},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		opts    ApplyOptions
+		want    []string
+		wantErr bool
+	}{
+		{name: "fail", opts: ApplyOptions{PutOptions: cluster.PutOptions{Existing: "fail"}}, wantErr: true},
+		{name: "skip", opts: ApplyOptions{PutOptions: cluster.PutOptions{Existing: "skip"}}, want: []string{"role writer", "role mapping readers"}},
+		{name: "overwrite", opts: ApplyOptions{PutOptions: cluster.PutOptions{Existing: "overwrite"}}, want: []string{"role reader", "role writer", "role mapping readers"}},
+		{name: "dry run", opts: ApplyOptions{PutOptions: cluster.PutOptions{Existing: "overwrite"}, DryRun: true}},
+		{name: "invalid", opts: ApplyOptions{PutOptions: cluster.PutOptions{Existing: "merge"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// reader differs on the destination and viewer is identical
+			api := &fakeAPI{
+				roles: map[string]interface{}{
+					"reader": map[string]interface{}{"cluster": []interface{}{}},
+					"viewer": map[string]interface{}{"cluster": []interface{}{}},
+				},
+				mappings: map[string]interface{}{},
+			}
+
+			err := Apply(api, export, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(api.put, tt.want) {
+				t.Errorf("Apply() put %v, want %v", api.put, tt.want)
+			}
+		})
+	}
+}
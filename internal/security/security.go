@@ -0,0 +1,122 @@
+// Package security describes the roles and role mappings handled by the
+// security type, reads them from a cluster, and compares them with and
+// applies them to those of a destination cluster.
+package security
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Export holds roles and role mappings keyed by name, as returned by the get
+// role and get role mapping APIs without the fields rejected by the put APIs.
+// It is also the format of a security backup file.
+type Export struct {
+	Roles        map[string]interface{} `json:"roles"`
+	RoleMappings map[string]interface{} `json:"role_mappings"`
+}
+
+// Change actions
+const (
+	Create    = "create"
+	Update    = "update"
+	Unchanged = "unchanged"
+)
+
+// Change describes what applying a role or role mapping does on the
+// destination. Fields lists the top-level fields that differ for an update.
+type Change struct {
+	Name   string
+	Action string
+	Fields []string
+}
+
+// readOnlyRoleFields are returned by the get role API but not accepted by
+// the put role API
+var readOnlyRoleFields = []string{"transient_metadata"}
+
+// IsReserved reports whether a role or role mapping is reserved (built into
+// the cluster) and cannot be changed
+func IsReserved(body interface{}) bool {
+	b, _ := body.(map[string]interface{})
+	metadata, _ := b["metadata"].(map[string]interface{})
+	reserved, _ := metadata["_reserved"].(bool)
+	return reserved
+}
+
+// RoleBody returns a copy of a role as returned by the get role API without
+// the fields the put role API rejects
+func RoleBody(body interface{}) interface{} {
+	b, ok := body.(map[string]interface{})
+	if !ok {
+		return body
+	}
+
+	clean := make(map[string]interface{}, len(b))
+	for key, value := range b {
+		clean[key] = value
+	}
+	for _, field := range readOnlyRoleFields {
+		delete(clean, field)
+	}
+
+	return clean
+}
+
+// Diff compares the desired roles or role mappings with the current ones of
+// the destination and returns one change per desired entry, sorted by name
+func Diff(desired, current map[string]interface{}) []Change {
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := make([]Change, 0, len(names))
+	for _, name := range names {
+		existing, ok := current[name]
+		if !ok {
+			changes = append(changes, Change{Name: name, Action: Create})
+			continue
+		}
+
+		fields := changedFields(desired[name], existing)
+		if len(fields) == 0 {
+			changes = append(changes, Change{Name: name, Action: Unchanged})
+		} else {
+			changes = append(changes, Change{Name: name, Action: Update, Fields: fields})
+		}
+	}
+
+	return changes
+}
+
+// changedFields returns the sorted top-level fields that differ between two
+// JSON objects
+func changedFields(a, b interface{}) []string {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if !aok || !bok {
+		if reflect.DeepEqual(a, b) {
+			return nil
+		}
+		return []string{"(body)"}
+	}
+
+	seen := make(map[string]bool)
+	var fields []string
+	for _, m := range []map[string]interface{}{am, bm} {
+		for key := range m {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if !reflect.DeepEqual(am[key], bm[key]) {
+				fields = append(fields, key)
+			}
+		}
+	}
+
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,81 @@
+package security
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsReserved(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     interface{}
+		expected bool
+	}{
+		{"reserved", map[string]interface{}{"metadata": map[string]interface{}{"_reserved": true}}, true},
+		{"custom metadata", map[string]interface{}{"metadata": map[string]interface{}{"team": "search"}}, false},
+		{"no metadata", map[string]interface{}{"cluster": []interface{}{"monitor"}}, false},
+		{"not an object", "role", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsReserved(tt.body); got != tt.expected {
+				t.Errorf("IsReserved() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRoleBody(t *testing.T) {
+	role := map[string]interface{}{
+		"cluster":            []interface{}{"monitor"},
+		"transient_metadata": map[string]interface{}{"enabled": true},
+	}
+
+	body := RoleBody(role).(map[string]interface{})
+	if _, ok := body["transient_metadata"]; ok {
+		t.Error("Expected transient_metadata to be removed")
+	}
+	if _, ok := body["cluster"]; !ok {
+		t.Error("Expected cluster to be kept")
+	}
+	if _, ok := role["transient_metadata"]; !ok {
+		t.Error("Expected the original role to be left unchanged")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	desired := map[string]interface{}{
+		"reader": map[string]interface{}{
+			"cluster": []interface{}{"monitor"},
+			"indices": []interface{}{map[string]interface{}{"names": []interface{}{"logs-*"}, "privileges": []interface{}{"read"}}},
+		},
+		"writer": map[string]interface{}{
+			"cluster":  []interface{}{"monitor"},
+			"indices":  []interface{}{map[string]interface{}{"names": []interface{}{"logs-*"}, "privileges": []interface{}{"write"}}},
+			"metadata": map[string]interface{}{"version": float64(2)},
+		},
+		"admin": map[string]interface{}{"cluster": []interface{}{"all"}},
+	}
+	current := map[string]interface{}{
+		"reader": map[string]interface{}{
+			"cluster": []interface{}{"monitor"},
+			"indices": []interface{}{map[string]interface{}{"names": []interface{}{"logs-*"}, "privileges": []interface{}{"read"}}},
+		},
+		"writer": map[string]interface{}{
+			"cluster": []interface{}{"monitor"},
+			"indices": []interface{}{map[string]interface{}{"names": []interface{}{"logs-*"}, "privileges": []interface{}{"read"}}},
+		},
+		"other": map[string]interface{}{"cluster": []interface{}{"none"}},
+	}
+
+	expected := []Change{
+		{Name: "admin", Action: Create},
+		{Name: "reader", Action: Unchanged},
+		{Name: "writer", Action: Update, Fields: []string{"indices", "metadata"}},
+	}
+
+	if got := Diff(desired, current); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Diff() = %+v, want %+v", got, expected)
+	}
+}
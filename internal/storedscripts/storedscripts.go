@@ -0,0 +1,132 @@
+// Package storedscripts reads stored scripts and search templates from a
+// cluster and puts them on a destination, for the transfer and restore
+// packages.
+package storedscripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// Client is the part of the Elasticsearch API used for stored scripts
+type Client interface {
+	ClusterState(o ...func(*esapi.ClusterStateRequest)) (*esapi.Response, error)
+	PutScript(id string, body io.Reader, o ...func(*esapi.PutScriptRequest)) (*esapi.Response, error)
+}
+
+// GetScripts returns all stored scripts of the cluster keyed by id, as put
+// script request bodies: {"script": {"lang": ..., "source": ...}}. There is
+// no API listing stored scripts, so they are read from the cluster state.
+func GetScripts(client Client) (map[string]interface{}, error) {
+	res, err := client.ClusterState(func(r *esapi.ClusterStateRequest) {
+		r.Metric = []string{"metadata"}
+		r.FilterPath = []string{"metadata.stored_scripts"}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("get cluster state failed: %s", res.String())
+	}
+
+	var result struct {
+		Metadata struct {
+			StoredScripts map[string]interface{} `json:"stored_scripts"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	scripts := make(map[string]interface{})
+	for id, script := range result.Metadata.StoredScripts {
+		scripts[id] = map[string]interface{}{"script": script}
+	}
+
+	return scripts, nil
+}
+
+// PutScripts puts stored scripts on the destination. Scripts that already
+// exist are handled by opts.Existing; with the default "fail" policy nothing
+// is put if any of them exists.
+func PutScripts(client Client, scripts map[string]interface{}, opts cluster.PutOptions) error {
+	if err := cluster.CheckExisting(opts.Existing, "script"); err != nil {
+		return err
+	}
+
+	current, err := GetScripts(client)
+	if err != nil {
+		return fmt.Errorf("failed to get destination stored scripts: %w", err)
+	}
+
+	ids := make([]string, 0, len(scripts))
+	for id := range scripts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Decide what to do with every script before changing anything
+	var selected []string
+	var conflicts []string
+	for _, id := range ids {
+		if _, exists := current[id]; exists {
+			switch opts.Existing {
+			case "skip":
+				fmt.Printf("Skipping existing stored script %s\n", id)
+				continue
+			case "overwrite":
+			default:
+				conflicts = append(conflicts, id)
+			}
+		}
+
+		selected = append(selected, id)
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("stored scripts already exist: %s (use --existing=skip or --existing=overwrite)",
+			strings.Join(conflicts, ", "))
+	}
+
+	for _, id := range selected {
+		if err := putScript(client, id, scripts[id]); err != nil {
+			return fmt.Errorf("failed to put stored script %s: %w", id, err)
+		}
+
+		if opts.Verbose {
+			fmt.Printf("Put stored script %s\n", id)
+		}
+	}
+
+	fmt.Printf("Put %d stored scripts to %s\n", len(selected), opts.Destination)
+
+	return nil
+}
+
+// putScript creates or replaces a stored script
+func putScript(client Client, id string, script interface{}) error {
+	data, err := json.Marshal(script)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.PutScript(id, strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("put script failed: %s", res.String())
+	}
+
+	return nil
+}
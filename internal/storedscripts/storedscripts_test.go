@@ -0,0 +1,75 @@
+package storedscripts
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// fakeAPI serves the stored scripts of existing in the cluster state and
+// records the ids put
+type fakeAPI struct {
+	existing map[string]interface{}
+	put      []string
+}
+
+func (f *fakeAPI) ClusterState(o ...func(*esapi.ClusterStateRequest)) (*esapi.Response, error) {
+	state := map[string]interface{}{"metadata": map[string]interface{}{"stored_scripts": f.existing}}
+	data, _ := json.Marshal(state)
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(string(data)))}, nil
+}
+
+func (f *fakeAPI) PutScript(id string, body io.Reader, o ...func(*esapi.PutScriptRequest)) (*esapi.Response, error) {
+	f.put = append(f.put, id)
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"acknowledged":true}`))}, nil
+}
+
+func TestGetScripts(t *testing.T) {
+	script := map[string]interface{}{"lang": "painless", "source": "ctx._source.n++"}
+	api := &fakeAPI{existing: map[string]interface{}{"increment": script}}
+
+	scripts, err := GetScripts(api)
+	if err != nil {
+		t.Fatalf("GetScripts failed: %v", err)
+	}
+
+	want := map[string]interface{}{"increment": map[string]interface{}{"script": script}}
+	if !reflect.DeepEqual(scripts, want) {
+		t.Errorf("GetScripts() = %v, want %v", scripts, want)
+	}
+}
+
+func TestPutScripts(t *testing.T) {
+	scripts := map[string]interface{}{
+		"increment": map[string]interface{}{"script": map[string]interface{}{"lang": "painless", "source": "1"}},
+		"search":    map[string]interface{}{"script": map[string]interface{}{"lang": "mustache", "source": "{}"}},
+	}
+
+	tests := []struct {
+		existing string
+		want     []string
+		wantErr  bool
+	}{
+		{existing: "fail", wantErr: true},
+		{existing: "skip", want: []string{"search"}},
+		{existing: "overwrite", want: []string{"increment", "search"}},
+		{existing: "merge", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		api := &fakeAPI{existing: map[string]interface{}{"increment": map[string]interface{}{}}}
+
+		err := PutScripts(api, scripts, cluster.PutOptions{Existing: tt.existing})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("PutScripts(%s) error = %v, wantErr %v", tt.existing, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(api.put, tt.want) {
+			t.Errorf("PutScripts(%s) put %v, want %v", tt.existing, api.put, tt.want)
+		}
+	}
+}
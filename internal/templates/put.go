@@ -0,0 +1,146 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// Client is the part of the Elasticsearch API used to put templates
+type Client interface {
+	ClusterExistsComponentTemplate(name string, o ...func(*esapi.ClusterExistsComponentTemplateRequest)) (*esapi.Response, error)
+	ClusterPutComponentTemplate(name string, body io.Reader, o ...func(*esapi.ClusterPutComponentTemplateRequest)) (*esapi.Response, error)
+	IndicesExistsIndexTemplate(name string, o ...func(*esapi.IndicesExistsIndexTemplateRequest)) (*esapi.Response, error)
+	IndicesPutIndexTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutIndexTemplateRequest)) (*esapi.Response, error)
+	IndicesExistsTemplate(name []string, o ...func(*esapi.IndicesExistsTemplateRequest)) (*esapi.Response, error)
+	IndicesPutTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutTemplateRequest)) (*esapi.Response, error)
+}
+
+// Select returns the templates of a set to put on the destination, in
+// dependency order. Existing templates are handled by opts.Existing; with the
+// default "fail" policy they are reported in an error.
+func Select(client Client, set *Set, opts cluster.PutOptions) ([]Template, error) {
+	if err := cluster.CheckExisting(opts.Existing, "template"); err != nil {
+		return nil, err
+	}
+
+	var selected []Template
+	var conflicts []string
+	for _, t := range set.Ordered() {
+		exists, err := Exists(client, t.Kind, t.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			switch opts.Existing {
+			case "skip":
+				if opts.Verbose {
+					fmt.Printf("Skipping existing %s template %s\n", t.Kind, t.Name)
+				}
+				continue
+			case "overwrite":
+			default:
+				conflicts = append(conflicts, t.Kind+" template "+t.Name)
+			}
+		}
+
+		selected = append(selected, t)
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("templates already exist: %s (use --existing=skip or --existing=overwrite)",
+			strings.Join(conflicts, ", "))
+	}
+
+	return selected, nil
+}
+
+// Put puts the templates of a set on the destination in dependency order.
+// Nothing is put if Select fails for any of them.
+func Put(client Client, set *Set, opts cluster.PutOptions) error {
+	// Decide what to do with every template before changing anything
+	selected, err := Select(client, set, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range selected {
+		if err := put(client, t); err != nil {
+			return fmt.Errorf("failed to put %s template %s: %w", t.Kind, t.Name, err)
+		}
+
+		if opts.Verbose {
+			fmt.Printf("Put %s template %s\n", t.Kind, t.Name)
+		}
+	}
+
+	fmt.Printf("Put %d templates to %s\n", len(selected), opts.Destination)
+
+	return nil
+}
+
+// Exists checks whether a template of the given kind exists
+func Exists(client Client, kind, name string) (bool, error) {
+	var res *esapi.Response
+	var err error
+
+	switch kind {
+	case Component:
+		res, err = client.ClusterExistsComponentTemplate(name)
+	case Index:
+		res, err = client.IndicesExistsIndexTemplate(name)
+	case Legacy:
+		res, err = client.IndicesExistsTemplate([]string{name})
+	default:
+		return false, fmt.Errorf("unknown template kind: %s", kind)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s template exists check for %s failed: %s", kind, name, res.String())
+	}
+}
+
+// put creates or replaces a template
+func put(client Client, t Template) error {
+	data, err := json.Marshal(t.Body)
+	if err != nil {
+		return err
+	}
+	body := strings.NewReader(string(data))
+
+	var res *esapi.Response
+	switch t.Kind {
+	case Component:
+		res, err = client.ClusterPutComponentTemplate(t.Name, body)
+	case Index:
+		res, err = client.IndicesPutIndexTemplate(t.Name, body)
+	case Legacy:
+		res, err = client.IndicesPutTemplate(t.Name, body)
+	default:
+		return fmt.Errorf("unknown template kind: %s", t.Kind)
+	}
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("put template failed: %s", res.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package templates
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// fakeAPI records the templates put, keyed by kind and name
+type fakeAPI struct {
+	existing map[string]bool
+	put      []string
+}
+
+func (f *fakeAPI) exists(key string) (*esapi.Response, error) {
+	status := 404
+	if f.existing[key] {
+		status = 200
+	}
+	return &esapi.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func (f *fakeAPI) record(key string) (*esapi.Response, error) {
+	f.put = append(f.put, key)
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"acknowledged":true}`))}, nil
+}
+
+func (f *fakeAPI) ClusterExistsComponentTemplate(name string, o ...func(*esapi.ClusterExistsComponentTemplateRequest)) (*esapi.Response, error) {
+	return f.exists("component/" + name)
+}
+
+func (f *fakeAPI) ClusterPutComponentTemplate(name string, body io.Reader, o ...func(*esapi.ClusterPutComponentTemplateRequest)) (*esapi.Response, error) {
+	return f.record("component/" + name)
+}
+
+func (f *fakeAPI) IndicesExistsIndexTemplate(name string, o ...func(*esapi.IndicesExistsIndexTemplateRequest)) (*esapi.Response, error) {
+	return f.exists("index/" + name)
+}
+
+func (f *fakeAPI) IndicesPutIndexTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutIndexTemplateRequest)) (*esapi.Response, error) {
+	return f.record("index/" + name)
+}
+
+func (f *fakeAPI) IndicesExistsTemplate(name []string, o ...func(*esapi.IndicesExistsTemplateRequest)) (*esapi.Response, error) {
+	return f.exists("legacy/" + name[0])
+}
+
+func (f *fakeAPI) IndicesPutTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutTemplateRequest)) (*esapi.Response, error) {
+	return f.record("legacy/" + name)
+}
+
+func TestPut(t *testing.T) {
+	set := &Set{
+		ComponentTemplates: []ComponentTemplate{{Name: "base", ComponentTemplate: map[string]interface{}{}}},
+		IndexTemplates: []IndexTemplate{{Name: "logs", IndexTemplate: map[string]interface{}{
+			"index_patterns": []interface{}{"logs-*"},
+			"composed_of":    []interface{}{"base"},
+		}}},
+		LegacyTemplates: map[string]map[string]interface{}{"old": {}},
+	}
+
+	tests := []struct {
+		existing string
+		want     []string
+		wantErr  bool
+	}{
+		{existing: "fail", wantErr: true},
+		{existing: "skip", want: []string{"component/base", "legacy/old"}},
+		{existing: "overwrite", want: []string{"component/base", "index/logs", "legacy/old"}},
+		{existing: "merge", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		api := &fakeAPI{existing: map[string]bool{"index/logs": true}}
+
+		err := Put(api, set, cluster.PutOptions{Existing: tt.existing, Destination: "http://dest:9200"})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Put(%s) error = %v, wantErr %v", tt.existing, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(api.put, tt.want) {
+			t.Errorf("Put(%s) put %v, want %v", tt.existing, api.put, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package templates describes the index, component and legacy templates
+// handled by the templates type, and the order in which they are applied.
+package templates
+
+import (
+	"sort"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+// Template kinds
+const (
+	Component = "component"
+	Index     = "index"
+	Legacy    = "legacy"
+)
+
+// readOnlyFields are returned by the get template APIs of recent clusters but
+// rejected by the put template APIs
+var readOnlyFields = []string{
+	"created_date",
+	"created_date_millis",
+	"modified_date",
+	"modified_date_millis",
+}
+
+// ComponentTemplate is an entry of the get component template API response
+type ComponentTemplate struct {
+	Name              string                 `json:"name"`
+	ComponentTemplate map[string]interface{} `json:"component_template"`
+}
+
+// IndexTemplate is an entry of the get index template API response
+type IndexTemplate struct {
+	Name          string                 `json:"name"`
+	IndexTemplate map[string]interface{} `json:"index_template"`
+}
+
+// Set holds templates of all kinds. It is also the format of a templates
+// backup file.
+type Set struct {
+	ComponentTemplates []ComponentTemplate               `json:"component_templates"`
+	IndexTemplates     []IndexTemplate                   `json:"index_templates"`
+	LegacyTemplates    map[string]map[string]interface{} `json:"legacy_templates"`
+}
+
+// Template is a single template of any kind with the body of its put request
+type Template struct {
+	Kind string
+	Name string
+	Body map[string]interface{}
+}
+
+// Ordered returns the templates of the set in the order they must be put:
+// component templates first, so that they exist before the index templates
+// composed of them, then index templates and legacy templates, each sorted
+// by name
+func (s *Set) Ordered() []Template {
+	var ordered []Template
+
+	components := make([]Template, 0, len(s.ComponentTemplates))
+	for _, t := range s.ComponentTemplates {
+		components = append(components, Template{Kind: Component, Name: t.Name, Body: putBody(t.ComponentTemplate)})
+	}
+	ordered = append(ordered, sortByName(components)...)
+
+	indexTemplates := make([]Template, 0, len(s.IndexTemplates))
+	for _, t := range s.IndexTemplates {
+		indexTemplates = append(indexTemplates, Template{Kind: Index, Name: t.Name, Body: putBody(t.IndexTemplate)})
+	}
+	ordered = append(ordered, sortByName(indexTemplates)...)
+
+	legacy := make([]Template, 0, len(s.LegacyTemplates))
+	for name, body := range s.LegacyTemplates {
+		legacy = append(legacy, Template{Kind: Legacy, Name: name, Body: putBody(body)})
+	}
+	ordered = append(ordered, sortByName(legacy)...)
+
+	return ordered
+}
+
+// Len returns the number of templates in the set
+func (s *Set) Len() int {
+	return len(s.ComponentTemplates) + len(s.IndexTemplates) + len(s.LegacyTemplates)
+}
+
+// Add adds the templates of other that are not in the set yet
+func (s *Set) Add(other *Set) {
+	components := make(map[string]bool)
+	for _, t := range s.ComponentTemplates {
+		components[t.Name] = true
+	}
+	for _, t := range other.ComponentTemplates {
+		if !components[t.Name] {
+			components[t.Name] = true
+			s.ComponentTemplates = append(s.ComponentTemplates, t)
+		}
+	}
+
+	indexTemplates := make(map[string]bool)
+	for _, t := range s.IndexTemplates {
+		indexTemplates[t.Name] = true
+	}
+	for _, t := range other.IndexTemplates {
+		if !indexTemplates[t.Name] {
+			indexTemplates[t.Name] = true
+			s.IndexTemplates = append(s.IndexTemplates, t)
+		}
+	}
+
+	for name, body := range other.LegacyTemplates {
+		if s.LegacyTemplates == nil {
+			s.LegacyTemplates = make(map[string]map[string]interface{})
+		}
+		if _, ok := s.LegacyTemplates[name]; !ok {
+			s.LegacyTemplates[name] = body
+		}
+	}
+}
+
+// RemoveSystem removes the templates managed by the cluster itself: those
+// with a "."-prefixed name and those marked as managed in their _meta
+func (s *Set) RemoveSystem() {
+	var components []ComponentTemplate
+	for _, t := range s.ComponentTemplates {
+		if !cluster.IsSystem(t.Name, t.ComponentTemplate) {
+			components = append(components, t)
+		}
+	}
+	s.ComponentTemplates = components
+
+	var indexTemplates []IndexTemplate
+	for _, t := range s.IndexTemplates {
+		if !cluster.IsSystem(t.Name, t.IndexTemplate) {
+			indexTemplates = append(indexTemplates, t)
+		}
+	}
+	s.IndexTemplates = indexTemplates
+
+	for name, body := range s.LegacyTemplates {
+		if cluster.IsSystem(name, body) {
+			delete(s.LegacyTemplates, name)
+		}
+	}
+}
+
+// MissingComponents returns the sorted names of the component templates that
+// index templates of the set are composed of but that are not in the set
+func (s *Set) MissingComponents() []string {
+	present := make(map[string]bool)
+	for _, t := range s.ComponentTemplates {
+		present[t.Name] = true
+	}
+
+	missing := make(map[string]bool)
+	for _, t := range s.IndexTemplates {
+		for _, name := range ComposedOf(t.IndexTemplate) {
+			if !present[name] {
+				missing[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ComposedOf returns the component templates an index template is composed of
+func ComposedOf(indexTemplate map[string]interface{}) []string {
+	list, _ := indexTemplate["composed_of"].([]interface{})
+
+	var names []string
+	for _, item := range list {
+		if name, ok := item.(string); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// putBody returns a copy of a template body without read-only fields
+func putBody(body map[string]interface{}) map[string]interface{} {
+	clean := make(map[string]interface{}, len(body))
+	for key, value := range body {
+		clean[key] = value
+	}
+	for _, field := range readOnlyFields {
+		delete(clean, field)
+	}
+
+	return clean
+}
+
+// sortByName sorts templates of one kind by name
+func sortByName(templates []Template) []Template {
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates
+}
@@ -0,0 +1,93 @@
+package templates
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func sampleSet(t *testing.T) *Set {
+	t.Helper()
+
+	body := `{
+		"component_templates": [
+			{"name": "logs-settings", "component_template": {"template": {"settings": {"number_of_shards": 1}}}},
+			{"name": "ecs-mappings", "component_template": {"template": {"mappings": {}}, "_meta": {"managed": true}}}
+		],
+		"index_templates": [
+			{"name": "logs", "index_template": {"index_patterns": ["logs-*"], "composed_of": ["logs-settings", "logs-mappings"], "created_date_millis": 1700000000000}},
+			{"name": ".kibana", "index_template": {"index_patterns": [".kibana*"]}}
+		],
+		"legacy_templates": {
+			"old": {"index_patterns": ["old-*"], "order": 0}
+		}
+	}`
+
+	var set Set
+	if err := json.Unmarshal([]byte(body), &set); err != nil {
+		t.Fatalf("Failed to parse set: %v", err)
+	}
+	return &set
+}
+
+func TestOrdered(t *testing.T) {
+	set := sampleSet(t)
+
+	var order []string
+	for _, template := range set.Ordered() {
+		order = append(order, template.Kind+"/"+template.Name)
+	}
+
+	expected := []string{
+		"component/ecs-mappings", "component/logs-settings",
+		"index/.kibana", "index/logs",
+		"legacy/old",
+	}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Ordered() = %v, want %v", order, expected)
+	}
+
+	for _, template := range set.Ordered() {
+		if _, ok := template.Body["created_date_millis"]; ok {
+			t.Errorf("Expected read-only fields to be removed from %s", template.Name)
+		}
+	}
+	if _, ok := set.IndexTemplates[0].IndexTemplate["created_date_millis"]; !ok {
+		t.Error("Expected Ordered() not to modify the set")
+	}
+}
+
+func TestRemoveSystem(t *testing.T) {
+	set := sampleSet(t)
+	set.RemoveSystem()
+
+	if len(set.ComponentTemplates) != 1 || set.ComponentTemplates[0].Name != "logs-settings" {
+		t.Errorf("Expected managed component template to be removed, got %+v", set.ComponentTemplates)
+	}
+	if len(set.IndexTemplates) != 1 || set.IndexTemplates[0].Name != "logs" {
+		t.Errorf("Expected dot-prefixed index template to be removed, got %+v", set.IndexTemplates)
+	}
+	if set.Len() != 3 {
+		t.Errorf("Expected 3 templates left, got %d", set.Len())
+	}
+}
+
+func TestMissingComponentsAndAdd(t *testing.T) {
+	set := sampleSet(t)
+
+	if missing := set.MissingComponents(); !reflect.DeepEqual(missing, []string{"logs-mappings"}) {
+		t.Errorf("MissingComponents() = %v, want [logs-mappings]", missing)
+	}
+
+	set.Add(&Set{ComponentTemplates: []ComponentTemplate{
+		{Name: "logs-mappings", ComponentTemplate: map[string]interface{}{}},
+		{Name: "logs-settings", ComponentTemplate: map[string]interface{}{"replaced": true}},
+	}})
+
+	if missing := set.MissingComponents(); len(missing) != 0 {
+		t.Errorf("Expected no missing components after Add, got %v", missing)
+	}
+	if len(set.ComponentTemplates) != 3 {
+		t.Errorf("Expected Add to skip templates already in the set, got %d", len(set.ComponentTemplates))
+	}
+}
@@ -0,0 +1,100 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+// getAliases returns the aliases of an index in the format of the get alias
+// API: {"index": {"aliases": {...}}}
+func getAliases(client *Client, index string) (map[string]interface{}, error) {
+	res, err := client.API.IndicesGetAlias(
+		func(r *esapi.IndicesGetAliasRequest) {
+			r.Index = []string{index}
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// An index without aliases may be reported as a 404 by older clusters
+	if res.StatusCode == 404 {
+		return map[string]interface{}{
+			index: map[string]interface{}{"aliases": map[string]interface{}{}},
+		}, nil
+	}
+
+	if res.IsError() {
+		return nil, fmt.Errorf("get aliases failed: %s", res.String())
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// transferAliases copies the aliases of the source indices, including their
+// filter, routing and is_write_index properties. On a cluster they are added
+// to the destination indices in a single atomic _aliases request; a file
+// receives them in the format of the get alias API. The output URL may name
+// the index only when the source is a single index.
+func transferAliases(sourceClient *Client, renamer *rename.Renamer, config Config) error {
+	expression := extractIndex(config.Input)
+	if expression == "" {
+		return fmt.Errorf("could not extract index from input URL")
+	}
+
+	indices, err := resolveIndices(sourceClient, expression, config)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source indices: %w", err)
+	}
+
+	if !isFile(config.Output) && extractIndex(config.Output) != "" && len(indices) > 1 {
+		return fmt.Errorf("%s matches %d indices, output URL must not name an index", expression, len(indices))
+	}
+
+	aliases, err := getAliases(sourceClient, strings.Join(indices, ","))
+	if err != nil {
+		return fmt.Errorf("failed to get aliases: %w", err)
+	}
+
+	if isFile(config.Output) {
+		return writeFileBackup(sourceClient, aliases, nil, config)
+	}
+
+	destURL := getBaseURL(config.Output)
+	destClient, err := createClient(destURL, config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	var actions []map[string]interface{}
+	for _, index := range indices {
+		destIndex := destinationIndex(config, renamer, index)
+
+		actions = append(actions, indexdef.AliasActions(indexdef.Entry(aliases, index, "aliases"), destIndex)...)
+	}
+
+	if len(actions) == 0 {
+		fmt.Printf("No aliases found on %s\n", expression)
+		return nil
+	}
+
+	if err := cluster.UpdateAliases(destClient.API, actions); err != nil {
+		return fmt.Errorf("failed to apply aliases: %w", err)
+	}
+
+	fmt.Printf("Applied %d aliases to %s\n", len(actions), config.Output)
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package transfer
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+func TestGetAliasesNotFound(t *testing.T) {
+	client := &Client{
+		API: &MockElasticsearchAPI{
+			AliasResponse: &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(`{}`))},
+		},
+		URL: "http://mock:9200",
+	}
+
+	aliases, err := getAliases(client, "test-index")
+	if err != nil {
+		t.Fatalf("getAliases failed: %v", err)
+	}
+
+	entry, ok := aliases["test-index"].(map[string]interface{})
+	if !ok || len(entry["aliases"].(map[string]interface{})) != 0 {
+		t.Errorf("Expected empty aliases for test-index, got %v", aliases)
+	}
+}
+
+func TestTransferAliasesToFile(t *testing.T) {
+	output := t.TempDir() + "/aliases.json"
+	config := Config{
+		Input:  "http://localhost:9200/test-index",
+		Output: output,
+		Type:   "aliases",
+	}
+
+	if err := transferAliases(createMockClient(), nil, config); err != nil {
+		t.Fatalf("transferAliases failed: %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var aliases map[string]map[string]map[string]interface{}
+	if err := json.Unmarshal(content, &aliases); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if _, ok := aliases["test-index"]["aliases"]["test-alias"]; !ok {
+		t.Errorf("Expected test-alias for test-index in output, got %v", aliases)
+	}
+}
+
+func TestTransferAliasesRejectsSingleDestination(t *testing.T) {
+	client := &Client{
+		API: &MockElasticsearchAPI{ResolveResponse: &esapi.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"indices": [{"name": "logs-a"}, {"name": "logs-b"}]}`)),
+		}},
+		URL: "http://mock:9200",
+	}
+	config := Config{
+		Input:  "http://localhost:9200/logs-*",
+		Output: "http://localhost:9200/logs",
+		Type:   "aliases",
+	}
+
+	err := transferAliases(client, nil, config)
+	if err == nil || !strings.Contains(err.Error(), "must not name an index") {
+		t.Errorf("Expected several source indices into one destination index to be rejected, got %v", err)
+	}
+}
+
+func TestUpdateAliases(t *testing.T) {
+	api := &MockElasticsearchAPI{}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	actions := []map[string]interface{}{
+		{"add": map[string]interface{}{"index": "logs-v2", "alias": "logs", "is_write_index": true}},
+	}
+	if err := cluster.UpdateAliases(client.API, actions); err != nil {
+		t.Fatalf("updateAliases failed: %v", err)
+	}
+
+	if len(api.AliasRequests) != 1 {
+		t.Fatalf("Expected one alias request, got %d", len(api.AliasRequests))
+	}
+
+	var body struct {
+		Actions []map[string]map[string]interface{} `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(api.AliasRequests[0]), &body); err != nil {
+		t.Fatalf("Alias request is not valid JSON: %v", err)
+	}
+	if len(body.Actions) != 1 || body.Actions[0]["add"]["is_write_index"] != true {
+		t.Errorf("Expected add action with is_write_index, got %s", api.AliasRequests[0])
+	}
+}
@@ -0,0 +1,304 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/rename"
+	"github.com/schollz/progressbar/v3"
+)
+
+// indexSnapshot is everything read from the source about one index for the
+// "all" type
+type indexSnapshot struct {
+	name      string
+	create    map[string]interface{}
+	aliases   map[string]interface{}
+	documents int
+}
+
+// transferAll migrates complete indices in one run: every destination index
+// is created from the source settings and mappings, the data is copied, and
+// the aliases are applied last. When the output is a file, all of it is
+// bundled into a single NDJSON file instead.
+func transferAll(sourceClient *Client, renamer *rename.Renamer, config Config) error {
+	expression := extractIndex(config.Input)
+	if expression == "" {
+		return fmt.Errorf("could not extract index from input URL")
+	}
+
+	sources, err := resolveSources(sourceClient, expression)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source indices: %w", err)
+	}
+
+	// Recreating backing indices literally would not give a data stream
+	var indices []string
+	for _, source := range sources {
+		if source.DataStream {
+			return fmt.Errorf("%s is a data stream, use --type=data to copy it through its index template", source.Name)
+		}
+		indices = append(indices, source.Name)
+	}
+
+	snapshots, err := getSnapshots(sourceClient, indices, config)
+	if err != nil {
+		return err
+	}
+
+	if isFile(config.Output) {
+		return writeBundle(sourceClient, snapshots, config)
+	}
+
+	destURL := getBaseURL(config.Output)
+	destClient, err := createClient(destURL, config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return copyIndices(sourceClient, destClient, snapshots, renamer, config)
+}
+
+// getSnapshots reads the create index body, aliases and document count of
+// every index. Document counts are capped so that their sum honours the limit.
+func getSnapshots(client *Client, indices []string, config Config) ([]indexSnapshot, error) {
+	bodies, err := getCreateBodies(client, indices, config)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := getAliases(client, strings.Join(indices, ","))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aliases: %w", err)
+	}
+
+	snapshots := make([]indexSnapshot, 0, len(indices))
+	planned := 0
+	for _, index := range indices {
+		count, err := getDocumentCount(client, index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document count of %s: %w", index, err)
+		}
+
+		if limit, _ := remainingLimit(config.Limit, planned); config.Limit > 0 && count > limit {
+			count = limit
+		}
+		planned += count
+
+		snapshots = append(snapshots, indexSnapshot{
+			name:      index,
+			create:    bodies[index],
+			aliases:   indexdef.Entry(aliases, index, "aliases"),
+			documents: count,
+		})
+	}
+
+	return snapshots, nil
+}
+
+// copyIndices creates, fills and aliases the destination indices with a single
+// progress bar over all documents
+func copyIndices(sourceClient, destClient *Client, snapshots []indexSnapshot, renamer *rename.Renamer, config Config) error {
+	// Check every destination before creating anything
+	destIndices := make([]string, len(snapshots))
+	sources := make(map[string]string)
+	for i, snapshot := range snapshots {
+		destIndex := destinationIndex(config, renamer, snapshot.name)
+
+		if other, ok := sources[destIndex]; ok {
+			return fmt.Errorf("indices %s and %s would both be transferred into %s", other, snapshot.name, destIndex)
+		}
+		sources[destIndex] = snapshot.name
+
+		exists, err := cluster.IndexExists(destClient.API, destIndex)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("index %s already exists on the destination", destIndex)
+		}
+
+		destIndices[i] = destIndex
+	}
+
+	if err := copyIndexDependencies(sourceClient, destClient, snapshotSettings(snapshots), config); err != nil {
+		return err
+	}
+
+	for i, snapshot := range snapshots {
+		if err := cluster.CreateIndex(destClient.API, destIndices[i], snapshot.create); err != nil {
+			return err
+		}
+	}
+
+	bar := newTotalBar(snapshots, "Transferring documents", config)
+
+	copied := make([]int, len(snapshots))
+	for i, snapshot := range snapshots {
+		indexConfig := config
+		indexConfig.Limit = snapshot.documents
+		if snapshot.documents == 0 {
+			continue
+		}
+
+		count, err := copyDocuments(sourceClient, destClient, snapshot.name, destIndices[i], bar, indexConfig)
+		if err != nil {
+			return fmt.Errorf("failed to transfer index %s: %w", snapshot.name, err)
+		}
+		copied[i] = count
+	}
+
+	if bar != nil {
+		bar.Finish()
+	}
+
+	var actions []map[string]interface{}
+	for i, snapshot := range snapshots {
+		actions = append(actions, indexdef.AliasActions(snapshot.aliases, destIndices[i])...)
+	}
+	if len(actions) > 0 {
+		if err := cluster.UpdateAliases(destClient.API, actions); err != nil {
+			return fmt.Errorf("failed to apply aliases: %w", err)
+		}
+	}
+
+	total := 0
+	for i, snapshot := range snapshots {
+		fmt.Printf("  %s -> %s: %d of %d documents, %d aliases\n",
+			snapshot.name, destIndices[i], copied[i], snapshot.documents, len(snapshot.aliases))
+		total += copied[i]
+	}
+	fmt.Printf("Transferred %d indices, %d documents and %d aliases to %s\n",
+		len(snapshots), total, len(actions), config.Output)
+
+	return nil
+}
+
+// snapshotSettings returns the index settings of every snapshot
+func snapshotSettings(snapshots []indexSnapshot) []map[string]interface{} {
+	var indexSettings []map[string]interface{}
+	for _, snapshot := range snapshots {
+		settings, _ := snapshot.create["settings"].(map[string]interface{})
+		indexSettings = append(indexSettings, settings)
+	}
+	return indexSettings
+}
+
+// writeBundle writes the indices to a single bundle file (see backup.BundleLine),
+// with the referenced ingest pipelines next to it
+func writeBundle(client *Client, snapshots []indexSnapshot, config Config) error {
+	file, err := createFile(config.Output, config)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	header := &backup.BundleHeader{
+		LayoutVersion: backup.LayoutVersion,
+		CreatedAt:     time.Now().UTC(),
+		Source:        redactURL(getBaseURL(config.Input)),
+	}
+	for _, snapshot := range snapshots {
+		header.Indices = append(header.Indices, snapshot.name)
+	}
+	if err := writeBundleLine(file, backup.BundleLine{Header: header}); err != nil {
+		return err
+	}
+
+	bar := newTotalBar(snapshots, "Exporting documents", config)
+
+	total := 0
+	for _, snapshot := range snapshots {
+		definition := &backup.IndexDefinition{
+			Index:     snapshot.name,
+			Create:    snapshot.create,
+			Aliases:   snapshot.aliases,
+			Documents: snapshot.documents,
+		}
+		if err := writeBundleLine(file, backup.BundleLine{Definition: definition}); err != nil {
+			return err
+		}
+
+		if snapshot.documents == 0 {
+			continue
+		}
+
+		count, err := scrollDocuments(client, snapshot.name, snapshot.documents, config.ScrollSize, func(doc Document) error {
+			if err := writeDocument(file, doc, "ndjson"); err != nil {
+				return fmt.Errorf("failed to write document: %w", err)
+			}
+
+			if bar != nil {
+				bar.Add(1)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export index %s: %w", snapshot.name, err)
+		}
+		total += count
+	}
+
+	if bar != nil {
+		bar.Finish()
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	if config.Output != backup.Stdio {
+		sidecars, err := writePipelinesSidecar(client, snapshotSettings(snapshots), config)
+		if err != nil {
+			return err
+		}
+
+		manifest, err := newFileManifest(client, config)
+		if err != nil {
+			return err
+		}
+		manifest.Indices = header.Indices
+		manifest.Query = matchAllQuery
+		manifest.Documents = total
+		manifest.Format = "ndjson"
+		if err := writeFileManifest(manifest, append([]backup.File{file.Sum()}, sidecars...), config); err != nil {
+			return err
+		}
+	}
+
+	logf(config, "Exported %d indices (%d documents) to %s\n", len(snapshots), total, config.Output)
+
+	return nil
+}
+
+// writeBundleLine writes a non-document line of a bundle
+func writeBundleLine(writer io.Writer, line backup.BundleLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(writer, "%s\n", data)
+	return err
+}
+
+// newTotalBar returns a progress bar over the documents of all snapshots, or
+// nil in verbose mode
+func newTotalBar(snapshots []indexSnapshot, description string, config Config) *progressbar.ProgressBar {
+	if config.Verbose {
+		return nil
+	}
+
+	total := 0
+	for _, snapshot := range snapshots {
+		total += snapshot.documents
+	}
+
+	return progressbar.DefaultBytes(int64(total), description)
+}
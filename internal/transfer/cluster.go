@@ -0,0 +1,324 @@
+package transfer
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+)
+
+// backupCluster backs up every selected index and data stream of the source
+// cluster into the output directory: one subdirectory per index holding its
+// mapping, settings, aliases and data, one per data stream holding its data,
+// the templates the data streams are recreated from, the ingest pipelines
+// the indices and data streams refer to when IncludePipelines is set, and a
+// manifest describing the whole backup
+func backupCluster(client *Client, config Config) error {
+	if !isFile(config.Output) || config.Output == backup.Stdio {
+		return fmt.Errorf("--all requires a directory as output")
+	}
+
+	sources, err := selectSources(client, config)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(config.Output, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	kind, err := compression(backup.DataName(config.Format), config)
+	if err != nil {
+		return err
+	}
+
+	name, version := provenance(client, config)
+	manifest := &backup.Manifest{
+		LayoutVersion:  backup.LayoutVersion,
+		ToolVersion:    config.Version,
+		CreatedAt:      time.Now().UTC(),
+		Source:         redactURL(getBaseURL(config.Input)),
+		ClusterName:    name,
+		ClusterVersion: version,
+		Format:         config.Format,
+		Compression:    kind,
+	}
+
+	var indices []string
+	var streams []*backup.DataStream
+	total := 0
+	for _, source := range sources {
+		if source.DataStream {
+			entry, stream, files, err := backupDataStream(client, source.Name, config)
+			if err != nil {
+				return fmt.Errorf("failed to back up data stream %s: %w", source.Name, err)
+			}
+
+			manifest.DataStreams = append(manifest.DataStreams, *entry)
+			manifest.Files = append(manifest.Files, files...)
+			streams = append(streams, stream)
+			total += entry.Documents
+
+			fmt.Printf("Backed up %d documents from data stream %s\n", entry.Documents, source.Name)
+			continue
+		}
+
+		entry, files, err := backupIndex(client, source.Name, config)
+		if err != nil {
+			return fmt.Errorf("failed to back up index %s: %w", source.Name, err)
+		}
+
+		manifest.Indices = append(manifest.Indices, *entry)
+		manifest.Files = append(manifest.Files, files...)
+		indices = append(indices, source.Name)
+		total += entry.Documents
+
+		fmt.Printf("Backed up %d documents from %s\n", entry.Documents, source.Name)
+	}
+
+	files, err := backupPipelines(client, indices, streams, manifest, config)
+	if err != nil {
+		return err
+	}
+	manifest.Files = append(manifest.Files, files...)
+
+	if len(streams) > 0 {
+		metadataConfig := config
+		metadataConfig.Compress = "none"
+		file, err := writeToFile(filepath.Join(config.Output, backup.DataStreamsName), streams, metadataConfig)
+		if err != nil {
+			return fmt.Errorf("failed to write data streams file: %w", err)
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	if err := backup.WriteManifest(config.Output, manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	fmt.Printf("Backed up %d indices and %d data streams (%d documents) to %s\n",
+		len(indices), len(streams), total, config.Output)
+
+	return nil
+}
+
+// selectSources lists the indices and data streams matched by the input
+// index expression (all of them for a bare cluster URL) and filters them by
+// name with the include, exclude and system index options. Data streams are
+// kept whole rather than expanded into their backing indices, and every
+// system data stream left out is reported, since it is easily missed.
+func selectSources(client *Client, config Config) ([]dataSource, error) {
+	expression := extractIndex(config.Input)
+	if expression == "" {
+		expression = "*"
+	}
+
+	var opts []func(*esapi.IndicesResolveIndexRequest)
+	if config.IncludeSystem {
+		opts = append(opts, func(r *esapi.IndicesResolveIndexRequest) {
+			r.ExpandWildcards = "open,hidden"
+		})
+	}
+
+	sources, err := resolveSources(client, expression, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indices: %w", err)
+	}
+
+	var selected []dataSource
+	for _, source := range sources {
+		if !config.IncludeSystem && strings.HasPrefix(source.Name, ".") {
+			if source.DataStream {
+				fmt.Printf("Skipping system data stream %s (use --includeSystem to back it up)\n", source.Name)
+			}
+			continue
+		}
+		if len(config.Include) > 0 && !cluster.MatchesAny(source.Name, config.Include) {
+			continue
+		}
+		if cluster.MatchesAny(source.Name, config.Exclude) {
+			continue
+		}
+		selected = append(selected, source)
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no indices selected for backup")
+	}
+
+	return selected, nil
+}
+
+// backupIndex writes the mapping, settings, aliases and data of an index to
+// its subdirectory of the backup directory, and returns the checksums of
+// the files written, named relative to the backup directory
+func backupIndex(client *Client, index string, config Config) (*backup.IndexEntry, []backup.File, error) {
+	dir := filepath.Join(config.Output, index)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	entry := &backup.IndexEntry{
+		Name:     index,
+		Mapping:  filepath.Join(index, backup.MappingName),
+		Settings: filepath.Join(index, backup.SettingsName),
+		Aliases:  filepath.Join(index, backup.AliasesName),
+	}
+
+	// Only the data file is compressed
+	metadataConfig := config
+	metadataConfig.Compress = "none"
+
+	mapping, err := getMapping(client, index)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get mapping: %w", err)
+	}
+	settings, err := getSettings(client, index)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	aliases, err := getAliases(client, index)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get aliases: %w", err)
+	}
+
+	var files []backup.File
+	for _, metadata := range []struct {
+		name string
+		data interface{}
+	}{
+		{entry.Mapping, mapping},
+		{entry.Settings, settings},
+		{entry.Aliases, aliases},
+	} {
+		file, err := writeToFile(filepath.Join(config.Output, metadata.name), metadata.data, metadataConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		file.Name = metadata.name
+		files = append(files, file)
+	}
+
+	data, documents, err := backupData(client, index, false, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	entry.Data, entry.Documents = data.Name, documents
+
+	return entry, append(files, data), nil
+}
+
+// backupDataStream writes the documents of a data stream to its
+// subdirectory of the backup directory, and returns the data stream with
+// the templates it is recreated from and the checksum of its data file,
+// named relative to the backup directory
+func backupDataStream(client *Client, name string, config Config) (*backup.DataStreamEntry, *backup.DataStream, []backup.File, error) {
+	stream, err := getDataStreamBackup(client, name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(config.Output, name), 0755); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create data stream directory: %w", err)
+	}
+
+	data, documents, err := backupData(client, name, true, config)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	entry := &backup.DataStreamEntry{Name: name, Documents: documents, Data: data.Name}
+	return entry, stream, []backup.File{data}, nil
+}
+
+// backupData writes the documents of an index or data stream to the data
+// file of its subdirectory, compressed as configured and named accordingly,
+// and returns the checksum of the file, named relative to the backup
+// directory, and the number of documents written
+func backupData(client *Client, name string, dataStream bool, config Config) (backup.File, int, error) {
+	data := filepath.Join(name, backup.DataName(config.Format))
+	kind, err := compression(data, config)
+	if err != nil {
+		return backup.File{}, 0, err
+	}
+	if kind == "gzip" {
+		data += ".gz"
+	}
+
+	file, err := createFile(filepath.Join(config.Output, data), config)
+	if err != nil {
+		return backup.File{}, 0, fmt.Errorf("failed to create data file: %w", err)
+	}
+	defer file.Close()
+
+	writer, err := openDocumentWriter(client, file, []string{name}, config)
+	if err != nil {
+		return backup.File{}, 0, err
+	}
+
+	documents, err := exportIndex(client, writer, name, config.Limit, dataStream, config)
+	if err != nil {
+		return backup.File{}, 0, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return backup.File{}, 0, fmt.Errorf("failed to write data file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return backup.File{}, 0, fmt.Errorf("failed to write data file: %w", err)
+	}
+
+	sum := file.Sum()
+	sum.Name = data
+	return sum, documents, nil
+}
+
+// backupPipelines writes the ingest pipelines that the settings of the backed
+// up indices and the templates of the backed up data streams refer to at
+// the top of the backup directory, records the file in the manifest and
+// returns its checksum
+func backupPipelines(client *Client, indices []string, streams []*backup.DataStream, manifest *backup.Manifest, config Config) ([]backup.File, error) {
+	var indexSettings []map[string]interface{}
+	if len(indices) > 0 {
+		settings, err := getSettings(client, strings.Join(indices, ","))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get settings: %w", err)
+		}
+		for _, index := range indices {
+			indexSettings = append(indexSettings, indexdef.Entry(settings, index, "settings"))
+		}
+	}
+	for _, stream := range streams {
+		indexSettings = append(indexSettings, templateSettings(stream.Templates)...)
+	}
+
+	metadataConfig := config
+	metadataConfig.Compress = "none"
+	written, err := writeReferencedPipelines(client, filepath.Join(config.Output, backup.PipelinesName), indexSettings, metadataConfig)
+	if err != nil {
+		return nil, err
+	}
+	if len(written) > 0 {
+		manifest.Pipelines = backup.PipelinesName
+	}
+
+	return written, nil
+}
+
+// redactURL removes credentials from a URL so it can be recorded safely
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+
+	u.User = nil
+	return u.String()
+}
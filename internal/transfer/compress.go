@@ -0,0 +1,135 @@
+package transfer
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+)
+
+// compression returns the compression of an output file: config.Compress,
+// or gzip when it is empty and the file name ends in .gz
+func compression(path string, config Config) (string, error) {
+	switch config.Compress {
+	case "":
+		if strings.HasSuffix(strings.ToLower(path), ".gz") {
+			return "gzip", nil
+		}
+		return "none", nil
+	case "none", "gzip":
+		return config.Compress, nil
+	default:
+		return "", fmt.Errorf("unsupported compression: %s", config.Compress)
+	}
+}
+
+// outputFile is an output file written through an optional compressor
+type outputFile struct {
+	io.Writer
+	path    string
+	file    *os.File
+	gz      *gzip.Writer
+	counter *countingWriter
+
+	// input counts the bytes written to the file before compression
+	input *countingWriter
+}
+
+// countingWriter counts the bytes written through it and hashes them unless
+// hash is nil
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+	hash    *backup.Hash
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.written += int64(n)
+	if w.hash != nil {
+		w.hash.Write(p[:n])
+	}
+	return n, err
+}
+
+// createFile creates an output file, compressed according to compression
+// with config.CompressionLevel (0 for the default level). For backup.Stdio,
+// the output goes to standard output, which Close leaves open.
+func createFile(path string, config Config) (*outputFile, error) {
+	kind, err := compression(path, config)
+	if err != nil {
+		return nil, err
+	}
+
+	level := config.CompressionLevel
+	if level == 0 {
+		level = gzip.DefaultCompression
+	} else if level < gzip.BestSpeed || level > gzip.BestCompression {
+		return nil, fmt.Errorf("invalid compression level %d (use 1 to 9)", level)
+	}
+
+	file := os.Stdout
+	if path != backup.Stdio {
+		file, err = os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	counter := &countingWriter{writer: file, hash: backup.NewHash()}
+	if kind == "none" {
+		return &outputFile{Writer: counter, path: path, file: file, counter: counter, input: counter}, nil
+	}
+
+	gz, err := gzip.NewWriterLevel(counter, level)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	input := &countingWriter{writer: gz}
+	return &outputFile{Writer: input, path: path, file: file, gz: gz, counter: counter, input: input}, nil
+}
+
+// Written returns the number of bytes written to the file so far before
+// compression
+func (f *outputFile) Written() int64 {
+	return f.input.written
+}
+
+// Size returns the number of bytes stored in the file so far. Compressed
+// data is buffered by the compressor, so the size lags behind what has been
+// written until Close.
+func (f *outputFile) Size() int64 {
+	return f.counter.written
+}
+
+// Sum returns the checksum of the bytes stored in the file so far, recorded
+// under its file name; after Close, that of the whole file
+func (f *outputFile) Sum() backup.File {
+	return f.counter.hash.File(filepath.Base(f.path))
+}
+
+// Close flushes the compressor and closes the file
+func (f *outputFile) Close() error {
+	if f.gz != nil {
+		if err := f.gz.Close(); err != nil {
+			f.closeFile()
+			return err
+		}
+	}
+
+	return f.closeFile()
+}
+
+// closeFile closes the file unless it is standard output
+func (f *outputFile) closeFile() error {
+	if f.file == os.Stdout {
+		return nil
+	}
+	return f.file.Close()
+}
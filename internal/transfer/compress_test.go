@@ -0,0 +1,159 @@
+package transfer
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+)
+
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		path     string
+		compress string
+		want     string
+		wantErr  bool
+	}{
+		{"backup.ndjson", "", "none", false},
+		{"backup.ndjson.gz", "", "gzip", false},
+		{"backup.NDJSON.GZ", "", "gzip", false},
+		{"backup.ndjson", "gzip", "gzip", false},
+		{"backup.ndjson.gz", "none", "none", false},
+		{"backup.ndjson", "zstd", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := compression(tt.path, Config{Compress: tt.compress})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("compression(%s, %q) error = %v, wantErr %v", tt.path, tt.compress, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("compression(%s, %q) = %s, want %s", tt.path, tt.compress, got, tt.want)
+		}
+	}
+}
+
+// readGzipFile returns the decompressed content of a gzip file
+func readGzipFile(t *testing.T, path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Expected %s to be gzip: %v", path, err)
+	}
+
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Failed to decompress %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestWriteToFileCompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json.gz")
+
+	sum, err := writeToFile(path, map[string]string{"a": "b"}, Config{CompressionLevel: 9})
+	if err != nil {
+		t.Fatalf("writeToFile failed: %v", err)
+	}
+
+	if got := readGzipFile(t, path); got != "{\"a\":\"b\"}\n" {
+		t.Errorf("Unexpected content %q", got)
+	}
+
+	// The checksum is computed over the compressed bytes while writing them
+	if sum.Name != "templates.json.gz" {
+		t.Errorf("Expected the checksum to be named after the file, got %+v", sum)
+	}
+	checkFiles(t, filepath.Dir(path), []backup.File{sum})
+
+	if _, err := writeToFile(path, "x", Config{CompressionLevel: 12}); err == nil {
+		t.Error("Expected an error for an invalid compression level")
+	}
+}
+
+func TestExportToFileCompressed(t *testing.T) {
+	client := &Client{API: &MockElasticsearchAPI{}, URL: "http://mock:9200"}
+	output := filepath.Join(t.TempDir(), "data.ndjson")
+
+	config := Config{Output: output, Format: "ndjson", ScrollSize: 10, Verbose: true, Compress: "gzip"}
+	if err := exportToFile(client, []dataSource{{Name: "test-index"}}, config); err != nil {
+		t.Fatalf("exportToFile failed: %v", err)
+	}
+
+	if got := readGzipFile(t, output); !strings.Contains(got, `"_index":"test-index"`) {
+		t.Errorf("Expected the compressed documents, got %q", got)
+	}
+
+	// The sidecar follows the same compression
+	if got := readGzipFile(t, output+".mapping.json"); !strings.Contains(got, "test-index") {
+		t.Errorf("Expected the compressed mapping, got %q", got)
+	}
+}
+
+// captureOutput runs fn with standard output and standard error redirected
+// and returns what was written to each of them
+func captureOutput(t *testing.T, fn func()) (string, string) {
+	stdout, stderr := os.Stdout, os.Stderr
+	outReader, outWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	errReader, errWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+
+	os.Stdout, os.Stderr = outWriter, errWriter
+	func() {
+		defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+		fn()
+	}()
+	outWriter.Close()
+	errWriter.Close()
+
+	out, _ := io.ReadAll(outReader)
+	errOut, _ := io.ReadAll(errReader)
+	return string(out), string(errOut)
+}
+
+func TestExportToStdout(t *testing.T) {
+	client := &Client{API: &MockElasticsearchAPI{}, URL: "http://mock:9200"}
+
+	config := Config{Output: "-", Format: "ndjson", ScrollSize: 10, Verbose: true}
+	var exportErr error
+	out, errOut := captureOutput(t, func() {
+		exportErr = exportToFile(client, []dataSource{{Name: "test-index"}}, config)
+	})
+	if exportErr != nil {
+		t.Fatalf("exportToFile failed: %v", exportErr)
+	}
+
+	// Standard output carries nothing but the documents
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"_index":"test-index"`) {
+		t.Errorf("Expected one document on standard output, got %q", out)
+	}
+	if !strings.Contains(errOut, "Exported 1 documents to -") {
+		t.Errorf("Expected the log on standard error, got %q", errOut)
+	}
+
+	// No sidecar named after "-" is written
+	if _, err := os.Stat(backup.MappingFile(backup.Stdio)); !os.IsNotExist(err) {
+		t.Errorf("Expected no mapping file, got %v", err)
+	}
+
+	config.FileDocs = 10
+	if err := exportToFile(client, []dataSource{{Name: "test-index"}}, config); err == nil {
+		t.Error("Expected error when splitting standard output into parts")
+	}
+}
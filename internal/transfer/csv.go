@@ -0,0 +1,204 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultArraySeparator joins the values of an array in a CSV cell
+const defaultArraySeparator = "|"
+
+// csvMetadataColumns are the document metadata columns of the default CSV
+// header
+var csvMetadataColumns = []string{"_index", "_id"}
+
+// csvEncoder writes documents as CSV rows with a fixed set of columns
+type csvEncoder struct {
+	writer    *csv.Writer
+	columns   []string
+	separator string
+}
+
+// newCSVWriter returns a documentWriter for the csv format with the given
+// columns, and writes the header row. Columns are dot paths into the source,
+// or the _index and _id metadata.
+func newCSVWriter(writer io.Writer, columns []string, separator string) (*documentWriter, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("csv format requires at least one column")
+	}
+	if separator == "" {
+		separator = defaultArraySeparator
+	}
+
+	encoder := &csvEncoder{writer: csv.NewWriter(writer), columns: columns, separator: separator}
+	if err := encoder.writer.Write(columns); err != nil {
+		return nil, err
+	}
+
+	return &documentWriter{writer: writer, format: "csv", csv: encoder, dataStreams: make(map[string]bool)}, nil
+}
+
+// openDocumentWriter returns the documentWriter for config.Format. The csv
+// columns are config.Fields, or the metadata columns followed by the fields
+// of the mappings of indices.
+func openDocumentWriter(client *Client, writer io.Writer, indices []string, config Config) (*documentWriter, error) {
+	open, err := documentWriterFactory(client, indices, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(writer)
+}
+
+// documentWriterFactory returns a function opening documentWriters as
+// openDocumentWriter does, looking the csv columns up only once
+func documentWriterFactory(client *Client, indices []string, config Config) (func(io.Writer) (*documentWriter, error), error) {
+	if config.Format != "csv" {
+		return func(writer io.Writer) (*documentWriter, error) {
+			return newDocumentWriter(writer, config.Format, config.Pretty)
+		}, nil
+	}
+	if config.Pretty {
+		return nil, fmt.Errorf("pretty output requires the json format")
+	}
+
+	columns := config.Fields
+	if len(columns) == 0 {
+		mapping, err := getMapping(client, strings.Join(indices, ","))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get mapping: %w", err)
+		}
+		columns = append(append([]string{}, csvMetadataColumns...), mappingFields(mapping)...)
+	}
+
+	return func(writer io.Writer) (*documentWriter, error) {
+		return newCSVWriter(writer, columns, config.ArraySeparator)
+	}, nil
+}
+
+// mappingFields returns the sorted dot paths of the leaf fields of all
+// indices of a get mapping API response. Multi-fields and field aliases are
+// left out as they never appear in the source.
+func mappingFields(mapping map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	for _, entry := range mapping {
+		index, _ := entry.(map[string]interface{})
+		mappings, _ := index["mappings"].(map[string]interface{})
+		properties, _ := mappings["properties"].(map[string]interface{})
+		collectFields(properties, "", seen)
+	}
+
+	fields := make([]string, 0, len(seen))
+	for field := range seen {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// collectFields adds the leaf fields of mapping properties under prefix
+func collectFields(properties map[string]interface{}, prefix string, seen map[string]bool) {
+	for name, value := range properties {
+		field, _ := value.(map[string]interface{})
+		path := prefix + name
+
+		if children, ok := field["properties"].(map[string]interface{}); ok {
+			collectFields(children, path+".", seen)
+			continue
+		}
+		if field["type"] == "alias" {
+			continue
+		}
+		seen[path] = true
+	}
+}
+
+// write writes one document as a row
+func (e *csvEncoder) write(doc Document) error {
+	row := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		switch column {
+		case "_index":
+			row[i] = doc.Index
+		case "_id":
+			row[i] = doc.ID
+		default:
+			values := lookupField(doc.Source, column)
+			cells := make([]string, len(values))
+			for j, value := range values {
+				cells[j] = formatCSVValue(value)
+			}
+			row[i] = strings.Join(cells, e.separator)
+		}
+	}
+
+	return e.writer.Write(row)
+}
+
+// flush writes the buffered rows
+func (e *csvEncoder) flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// lookupField returns the values at a dot path in a document source. Path
+// segments match object keys, including keys that contain dots themselves,
+// and arrays along the path contribute the values of all their elements.
+func lookupField(value interface{}, path string) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		var values []interface{}
+		for _, item := range v {
+			values = append(values, lookupField(item, path)...)
+		}
+		return values
+	case map[string]interface{}:
+		if path == "" {
+			return []interface{}{v}
+		}
+		if child, ok := v[path]; ok {
+			return lookupField(child, "")
+		}
+		for i := 0; i < len(path); i++ {
+			if path[i] != '.' {
+				continue
+			}
+			if child, ok := v[path[:i]]; ok {
+				return lookupField(child, path[i+1:])
+			}
+		}
+		return nil
+	default:
+		if path != "" {
+			return nil
+		}
+		return []interface{}{v}
+	}
+}
+
+// formatCSVValue formats a single value of a CSV cell; objects are written
+// as JSON
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,132 @@
+package transfer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLookupField(t *testing.T) {
+	var source map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"user": {"name": "ann", "address": {"city": "Oslo"}},
+		"tags": ["a", "b"],
+		"items": [{"sku": "x1"}, {"sku": "x2", "qty": 2}],
+		"geo.lat": 59.9,
+		"empty": null
+	}`), &source)
+
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{"user.name", []interface{}{"ann"}},
+		{"user.address.city", []interface{}{"Oslo"}},
+		{"tags", []interface{}{"a", "b"}},
+		{"items.sku", []interface{}{"x1", "x2"}},
+		{"items.qty", []interface{}{float64(2)}},
+		{"geo.lat", []interface{}{59.9}},
+		{"empty", []interface{}{nil}},
+		{"missing", nil},
+		{"user.name.first", nil},
+	}
+
+	for _, tt := range tests {
+		if got := lookupField(source, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lookupField(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCSVValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{"text", "text"},
+		{float64(1000000), "1000000"},
+		{1.5, "1.5"},
+		{true, "true"},
+		{map[string]interface{}{"a": "b"}, `{"a":"b"}`},
+	}
+
+	for _, tt := range tests {
+		if got := formatCSVValue(tt.value); got != tt.want {
+			t.Errorf("formatCSVValue(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMappingFields(t *testing.T) {
+	var mapping map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"a": {"mappings": {"properties": {
+			"title": {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+			"user": {"properties": {"name": {"type": "keyword"}, "age": {"type": "integer"}}},
+			"author": {"type": "alias", "path": "user.name"}
+		}}},
+		"b": {"mappings": {"properties": {
+			"title": {"type": "text"},
+			"created": {"type": "date"}
+		}}}
+	}`), &mapping)
+
+	want := []string{"created", "title", "user.age", "user.name"}
+	if got := mappingFields(mapping); !reflect.DeepEqual(got, want) {
+		t.Errorf("mappingFields = %v, want %v", got, want)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf strings.Builder
+	writer, err := newCSVWriter(&buf, []string{"_id", "user.name", "tags", "note"}, ";")
+	if err != nil {
+		t.Fatalf("newCSVWriter failed: %v", err)
+	}
+
+	writer.Write(Document{Index: "i", ID: "1", Source: map[string]interface{}{
+		"user": map[string]interface{}{"name": "ann"},
+		"tags": []interface{}{"a", "b"},
+		"note": "says \"hi\", twice",
+	}})
+	writer.Write(Document{Index: "i", ID: "2", Source: map[string]interface{}{}})
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	expected := "_id,user.name,tags,note\n1,ann,a;b,\"says \"\"hi\"\", twice\"\n2,,,\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected csv output:\n%q\nwant:\n%q", buf.String(), expected)
+	}
+
+	if _, err := newCSVWriter(&buf, nil, ""); err == nil {
+		t.Error("Expected an error without columns")
+	}
+}
+
+func TestExportToFileCSV(t *testing.T) {
+	client := &Client{API: &MockElasticsearchAPI{}, URL: "http://mock:9200"}
+	output := filepath.Join(t.TempDir(), "out.csv")
+
+	config := Config{Output: output, Format: "csv", ScrollSize: 10, Verbose: true}
+	if err := exportToFile(client, []dataSource{{Name: "test-index"}}, config); err != nil {
+		t.Fatalf("exportToFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if lines[0] != "_index,_id,field1" {
+		t.Errorf("Expected header from the mapping, got %q", lines[0])
+	}
+	if len(lines) < 2 || !strings.HasPrefix(lines[1], "test-index,") {
+		t.Errorf("Expected a document row, got %v", lines)
+	}
+}
@@ -0,0 +1,125 @@
+package transfer
+
+import (
+	"fmt"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/templates"
+)
+
+// getDataStreamBackup describes a data stream together with the index
+// template it is created from and the component templates that template is
+// composed of
+func getDataStreamBackup(client *Client, name string) (*backup.DataStream, error) {
+	stream, err := cluster.GetDataStream(client.API, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data stream %s: %w", name, err)
+	}
+	if stream == nil {
+		return nil, fmt.Errorf("data stream %s does not exist", name)
+	}
+
+	set, err := getTemplatesOfKind(client, templates.Index, stream.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index template %s: %w", stream.Template, err)
+	}
+	if len(set.IndexTemplates) == 0 {
+		return nil, fmt.Errorf("index template %s of data stream %s does not exist", stream.Template, name)
+	}
+
+	if missing := set.MissingComponents(); len(missing) > 0 {
+		components, err := getTemplates(client, missing, templates.Component)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get component templates: %w", err)
+		}
+		set.Add(components)
+	}
+
+	return &backup.DataStream{Name: name, Template: stream.Template, Templates: set}, nil
+}
+
+// checkDataStreamRename returns an error if the data stream name would be
+// created as destName on the destination but its index template does not
+// match destName. Nothing is checked when destName already exists.
+func checkDataStreamRename(sourceClient, destClient *Client, name, destName string) error {
+	if name == destName {
+		return nil
+	}
+
+	exists, err := cluster.IsDataStream(destClient.API, destName)
+	if err != nil {
+		return fmt.Errorf("failed to check data stream %s: %w", destName, err)
+	}
+	if exists {
+		return nil
+	}
+
+	stream, err := getDataStreamBackup(sourceClient, name)
+	if err != nil {
+		return err
+	}
+
+	return stream.CheckDestination(destName)
+}
+
+// ensureDataStream makes sure the data stream destName exists on the
+// destination. A missing one is created from the index template of the
+// source data stream name, which is copied first along with its component
+// templates and the ILM policies and pipelines their settings refer to.
+func ensureDataStream(sourceClient, destClient *Client, name, destName string, config Config) error {
+	exists, err := cluster.IsDataStream(destClient.API, destName)
+	if err != nil {
+		return fmt.Errorf("failed to check data stream %s: %w", destName, err)
+	}
+	if exists {
+		return nil
+	}
+
+	stream, err := getDataStreamBackup(sourceClient, name)
+	if err != nil {
+		return err
+	}
+
+	if err := copyIndexDependencies(sourceClient, destClient, templateSettings(stream.Templates), config); err != nil {
+		return err
+	}
+
+	return createDataStreamFrom(destClient, stream, destName, config)
+}
+
+// createDataStreamFrom puts the templates of a backed up data stream that do
+// not exist on the destination yet (all of them with Existing "overwrite")
+// and creates the data stream destName from them
+func createDataStreamFrom(client *Client, stream *backup.DataStream, destName string, config Config) error {
+	templateOptions := putOptions(config)
+	if templateOptions.Existing != "overwrite" {
+		templateOptions.Existing = "skip"
+	}
+
+	if err := templates.Put(client.API, stream.Templates, templateOptions); err != nil {
+		return fmt.Errorf("failed to put templates of data stream %s: %w", stream.Name, err)
+	}
+
+	if err := cluster.CreateDataStream(client.API, destName); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created data stream %s from index template %s\n", destName, stream.Template)
+
+	return nil
+}
+
+// templateSettings returns the index settings of the component and index
+// templates of a set, in the format of the get settings API for one index
+func templateSettings(set *templates.Set) []map[string]interface{} {
+	var settings []map[string]interface{}
+	for _, t := range set.Ordered() {
+		template, _ := t.Body["template"].(map[string]interface{})
+		if s, ok := template["settings"].(map[string]interface{}); ok {
+			settings = append(settings, s)
+		}
+	}
+
+	return settings
+}
@@ -0,0 +1,225 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/backup"
+)
+
+// createMockDataStreamAPI returns a source cluster with the data stream
+// logs-app, created from the index template logs composed of logs-settings
+func createMockDataStreamAPI() *MockElasticsearchAPI {
+	return &MockElasticsearchAPI{
+		ResolveResponse: &esapi.Response{
+			StatusCode: 200,
+			Body: io.NopCloser(strings.NewReader(`{
+				"indices": [{"name": "plain"}],
+				"aliases": [],
+				"data_streams": [{"name": "logs-app", "backing_indices": [".ds-logs-app-000001", ".ds-logs-app-000002"], "timestamp_field": "@timestamp"}]
+			}`)),
+		},
+		SearchResponse: &esapi.Response{
+			StatusCode: 200,
+			Body: io.NopCloser(strings.NewReader(`{
+				"_scroll_id": "scroll",
+				"hits": {"hits": [{"_index": ".ds-logs-app-000002", "_id": "1", "_source": {"@timestamp": "2024-01-01T00:00:00Z"}}]}
+			}`)),
+		},
+		CountResponse: createMockCountResponse(1, false),
+		DataStreams:   map[string]string{"logs-app": "logs"},
+		IndexTemplates: map[string]string{
+			"logs": `{"index_patterns": ["logs-*"], "data_stream": {}, "composed_of": ["logs-settings"], "priority": 200}`,
+		},
+		ComponentTemplates: map[string]string{
+			"logs-settings": `{"template": {"settings": {"index": {"lifecycle": {"name": "logs-policy"}}}}}`,
+		},
+		Policies: map[string]string{
+			"logs-policy": `{"phases": {"hot": {"actions": {"rollover": {"max_age": "30d"}}}}}`,
+		},
+	}
+}
+
+func TestResolveSources(t *testing.T) {
+	client := &Client{API: createMockDataStreamAPI(), URL: "http://mock:9200"}
+
+	sources, err := resolveSources(client, "*")
+	if err != nil {
+		t.Fatalf("resolveSources failed: %v", err)
+	}
+
+	expected := []dataSource{{Name: "logs-app", DataStream: true}, {Name: "plain"}}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Expected %v, got %v", expected, sources)
+	}
+}
+
+func TestResolveIndicesSkipsDataStreams(t *testing.T) {
+	client := &Client{API: createMockDataStreamAPI(), URL: "http://mock:9200"}
+
+	var indices []string
+	output, _ := captureOutput(t, func() {
+		var err error
+		if indices, err = resolveIndices(client, "*", Config{}); err != nil {
+			t.Errorf("resolveIndices failed: %v", err)
+		}
+	})
+
+	// Neither the data stream nor its backing indices are recreated as
+	// indices by the mapping, settings, index and aliases types
+	if !reflect.DeepEqual(indices, []string{"plain"}) {
+		t.Errorf("Expected only the plain index, got %v", indices)
+	}
+	if !strings.Contains(output, "Skipping data stream logs-app") {
+		t.Errorf("Expected the skipped data stream to be reported, got %q", output)
+	}
+}
+
+func TestExportDataStreamToFile(t *testing.T) {
+	client := &Client{API: createMockDataStreamAPI(), URL: "http://mock:9200"}
+
+	output := t.TempDir() + "/logs.ndjson"
+	config := Config{Output: output, Format: "ndjson", ScrollSize: 10, Verbose: true}
+
+	if err := exportToFile(client, []dataSource{{Name: "logs-app", DataStream: true}}, config); err != nil {
+		t.Fatalf("exportToFile failed: %v", err)
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		t.Fatal("Expected a document in the output")
+	}
+	var doc Document
+	if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid document: %v", err)
+	}
+	if doc.Index != "logs-app" {
+		t.Errorf("Expected document to be recorded under the data stream, got %s", doc.Index)
+	}
+
+	if _, err := os.Stat(backup.MappingFile(output)); !os.IsNotExist(err) {
+		t.Error("Expected no mapping file for backing indices")
+	}
+
+	data, err := os.ReadFile(backup.DataStreamsFile(output))
+	if err != nil {
+		t.Fatalf("Failed to read data streams file: %v", err)
+	}
+	var streams []backup.DataStream
+	if err := json.Unmarshal(data, &streams); err != nil {
+		t.Fatalf("Invalid data streams file: %v", err)
+	}
+	if len(streams) != 1 || streams[0].Name != "logs-app" || streams[0].Template != "logs" {
+		t.Fatalf("Unexpected data streams: %+v", streams)
+	}
+	if len(streams[0].Templates.IndexTemplates) != 1 || len(streams[0].Templates.ComponentTemplates) != 1 {
+		t.Errorf("Expected the index template and its component template, got %+v", streams[0].Templates)
+	}
+}
+
+func TestEnsureDataStream(t *testing.T) {
+	source := &Client{API: createMockDataStreamAPI(), URL: "http://source:9200"}
+	api := &MockElasticsearchAPI{}
+	dest := &Client{API: api, URL: "http://dest:9200"}
+
+	if err := ensureDataStream(source, dest, "logs-app", "logs-app", Config{Output: "http://dest:9200"}); err != nil {
+		t.Fatalf("ensureDataStream failed: %v", err)
+	}
+
+	if expected := []string{"component/logs-settings", "index/logs"}; !reflect.DeepEqual(api.PutTemplates, expected) {
+		t.Errorf("Expected templates %v to be put, got %v", expected, api.PutTemplates)
+	}
+	if !reflect.DeepEqual(api.PutPolicies, []string{"logs-policy"}) {
+		t.Errorf("Expected the lifecycle policy to be copied, got %v", api.PutPolicies)
+	}
+	if !reflect.DeepEqual(api.CreatedDataStreams, []string{"logs-app"}) {
+		t.Errorf("Expected logs-app to be created, got %v", api.CreatedDataStreams)
+	}
+
+	// An existing data stream is left alone
+	api.PutTemplates = nil
+	if err := ensureDataStream(source, dest, "logs-app", "logs-app", Config{}); err != nil {
+		t.Fatalf("ensureDataStream failed: %v", err)
+	}
+	if len(api.PutTemplates) != 0 || len(api.CreatedDataStreams) != 1 {
+		t.Errorf("Expected nothing to change, got %v and %v", api.PutTemplates, api.CreatedDataStreams)
+	}
+}
+
+func TestCopyDocumentsToDataStream(t *testing.T) {
+	source := &Client{API: createMockDataStreamAPI(), URL: "http://source:9200"}
+	api := &MockElasticsearchAPI{DataStreams: map[string]string{"logs-app": "logs"}}
+	dest := &Client{API: api, URL: "http://dest:9200"}
+
+	count, err := copyDocuments(source, dest, "logs-app", "logs-app", nil, Config{Concurrency: 2, ScrollSize: 10})
+	if err != nil {
+		t.Fatalf("copyDocuments failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 document, got %d", count)
+	}
+	if api.OpTypes["logs-app"] != "create" {
+		t.Errorf("Expected op_type create for a data stream, got %q", api.OpTypes["logs-app"])
+	}
+
+	plain := &MockElasticsearchAPI{}
+	source = &Client{API: createMockDataStreamAPI(), URL: "http://source:9200"}
+	if _, err := copyDocuments(source, &Client{API: plain, URL: "http://dest:9200"}, "logs-app", "logs-copy", nil, Config{Concurrency: 1, ScrollSize: 10}); err != nil {
+		t.Fatalf("copyDocuments failed: %v", err)
+	}
+	if plain.OpTypes["logs-copy"] != "" {
+		t.Errorf("Expected default op_type for an index, got %q", plain.OpTypes["logs-copy"])
+	}
+}
+
+func TestCheckDataStreamRename(t *testing.T) {
+	source := &Client{API: createMockDataStreamAPI(), URL: "http://source:9200"}
+	dest := &Client{API: &MockElasticsearchAPI{DataStreams: map[string]string{"archive-app": "archive"}}, URL: "http://dest:9200"}
+
+	tests := []struct {
+		destName string
+		wantErr  bool
+	}{
+		{"logs-app", false},
+		{"logs-copy", false},
+		// An existing destination data stream is not created
+		{"archive-app", false},
+		{"metrics-app", true},
+	}
+
+	for _, tt := range tests {
+		err := checkDataStreamRename(source, dest, "logs-app", tt.destName)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkDataStreamRename(%s) error = %v, wantErr %v", tt.destName, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCopyDocumentsWithoutTimestamp(t *testing.T) {
+	source := &Client{API: &MockElasticsearchAPI{Sources: map[string]string{"plain": `{"field": "value"}`}}, URL: "http://source:9200"}
+	api := &MockElasticsearchAPI{DataStreams: map[string]string{"logs-app": "logs"}}
+	dest := &Client{API: api, URL: "http://dest:9200"}
+
+	count, err := copyDocuments(source, dest, "plain", "logs-app", nil, Config{Concurrency: 1, ScrollSize: 10})
+	if err != nil {
+		t.Fatalf("copyDocuments failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the document without @timestamp to be skipped, got %d", count)
+	}
+	if _, ok := api.OpTypes["logs-app"]; ok {
+		t.Error("Expected nothing to be written to the data stream")
+	}
+}
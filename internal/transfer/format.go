@@ -0,0 +1,123 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// documentWriter writes documents to a data file in the json, ndjson, bulk
+// or csv format. The json format is a single JSON array written one document at a
+// time, so it never holds more than one document in memory; Close writes the
+// closing bracket. The bulk format is the body of a bulk API request: an
+// action line followed by the source of each document.
+type documentWriter struct {
+	writer  io.Writer
+	format  string
+	pretty  bool
+	written int
+
+	// csv encodes the rows of the csv format
+	csv *csvEncoder
+
+	// dataStreams lists the indices written with the create action in the
+	// bulk format, which data streams require
+	dataStreams map[string]bool
+}
+
+// newDocumentWriter returns a documentWriter for format; pretty indents the
+// documents of a json array
+func newDocumentWriter(writer io.Writer, format string, pretty bool) (*documentWriter, error) {
+	switch format {
+	case "json":
+	case "ndjson", "bulk":
+		if pretty {
+			return nil, fmt.Errorf("pretty output requires the json format")
+		}
+	case "csv":
+		return nil, fmt.Errorf("csv format requires columns")
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	return &documentWriter{writer: writer, format: format, pretty: pretty, dataStreams: make(map[string]bool)}, nil
+}
+
+// addDataStream records that the documents of index are written to a data
+// stream
+func (w *documentWriter) addDataStream(index string) {
+	w.dataStreams[index] = true
+}
+
+// Write writes one document
+func (w *documentWriter) Write(doc Document) error {
+	switch w.format {
+	case "ndjson":
+		return writeDocument(w.writer, doc, w.format)
+	case "bulk":
+		return w.writeBulk(doc)
+	case "csv":
+		return w.csv.write(doc)
+	}
+
+	separator := ",\n"
+	if w.written == 0 {
+		separator = "[\n"
+	}
+	if _, err := io.WriteString(w.writer, separator); err != nil {
+		return err
+	}
+	w.written++
+
+	if !w.pretty {
+		return writeDocument(w.writer, doc, w.format)
+	}
+
+	data, err := json.MarshalIndent(doc, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.writer, "  %s", data)
+	return err
+}
+
+// writeBulk writes the action line and the source line of a document
+func (w *documentWriter) writeBulk(doc Document) error {
+	action := "index"
+	if w.dataStreams[doc.Index] {
+		action = "create"
+	}
+
+	meta, err := json.Marshal(map[string]interface{}{
+		action: map[string]string{"_index": doc.Index, "_id": doc.ID},
+	})
+	if err != nil {
+		return err
+	}
+
+	source, err := json.Marshal(doc.Source)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w.writer, "%s\n%s\n", meta, source)
+	return err
+}
+
+// Close terminates a json array and flushes csv rows; it does not close the
+// underlying writer
+func (w *documentWriter) Close() error {
+	switch w.format {
+	case "csv":
+		return w.csv.flush()
+	case "ndjson", "bulk":
+		return nil
+	}
+
+	closing := "\n]\n"
+	if w.written == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(w.writer, closing)
+	return err
+}
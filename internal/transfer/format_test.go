@@ -0,0 +1,112 @@
+package transfer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDocumentWriterJSONArray(t *testing.T) {
+	docs := []Document{
+		{Index: "test-index", ID: "1", Source: map[string]interface{}{"field": "a"}},
+		{Index: "test-index", ID: "2", Source: map[string]interface{}{"field": "b"}},
+	}
+
+	for _, pretty := range []bool{false, true} {
+		var buf strings.Builder
+		writer, err := newDocumentWriter(&buf, "json", pretty)
+		if err != nil {
+			t.Fatalf("newDocumentWriter failed: %v", err)
+		}
+		for _, doc := range docs {
+			if err := writer.Write(doc); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		var decoded []Document
+		if err := json.Unmarshal([]byte(buf.String()), &decoded); err != nil {
+			t.Fatalf("Expected a valid JSON array (pretty=%v), got %v:\n%s", pretty, err, buf.String())
+		}
+		if len(decoded) != 2 || decoded[1].ID != "2" {
+			t.Errorf("Expected both documents back, got %+v", decoded)
+		}
+
+		indented := strings.Contains(buf.String(), "\n    \"_index\"")
+		if indented != pretty {
+			t.Errorf("Expected indentation %v, got:\n%s", pretty, buf.String())
+		}
+	}
+}
+
+func TestDocumentWriterEmptyArray(t *testing.T) {
+	var buf strings.Builder
+	writer, err := newDocumentWriter(&buf, "json", false)
+	if err != nil {
+		t.Fatalf("newDocumentWriter failed: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if buf.String() != "[]\n" {
+		t.Errorf("Expected an empty array, got %q", buf.String())
+	}
+}
+
+func TestDocumentWriterNDJSON(t *testing.T) {
+	var buf strings.Builder
+	writer, err := newDocumentWriter(&buf, "ndjson", false)
+	if err != nil {
+		t.Fatalf("newDocumentWriter failed: %v", err)
+	}
+	writer.Write(Document{Index: "test-index", ID: "1"})
+	writer.Write(Document{Index: "test-index", ID: "2"})
+	writer.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || strings.HasPrefix(lines[0], "[") {
+		t.Errorf("Expected two document lines, got %q", buf.String())
+	}
+}
+
+func TestNewDocumentWriterErrors(t *testing.T) {
+	var buf strings.Builder
+	if _, err := newDocumentWriter(&buf, "ndjson", true); err == nil {
+		t.Error("Expected an error for pretty ndjson")
+	}
+	if _, err := newDocumentWriter(&buf, "csv", false); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestDocumentWriterBulk(t *testing.T) {
+	var buf strings.Builder
+	writer, err := newDocumentWriter(&buf, "bulk", false)
+	if err != nil {
+		t.Fatalf("newDocumentWriter failed: %v", err)
+	}
+	writer.addDataStream("logs-app")
+
+	writer.Write(Document{Index: "test-index", ID: "1", Source: map[string]interface{}{"field": "a"}})
+	writer.Write(Document{Index: "logs-app", ID: "2", Source: map[string]interface{}{"@timestamp": "2024-01-01"}})
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	expected := `{"index":{"_id":"1","_index":"test-index"}}
+{"field":"a"}
+{"create":{"_id":"2","_index":"logs-app"}}
+{"@timestamp":"2024-01-01"}
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected bulk output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+
+	if _, err := newDocumentWriter(&buf, "bulk", true); err == nil {
+		t.Error("Expected an error for pretty bulk")
+	}
+}
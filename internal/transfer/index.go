@@ -0,0 +1,132 @@
+package transfer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/rename"
+)
+
+// transferIndex creates destination indices from the settings and mappings of
+// the source indices in a single create index request each. Unlike the
+// settings type this also carries static settings such as number_of_shards.
+// When the output is a file, the create index bodies are written to it keyed
+// by source index name, with the referenced ingest pipelines next to it.
+func transferIndex(sourceClient *Client, renamer *rename.Renamer, config Config) error {
+	expression := extractIndex(config.Input)
+	if expression == "" {
+		return fmt.Errorf("could not extract index from input URL")
+	}
+
+	indices, err := resolveIndices(sourceClient, expression, config)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source indices: %w", err)
+	}
+
+	bodies, err := getCreateBodies(sourceClient, indices, config)
+	if err != nil {
+		return err
+	}
+
+	var indexSettings []map[string]interface{}
+	for _, index := range indices {
+		settings, _ := bodies[index]["settings"].(map[string]interface{})
+		indexSettings = append(indexSettings, settings)
+	}
+
+	if isFile(config.Output) {
+		sidecars, err := writePipelinesSidecar(sourceClient, indexSettings, config)
+		if err != nil {
+			return err
+		}
+		return writeFileBackup(sourceClient, bodies, sidecars, config)
+	}
+
+	destURL := getBaseURL(config.Output)
+	destClient, err := createClient(destURL, config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	// Check every destination before creating anything
+	destIndices := make(map[string]string)
+	for _, index := range indices {
+		destIndex := destinationIndex(config, renamer, index)
+
+		exists, err := cluster.IndexExists(destClient.API, destIndex)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("index %s already exists on the destination", destIndex)
+		}
+
+		destIndices[index] = destIndex
+	}
+
+	if err := copyIndexDependencies(sourceClient, destClient, indexSettings, config); err != nil {
+		return err
+	}
+
+	for _, index := range indices {
+		if err := cluster.CreateIndex(destClient.API, destIndices[index], bodies[index]); err != nil {
+			return err
+		}
+
+		if config.Verbose {
+			fmt.Printf("Created index %s from %s\n", destIndices[index], index)
+		}
+	}
+
+	return nil
+}
+
+// getCreateBodies builds a create index request body for each source index
+// from its filtered settings and mappings, applying shard and replica
+// overrides
+func getCreateBodies(client *Client, indices []string, config Config) (map[string]map[string]interface{}, error) {
+	expression := strings.Join(indices, ",")
+
+	settings, err := getSettings(client, expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	mapping, err := getMapping(client, expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mapping: %w", err)
+	}
+
+	bodies := make(map[string]map[string]interface{}, len(indices))
+	for _, index := range indices {
+		body := indexdef.CreateBody(
+			indexdef.Entry(settings, index, "settings"),
+			indexdef.Entry(mapping, index, "mappings"),
+		)
+
+		if config.Shards != nil {
+			indexdef.SetIndexSetting(body, "number_of_shards", strconv.Itoa(*config.Shards))
+		}
+		if config.Replicas != nil {
+			indexdef.SetIndexSetting(body, "number_of_replicas", strconv.Itoa(*config.Replicas))
+		}
+
+		bodies[index] = body
+	}
+
+	return bodies, nil
+}
+
+// copyIndexDependencies copies what the settings of transferred indices
+// depend on: the ILM policies named by index.lifecycle.name, and the ingest
+// pipelines named by index.default_pipeline and index.final_pipeline
+func copyIndexDependencies(sourceClient, destClient *Client, settings []map[string]interface{}, config Config) error {
+	if err := copyReferencedPolicies(sourceClient, destClient, settingsPolicies(settings...), config); err != nil {
+		return err
+	}
+
+	return copyReferencedPipelines(sourceClient, destClient, settingsPipelines(settings...), config)
+}
@@ -0,0 +1,117 @@
+package transfer
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+)
+
+func createMockFullSettingsResponse() *esapi.Response {
+	responseBody := `{
+		"test-index": {
+			"settings": {
+				"index": {
+					"number_of_shards": "3",
+					"number_of_replicas": "1",
+					"uuid": "Xyz123",
+					"creation_date": "1700000000000",
+					"provided_name": "test-index",
+					"version": {"created": "8110099"}
+				}
+			}
+		}
+	}`
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(responseBody)),
+	}
+}
+
+func TestGetCreateBodies(t *testing.T) {
+	shards, replicas := 1, 0
+	client := &Client{
+		API: &MockElasticsearchAPI{SettingsResponse: createMockFullSettingsResponse()},
+		URL: "http://mock:9200",
+	}
+
+	bodies, err := getCreateBodies(client, []string{"test-index"}, Config{Shards: &shards, Replicas: &replicas})
+	if err != nil {
+		t.Fatalf("getCreateBodies failed: %v", err)
+	}
+
+	body, ok := bodies["test-index"]
+	if !ok {
+		t.Fatalf("Expected body for test-index, got %v", bodies)
+	}
+
+	index := body["settings"].(map[string]interface{})["index"].(map[string]interface{})
+	for _, key := range []string{"uuid", "creation_date", "provided_name", "version"} {
+		if _, ok := index[key]; ok {
+			t.Errorf("Expected %s to be filtered from settings", key)
+		}
+	}
+	if index["number_of_shards"] != "1" || index["number_of_replicas"] != "0" {
+		t.Errorf("Expected shard and replica overrides, got %v", index)
+	}
+
+	if _, ok := body["mappings"].(map[string]interface{})["properties"]; !ok {
+		t.Errorf("Expected mappings in create body, got %v", body["mappings"])
+	}
+}
+
+func TestIndexExistsAndCreateIndex(t *testing.T) {
+	api := &MockElasticsearchAPI{ExistingIndices: map[string]bool{"test-index": true}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if exists, err := cluster.IndexExists(client.API, "test-index"); err != nil || !exists {
+		t.Errorf("indexExists(test-index) = %v, %v; want true, nil", exists, err)
+	}
+	if exists, err := cluster.IndexExists(client.API, "new-index"); err != nil || exists {
+		t.Errorf("indexExists(new-index) = %v, %v; want false, nil", exists, err)
+	}
+
+	if err := cluster.CreateIndex(client.API, "new-index", map[string]interface{}{"settings": map[string]interface{}{}}); err != nil {
+		t.Fatalf("createIndex failed: %v", err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(api.CreatedBodies["new-index"]), &body); err != nil {
+		t.Errorf("Expected JSON create body, got %q", api.CreatedBodies["new-index"])
+	}
+}
+
+func TestTransferIndexToFile(t *testing.T) {
+	output := t.TempDir() + "/index.json"
+	client := &Client{
+		API: &MockElasticsearchAPI{SettingsResponse: createMockFullSettingsResponse()},
+		URL: "http://mock:9200",
+	}
+
+	config := Config{
+		Input:  "http://localhost:9200/test-index",
+		Output: output,
+		Type:   "index",
+	}
+
+	if err := transferIndex(client, nil, config); err != nil {
+		t.Fatalf("transferIndex failed: %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var bodies map[string]map[string]interface{}
+	if err := json.Unmarshal(content, &bodies); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if _, ok := bodies["test-index"]["settings"]; !ok {
+		t.Errorf("Expected settings for test-index in output, got %v", bodies)
+	}
+}
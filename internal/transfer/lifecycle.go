@@ -0,0 +1,152 @@
+package transfer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/lifecycle"
+)
+
+// transferLifecycle copies the ILM policies whose names match the input URL
+// path (all policies for a bare cluster URL), or the ISM policies with
+// OpenSearch. A file receives them keyed by name as put policy request
+// bodies.
+func transferLifecycle(sourceClient *Client, config Config) error {
+	if config.OpenSearch {
+		return transferISMPolicies(sourceClient, config)
+	}
+
+	all, err := lifecycle.GetPolicies(sourceClient.API)
+	if err != nil {
+		return fmt.Errorf("failed to get lifecycle policies: %w", err)
+	}
+
+	policies, err := matchPolicies(all, config)
+	if err != nil {
+		return err
+	}
+
+	if isFile(config.Output) {
+		return writeFileBackup(sourceClient, policies, nil, config)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("lifecycle type requires a cluster URL without path as output")
+	}
+
+	destURL := getBaseURL(config.Output)
+	destClient, err := createClient(destURL, config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return lifecycle.PutPolicies(destClient.API, policies, putOptions(config))
+}
+
+// transferISMPolicies copies the OpenSearch ISM policies whose ids match the
+// input URL path, like transferLifecycle does for ILM policies. The ISM API
+// is reached through its own client; sourceClient only serves the backup
+// manifest.
+func transferISMPolicies(sourceClient *Client, config Config) error {
+	source := lifecycle.NewISMClient(getBaseURL(config.Input), config.Username, config.Password)
+	all, _, err := lifecycle.GetISMPolicies(source)
+	if err != nil {
+		return fmt.Errorf("failed to get ISM policies: %w", err)
+	}
+
+	policies, err := matchPolicies(all, config)
+	if err != nil {
+		return err
+	}
+
+	if isFile(config.Output) {
+		return writeFileBackup(sourceClient, policies, nil, config)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("lifecycle type requires a cluster URL without path as output")
+	}
+
+	dest := lifecycle.NewISMClient(getBaseURL(config.Output), config.Username, config.Password)
+	return lifecycle.PutISMPolicies(dest, policies, putOptions(config))
+}
+
+// matchPolicies returns the policies whose names match the input URL path
+// (all policies for a bare cluster URL), leaving out system policies unless
+// IncludeSystem is set
+func matchPolicies(all map[string]interface{}, config Config) (map[string]interface{}, error) {
+	pattern := extractIndex(config.Input)
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	policies := make(map[string]interface{})
+	for name, policy := range all {
+		if !cluster.MatchesAny(name, strings.Split(pattern, ",")) {
+			continue
+		}
+		if !config.IncludeSystem && lifecycle.IsSystem(name, policy) {
+			continue
+		}
+		policies[name] = policy
+	}
+
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("no lifecycle policies match %s", pattern)
+	}
+
+	return policies, nil
+}
+
+// copyReferencedPolicies copies the ILM policies that the settings of
+// transferred indices refer to. Policies that already exist on the
+// destination are kept unless Existing is "overwrite".
+func copyReferencedPolicies(sourceClient, destClient *Client, names []string, config Config) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	all, err := lifecycle.GetPolicies(sourceClient.API)
+	if err != nil {
+		return fmt.Errorf("failed to get lifecycle policies: %w", err)
+	}
+
+	policies := make(map[string]interface{})
+	for _, name := range names {
+		if policy, ok := all[name]; ok {
+			policies[name] = policy
+		} else {
+			logf(config, "Warning: lifecycle policy %s does not exist on the source\n", name)
+		}
+	}
+
+	if len(policies) == 0 {
+		return nil
+	}
+
+	policyOptions := putOptions(config)
+	if policyOptions.Existing != "overwrite" {
+		policyOptions.Existing = "skip"
+	}
+
+	return lifecycle.PutPolicies(destClient.API, policies, policyOptions)
+}
+
+// settingsPolicies returns the sorted, deduplicated ILM policies referenced
+// by per-index settings
+func settingsPolicies(settings ...map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, s := range settings {
+		if name := indexdef.LifecyclePolicy(s); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
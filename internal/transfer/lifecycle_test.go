@@ -0,0 +1,158 @@
+package transfer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/lifecycle"
+)
+
+func createMockPoliciesAPI() *MockElasticsearchAPI {
+	return &MockElasticsearchAPI{
+		Policies: map[string]string{
+			"logs-hot-warm": `{"phases": {"hot": {"actions": {"rollover": {"max_age": "7d"}}}}}`,
+			"logs-delete":   `{"phases": {"delete": {"min_age": "30d", "actions": {"delete": {}}}}}`,
+			"logs":          `{"phases": {"hot": {"actions": {}}}, "_meta": {"managed": true}}`,
+			"metrics":       `{"phases": {"hot": {"actions": {}}}}`,
+		},
+	}
+}
+
+func TestTransferLifecycleToFile(t *testing.T) {
+	client := &Client{API: createMockPoliciesAPI(), URL: "http://mock:9200"}
+
+	output := t.TempDir() + "/lifecycle.json"
+	config := Config{
+		Input:  "http://localhost:9200/logs*",
+		Output: output,
+		Type:   "lifecycle",
+	}
+
+	if err := transferLifecycle(client, config); err != nil {
+		t.Fatalf("transferLifecycle failed: %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var policies map[string]map[string]interface{}
+	if err := json.Unmarshal(content, &policies); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	if len(policies) != 2 || policies["logs-hot-warm"] == nil || policies["logs-delete"] == nil {
+		t.Errorf("Expected logs-hot-warm and logs-delete, got %v", policies)
+	}
+	for name, body := range policies {
+		if _, ok := body["policy"]; !ok || len(body) != 1 {
+			t.Errorf("Expected put lifecycle body for %s, got %v", name, body)
+		}
+	}
+}
+
+func TestCopyIndexDependencies(t *testing.T) {
+	sourceAPI := createMockPoliciesAPI()
+	sourceAPI.Pipelines = map[string]string{"enrich": `{"processors": []}`}
+	source := &Client{API: sourceAPI, URL: "http://source:9200"}
+
+	destAPI := &MockElasticsearchAPI{Policies: map[string]string{"logs-delete": `{"phases": {}}`}}
+	dest := &Client{API: destAPI, URL: "http://dest:9200"}
+
+	settings := []map[string]interface{}{
+		{"index": map[string]interface{}{"lifecycle": map[string]interface{}{"name": "logs-hot-warm"}, "default_pipeline": "enrich"}},
+		{"index": map[string]interface{}{"lifecycle": map[string]interface{}{"name": "logs-delete"}}},
+		{"index": map[string]interface{}{"lifecycle": map[string]interface{}{"name": "missing"}}},
+	}
+
+	config := Config{Output: "http://dest:9200", IncludePipelines: true}
+	if err := copyIndexDependencies(source, dest, settings, config); err != nil {
+		t.Fatalf("copyIndexDependencies failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(destAPI.PutPolicies, []string{"logs-hot-warm"}) {
+		t.Errorf("Expected only logs-hot-warm to be put, got %v", destAPI.PutPolicies)
+	}
+	if !reflect.DeepEqual(destAPI.PutPipelines, []string{"enrich"}) {
+		t.Errorf("Expected enrich pipeline to be put, got %v", destAPI.PutPipelines)
+	}
+}
+
+func TestPutPoliciesExisting(t *testing.T) {
+	policies := map[string]interface{}{
+		"logs-delete": map[string]interface{}{"policy": map[string]interface{}{}},
+		"metrics":     map[string]interface{}{"policy": map[string]interface{}{}},
+	}
+
+	api := &MockElasticsearchAPI{Policies: map[string]string{"metrics": `{"phases": {}}`}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := lifecycle.PutPolicies(client.API, policies, putOptions(Config{})); err == nil {
+		t.Fatal("Expected error for existing policy")
+	}
+	if len(api.PutPolicies) != 0 {
+		t.Errorf("Expected nothing to be put, got %v", api.PutPolicies)
+	}
+
+	if err := lifecycle.PutPolicies(client.API, policies, putOptions(Config{Existing: "overwrite"})); err != nil {
+		t.Fatalf("PutPolicies failed: %v", err)
+	}
+	if !reflect.DeepEqual(api.PutPolicies, []string{"logs-delete", "metrics"}) {
+		t.Errorf("Expected both policies to be put, got %v", api.PutPolicies)
+	}
+}
+
+func TestTransferISMPoliciesToFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_plugins/_ism/policies" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"policies": [
+			{"_id": "logs-delete", "_seq_no": 1, "_primary_term": 1, "policy": {"policy_id": "logs-delete", "default_state": "hot", "states": []}},
+			{"_id": "metrics", "_seq_no": 2, "_primary_term": 1, "policy": {"policy_id": "metrics", "default_state": "hot", "states": []}}
+		], "total_policies": 2}`))
+	}))
+	defer server.Close()
+
+	output := t.TempDir() + "/ism.json"
+	config := Config{
+		Input:      server.URL + "/logs-*",
+		Output:     output,
+		Type:       "lifecycle",
+		OpenSearch: true,
+	}
+
+	if err := transferLifecycle(nil, config); err != nil {
+		t.Fatalf("transferLifecycle failed: %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var policies map[string]interface{}
+	if err := json.Unmarshal(content, &policies); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"logs-delete": map[string]interface{}{"policy": map[string]interface{}{"default_state": "hot", "states": []interface{}{}}},
+	}
+	if !reflect.DeepEqual(policies, expected) {
+		t.Errorf("Expected policies %v, got %v", expected, policies)
+	}
+}
+
+func TestOpenSearchRequiresLifecycleType(t *testing.T) {
+	config := Config{Input: "http://localhost:9200/logs", Output: "out.json", Type: "data", OpenSearch: true}
+	if err := Run(config); err == nil {
+		t.Error("Expected error for --opensearch with --type=data")
+	}
+}
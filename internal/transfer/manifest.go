@@ -0,0 +1,117 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+)
+
+// matchAllQuery is the query of every document export, which reads whole
+// indices
+var matchAllQuery = json.RawMessage(`{"match_all":{}}`)
+
+// clusterInfo returns the name and version of a cluster
+func clusterInfo(client *Client) (string, string, error) {
+	res, err := client.API.Info()
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return "", "", fmt.Errorf("info failed: [%s] %s", res.Status(), string(body))
+	}
+
+	var info struct {
+		ClusterName string `json:"cluster_name"`
+		Version     struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return "", "", fmt.Errorf("failed to parse info response: %w", err)
+	}
+
+	return info.ClusterName, info.Version.Number, nil
+}
+
+// provenance returns the name and version of the source cluster for a
+// backup manifest. Failing to get them does not fail the backup, it is only
+// reported.
+func provenance(client *Client, config Config) (string, string) {
+	// The Elasticsearch client cannot talk to OpenSearch
+	if config.OpenSearch {
+		return "", ""
+	}
+
+	name, version, err := clusterInfo(client)
+	if err != nil {
+		logf(config, "Warning: failed to get cluster info for the backup manifest: %v\n", err)
+	}
+	return name, version
+}
+
+// newFileManifest returns the manifest of a backup of config.Type written to
+// config.Output, with its provenance filled in
+func newFileManifest(client *Client, config Config) (*backup.FileManifest, error) {
+	kind, err := compression(config.Output, config)
+	if err != nil {
+		return nil, err
+	}
+
+	name, version := provenance(client, config)
+
+	return &backup.FileManifest{
+		ToolVersion:    config.Version,
+		CreatedAt:      time.Now().UTC(),
+		Source:         redactURL(getBaseURL(config.Input)),
+		ClusterName:    name,
+		ClusterVersion: version,
+		Type:           config.Type,
+		Compression:    kind,
+	}, nil
+}
+
+// writeFileManifest records files, the checksums of the files making up a
+// backup next to config.Output, in manifest and writes it next to
+// config.Output. Nothing is recorded for standard output.
+func writeFileManifest(manifest *backup.FileManifest, files []backup.File, config Config) error {
+	if config.Output == backup.Stdio {
+		return nil
+	}
+
+	manifest.Files = append(manifest.Files, files...)
+
+	if err := backup.WriteFileManifest(backup.ManifestFile(config.Output), manifest); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileBackup writes a definitions backup, such as mappings or
+// templates, as JSON to the file config.Output, together with its manifest
+// recording the file and sidecars, the files already written next to it
+func writeFileBackup(client *Client, data interface{}, sidecars []backup.File, config Config) error {
+	file, err := writeToFile(config.Output, data, config)
+	if err != nil {
+		return err
+	}
+	if config.Output == backup.Stdio {
+		return nil
+	}
+
+	manifest, err := newFileManifest(client, config)
+	if err != nil {
+		return err
+	}
+	if expression := extractIndex(config.Input); expression != "" {
+		manifest.Indices = []string{expression}
+	}
+
+	return writeFileManifest(manifest, append([]backup.File{file}, sidecars...), config)
+}
@@ -0,0 +1,493 @@
+package transfer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/ingest"
+	"github.com/lilmonk/elasticdump/internal/lifecycle"
+	"github.com/lilmonk/elasticdump/internal/rename"
+	"github.com/lilmonk/elasticdump/internal/templates"
+	"github.com/schollz/progressbar/v3"
+)
+
+// migrationTarget is an index or data stream copied by Migrate
+type migrationTarget struct {
+	snapshot   indexSnapshot
+	dest       string
+	dataStream bool
+	exists     bool
+	skipped    bool
+
+	copied int
+	err    error
+}
+
+// Migrate copies a whole cluster to another one in dependency order:
+// templates, ingest pipelines, lifecycle policies, indices and data streams,
+// their documents, and finally the aliases. It ends with a report comparing
+// the document counts of every source and destination index.
+func Migrate(config Config) error {
+	if extractIndex(config.Input) != "" || isFile(config.Output) || extractIndex(config.Output) != "" {
+		return fmt.Errorf("migrate requires cluster URLs without path as input and output")
+	}
+
+	renamer, err := rename.New(config.OutputIndexTemplate, config.RenameMap)
+	if err != nil {
+		return err
+	}
+
+	sourceClient, err := createClient(getBaseURL(config.Input), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create source client: %w", err)
+	}
+
+	destClient, err := createClient(getBaseURL(config.Output), config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return migrate(sourceClient, destClient, renamer, config)
+}
+
+// migrate runs the migration between two clients
+func migrate(sourceClient, destClient *Client, renamer *rename.Renamer, config Config) error {
+	switch config.Existing {
+	case "", "fail", "skip", "overwrite":
+	default:
+		return fmt.Errorf("unsupported existing policy: %s", config.Existing)
+	}
+
+	// Decide what to do with every definition and index before changing
+	// anything
+	definitions, err := getMigrationDefinitions(sourceClient, config)
+	if err != nil {
+		return err
+	}
+	if err := checkDefinitions(destClient, definitions, config); err != nil {
+		return err
+	}
+	targets, err := planMigration(sourceClient, destClient, renamer, config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Step 1/6: templates")
+	if err := templates.Put(destClient.API, definitions.templates, putOptions(config)); err != nil {
+		return err
+	}
+
+	fmt.Println("Step 2/6: ingest pipelines")
+	if err := ingest.PutPipelines(destClient.API, definitions.pipelines, putOptions(config)); err != nil {
+		return err
+	}
+
+	fmt.Println("Step 3/6: lifecycle policies")
+	if err := lifecycle.PutPolicies(destClient.API, definitions.policies, putOptions(config)); err != nil {
+		return err
+	}
+
+	fmt.Println("Step 4/6: indices")
+	if err := createTargets(sourceClient, destClient, targets, config); err != nil {
+		return err
+	}
+
+	fmt.Println("Step 5/6: data")
+	copyTargets(sourceClient, destClient, targets, config)
+
+	fmt.Println("Step 6/6: aliases")
+	var actions []map[string]interface{}
+	for _, target := range targets {
+		if !target.skipped {
+			actions = append(actions, indexdef.AliasActions(target.snapshot.aliases, target.dest)...)
+		}
+	}
+	if len(actions) > 0 {
+		if err := cluster.UpdateAliases(destClient.API, actions); err != nil {
+			return fmt.Errorf("failed to apply aliases: %w", err)
+		}
+	}
+	fmt.Printf("Applied %d aliases to %s\n", len(actions), config.Output)
+
+	return verifyMigration(destClient, targets, config)
+}
+
+// migrationDefinitions are the templates, ingest pipelines and lifecycle
+// policies copied by Migrate before the indices
+type migrationDefinitions struct {
+	templates *templates.Set
+	pipelines map[string]interface{}
+	policies  map[string]interface{}
+}
+
+// getMigrationDefinitions reads the templates, ingest pipelines and lifecycle
+// policies of the source, leaving out system ones unless IncludeSystem is set
+func getMigrationDefinitions(sourceClient *Client, config Config) (*migrationDefinitions, error) {
+	set, err := getTemplates(sourceClient, []string{"*"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get templates: %w", err)
+	}
+	if !config.IncludeSystem {
+		set.RemoveSystem()
+	}
+
+	pipelines, err := ingest.GetPipelines(sourceClient.API, "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest pipelines: %w", err)
+	}
+	for id, pipeline := range pipelines {
+		if !config.IncludeSystem && ingest.IsSystem(id, pipeline) {
+			delete(pipelines, id)
+		}
+	}
+
+	policies, err := lifecycle.GetPolicies(sourceClient.API)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lifecycle policies: %w", err)
+	}
+	for name, policy := range policies {
+		if !config.IncludeSystem && lifecycle.IsSystem(name, policy) {
+			delete(policies, name)
+		}
+	}
+
+	return &migrationDefinitions{templates: set, pipelines: pipelines, policies: policies}, nil
+}
+
+// checkDefinitions returns an error if a template, ingest pipeline or
+// lifecycle policy already exists on the destination with the "fail" existing
+// policy. The other policies never fail, so nothing is checked for them.
+func checkDefinitions(destClient *Client, definitions *migrationDefinitions, config Config) error {
+	if config.Existing == "skip" || config.Existing == "overwrite" {
+		return nil
+	}
+
+	if _, err := templates.Select(destClient.API, definitions.templates, putOptions(config)); err != nil {
+		return err
+	}
+	if _, err := ingest.SelectPipelines(destClient.API, definitions.pipelines, putOptions(config)); err != nil {
+		return err
+	}
+	if _, err := lifecycle.SelectPolicies(destClient.API, definitions.policies, putOptions(config)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// planMigration selects the indices and data streams to migrate and checks
+// their destinations. With the "fail" existing policy nothing is migrated if
+// any destination exists.
+func planMigration(sourceClient, destClient *Client, renamer *rename.Renamer, config Config) ([]*migrationTarget, error) {
+	var opts []func(*esapi.IndicesResolveIndexRequest)
+	if config.IncludeSystem {
+		opts = append(opts, func(r *esapi.IndicesResolveIndexRequest) {
+			r.ExpandWildcards = "open,hidden"
+		})
+	}
+
+	result, err := resolveExpression(sourceClient, "*", opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indices: %w", err)
+	}
+
+	streams := make(map[string]bool)
+	for _, stream := range result.DataStreams {
+		streams[stream.Name] = true
+	}
+
+	var indices []string
+	var streamNames []string
+	for _, index := range result.Indices {
+		if index.DataStream == "" && selectedForMigration(index.Name, config) {
+			indices = append(indices, index.Name)
+		}
+	}
+	for name := range streams {
+		if selectedForMigration(name, config) {
+			streamNames = append(streamNames, name)
+		}
+	}
+	sort.Strings(indices)
+	sort.Strings(streamNames)
+
+	if len(indices)+len(streamNames) == 0 {
+		return nil, fmt.Errorf("no indices selected for migration")
+	}
+
+	var targets []*migrationTarget
+	if len(indices) > 0 {
+		snapshots, err := getSnapshots(sourceClient, indices, config)
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range snapshots {
+			targets = append(targets, &migrationTarget{snapshot: snapshot})
+		}
+	}
+	for _, name := range streamNames {
+		count, err := getDocumentCount(sourceClient, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document count of %s: %w", name, err)
+		}
+		targets = append(targets, &migrationTarget{
+			snapshot:   indexSnapshot{name: name, documents: count},
+			dataStream: true,
+		})
+	}
+
+	sources := make(map[string]string)
+	var conflicts []string
+	for _, target := range targets {
+		target.dest = destinationIndex(config, renamer, target.snapshot.name)
+
+		if other, ok := sources[target.dest]; ok {
+			return nil, fmt.Errorf("indices %s and %s would both be migrated into %s", other, target.snapshot.name, target.dest)
+		}
+		sources[target.dest] = target.snapshot.name
+
+		if target.dataStream {
+			target.exists, err = cluster.IsDataStream(destClient.API, target.dest)
+		} else {
+			target.exists, err = cluster.IndexExists(destClient.API, target.dest)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if target.exists {
+			switch config.Existing {
+			case "skip":
+				target.skipped = true
+			case "overwrite":
+			default:
+				conflicts = append(conflicts, target.dest)
+			}
+		}
+
+		// A data stream is created from its index template, which must
+		// match a renamed destination
+		if target.dataStream && !target.skipped && target.dest != target.snapshot.name {
+			stream, err := getDataStreamBackup(sourceClient, target.snapshot.name)
+			if err != nil {
+				return nil, err
+			}
+			if err := stream.CheckDestination(target.dest); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("indices already exist: %s (use --existing=skip or --existing=overwrite)",
+			strings.Join(conflicts, ", "))
+	}
+
+	return targets, nil
+}
+
+// selectedForMigration applies the include, exclude and system index options
+// to an index or data stream name
+func selectedForMigration(name string, config Config) bool {
+	if !config.IncludeSystem && strings.HasPrefix(name, ".") {
+		return false
+	}
+	if len(config.Include) > 0 && !cluster.MatchesAny(name, config.Include) {
+		return false
+	}
+	return !cluster.MatchesAny(name, config.Exclude)
+}
+
+// createTargets creates the destination indices from the source settings and
+// mappings, and the destination data streams from their index templates.
+// Existing ones are deleted first when they are overwritten.
+func createTargets(sourceClient, destClient *Client, targets []*migrationTarget, config Config) error {
+	created := 0
+	for _, target := range targets {
+		if target.skipped {
+			fmt.Printf("Skipping existing index %s\n", target.dest)
+			continue
+		}
+
+		if target.dataStream {
+			if target.exists {
+				if err := deleteDataStream(destClient, target.dest); err != nil {
+					return err
+				}
+			}
+			if err := ensureDataStream(sourceClient, destClient, target.snapshot.name, target.dest, config); err != nil {
+				return err
+			}
+		} else {
+			if target.exists {
+				if err := deleteIndex(destClient, target.dest); err != nil {
+					return err
+				}
+			}
+			if err := cluster.CreateIndex(destClient.API, target.dest, target.snapshot.create); err != nil {
+				return err
+			}
+		}
+
+		if config.Verbose {
+			fmt.Printf("Created %s\n", target.dest)
+		}
+		created++
+	}
+
+	fmt.Printf("Created %d indices and data streams on %s\n", created, config.Output)
+
+	return nil
+}
+
+// copyTargets copies the documents of the created targets, config.Parallel
+// indices at a time, with a single progress bar over all documents. Errors
+// are recorded on the targets and reported by the verification.
+func copyTargets(sourceClient, destClient *Client, targets []*migrationTarget, config Config) {
+	var copying []indexSnapshot
+	for _, target := range targets {
+		if !target.skipped {
+			copying = append(copying, target.snapshot)
+		}
+	}
+
+	var bar *progressbar.ProgressBar
+	if len(copying) > 0 {
+		bar = newTotalBar(copying, "Migrating documents", config)
+	}
+
+	sem := make(chan struct{}, max(config.Parallel, 1))
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		if target.skipped || target.snapshot.documents == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(target *migrationTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			indexConfig := config
+			indexConfig.Limit = target.snapshot.documents
+			target.copied, target.err = copyDocuments(sourceClient, destClient, target.snapshot.name, target.dest, bar, indexConfig)
+		}(target)
+	}
+	wg.Wait()
+
+	if bar != nil {
+		bar.Finish()
+	}
+}
+
+// verifyMigration compares the document count of every migrated index with
+// the count of its source at the start of the migration, prints a report and
+// fails if any of them differs
+func verifyMigration(destClient *Client, targets []*migrationTarget, config Config) error {
+	var dests []string
+	for _, target := range targets {
+		if !target.skipped {
+			dests = append(dests, target.dest)
+		}
+	}
+	if len(dests) > 0 {
+		if err := refreshIndices(destClient, dests); err != nil {
+			return fmt.Errorf("failed to refresh destination indices: %w", err)
+		}
+	}
+
+	fmt.Println("Verification:")
+
+	failed := 0
+	total := 0
+	for _, target := range targets {
+		if target.skipped {
+			fmt.Printf("  %s -> %s: skipped, already exists\n", target.snapshot.name, target.dest)
+			continue
+		}
+
+		if target.err != nil {
+			fmt.Printf("  %s -> %s: FAILED, %v\n", target.snapshot.name, target.dest, target.err)
+			failed++
+			continue
+		}
+
+		count, err := getDocumentCount(destClient, target.dest)
+		if err != nil {
+			fmt.Printf("  %s -> %s: FAILED, %v\n", target.snapshot.name, target.dest, err)
+			failed++
+			continue
+		}
+		total += count
+
+		status := "OK"
+		if count != target.snapshot.documents {
+			status = "MISMATCH"
+			failed++
+		}
+		fmt.Printf("  %s -> %s: %d source, %d destination documents, %s\n",
+			target.snapshot.name, target.dest, target.snapshot.documents, count, status)
+	}
+
+	fmt.Printf("Migrated %d indices and %d documents to %s\n", len(dests), total, config.Output)
+
+	if failed > 0 {
+		return fmt.Errorf("verification failed for %d of %d indices", failed, len(dests))
+	}
+
+	return nil
+}
+
+// refreshIndices refreshes indices so that their documents are counted
+func refreshIndices(client *Client, indices []string) error {
+	res, err := client.API.IndicesRefresh(func(r *esapi.IndicesRefreshRequest) {
+		r.Index = indices
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("refresh failed: %s", res.String())
+	}
+
+	return nil
+}
+
+// deleteIndex deletes an index that is being overwritten
+func deleteIndex(client *Client, index string) error {
+	res, err := client.API.IndicesDelete([]string{index})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("delete index %s failed: %s", index, res.String())
+	}
+
+	return nil
+}
+
+// deleteDataStream deletes a data stream that is being overwritten, along
+// with its backing indices
+func deleteDataStream(client *Client, name string) error {
+	res, err := client.API.IndicesDeleteDataStream([]string{name})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("delete data stream %s failed: %s", name, res.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package transfer
+
+import (
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// createMockMigrationSource returns a source cluster with the indices
+// test-index, logs-a and tmp-x, and the data stream metrics-app
+func createMockMigrationSource() *MockElasticsearchAPI {
+	return &MockElasticsearchAPI{
+		SettingsResponse: createMockFullSettingsResponse(),
+		ResolveResponse: &esapi.Response{
+			StatusCode: 200,
+			Body: io.NopCloser(strings.NewReader(`{
+				"indices": [
+					{"name": "test-index"},
+					{"name": "logs-a"},
+					{"name": "tmp-x"},
+					{"name": ".ds-metrics-app-000001", "data_stream": "metrics-app"}
+				],
+				"aliases": [],
+				"data_streams": [{"name": "metrics-app", "backing_indices": [".ds-metrics-app-000001"], "timestamp_field": "@timestamp"}]
+			}`)),
+		},
+		Counts:      map[string]int{"test-index": 1, "logs-a": 1, "tmp-x": 1, "metrics-app": 1},
+		Sources:     map[string]string{"metrics-app": `{"@timestamp": "2024-01-01T00:00:00Z"}`},
+		DataStreams: map[string]string{"metrics-app": "metrics"},
+		IndexTemplates: map[string]string{
+			"metrics": `{"index_patterns": ["metrics-*"], "data_stream": {}}`,
+		},
+		Pipelines: map[string]string{
+			"enrich": `{"processors": []}`,
+		},
+		Policies: map[string]string{
+			"metrics-policy": `{"phases": {}}`,
+		},
+	}
+}
+
+func createMockMigrationDest() *MockElasticsearchAPI {
+	return &MockElasticsearchAPI{
+		Counts: map[string]int{"test-index": 1, "logs-a": 1, "metrics-app": 1},
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	source := &Client{API: createMockMigrationSource(), URL: "http://source:9200"}
+	destAPI := createMockMigrationDest()
+	dest := &Client{API: destAPI, URL: "http://dest:9200"}
+
+	config := Config{
+		Output:      "http://dest:9200",
+		Concurrency: 2,
+		Parallel:    2,
+		ScrollSize:  10,
+		Exclude:     []string{"tmp-*"},
+		Verbose:     true,
+	}
+
+	if err := migrate(source, dest, nil, config); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	if len(destAPI.PutTemplates) == 0 || destAPI.PutTemplates[0] != "index/metrics" {
+		t.Errorf("Expected the index template to be put first, got %v", destAPI.PutTemplates)
+	}
+	if !reflect.DeepEqual(destAPI.PutPipelines, []string{"enrich"}) {
+		t.Errorf("Expected the pipeline to be put, got %v", destAPI.PutPipelines)
+	}
+	if !reflect.DeepEqual(destAPI.PutPolicies, []string{"metrics-policy"}) {
+		t.Errorf("Expected the lifecycle policy to be put, got %v", destAPI.PutPolicies)
+	}
+
+	var created []string
+	for index := range destAPI.CreatedBodies {
+		created = append(created, index)
+	}
+	sort.Strings(created)
+	if !reflect.DeepEqual(created, []string{"logs-a", "test-index"}) {
+		t.Errorf("Expected logs-a and test-index to be created, got %v", created)
+	}
+	if !reflect.DeepEqual(destAPI.CreatedDataStreams, []string{"metrics-app"}) {
+		t.Errorf("Expected metrics-app to be created, got %v", destAPI.CreatedDataStreams)
+	}
+
+	if destAPI.OpTypes["metrics-app"] != "create" {
+		t.Errorf("Expected op_type create for the data stream, got %q", destAPI.OpTypes["metrics-app"])
+	}
+	if _, ok := destAPI.OpTypes["tmp-x"]; ok {
+		t.Error("Expected excluded index tmp-x not to be copied")
+	}
+	if len(destAPI.AliasRequests) != 1 {
+		t.Errorf("Expected one alias request, got %d", len(destAPI.AliasRequests))
+	}
+	if len(destAPI.Refreshed) != 3 {
+		t.Errorf("Expected the 3 destinations to be refreshed, got %v", destAPI.Refreshed)
+	}
+}
+
+func TestMigrateVerificationMismatch(t *testing.T) {
+	source := &Client{API: createMockMigrationSource(), URL: "http://source:9200"}
+	destAPI := createMockMigrationDest()
+	destAPI.Counts["logs-a"] = 0
+	dest := &Client{API: destAPI, URL: "http://dest:9200"}
+
+	err := migrate(source, dest, nil, Config{Concurrency: 1, ScrollSize: 10, Exclude: []string{"tmp-*"}, Verbose: true})
+	if err == nil || !strings.Contains(err.Error(), "verification failed for 1 of 3") {
+		t.Errorf("Expected verification failure, got %v", err)
+	}
+}
+
+func TestMigrateExisting(t *testing.T) {
+	config := Config{Concurrency: 1, ScrollSize: 10, Include: []string{"logs-*", "test-*"}, Verbose: true}
+
+	// fail: nothing is changed
+	destAPI := createMockMigrationDest()
+	destAPI.ExistingIndices = map[string]bool{"logs-a": true}
+	err := migrate(&Client{API: createMockMigrationSource()}, &Client{API: destAPI}, nil, config)
+	if err == nil || !strings.Contains(err.Error(), "logs-a") {
+		t.Fatalf("Expected logs-a conflict, got %v", err)
+	}
+	if len(destAPI.PutTemplates)+len(destAPI.CreatedBodies) != 0 {
+		t.Errorf("Expected nothing to change, got %v and %v", destAPI.PutTemplates, destAPI.CreatedBodies)
+	}
+
+	// skip: the existing index is left alone
+	destAPI = createMockMigrationDest()
+	destAPI.ExistingIndices = map[string]bool{"logs-a": true}
+	config.Existing = "skip"
+	if err := migrate(&Client{API: createMockMigrationSource()}, &Client{API: destAPI}, nil, config); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if _, ok := destAPI.CreatedBodies["logs-a"]; ok {
+		t.Error("Expected logs-a to be skipped")
+	}
+	if _, ok := destAPI.CreatedBodies["test-index"]; !ok {
+		t.Error("Expected test-index to be created")
+	}
+
+	// overwrite: the existing index is recreated
+	destAPI = createMockMigrationDest()
+	destAPI.ExistingIndices = map[string]bool{"logs-a": true}
+	config.Existing = "overwrite"
+	if err := migrate(&Client{API: createMockMigrationSource()}, &Client{API: destAPI}, nil, config); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if !reflect.DeepEqual(destAPI.DeletedIndices, []string{"logs-a"}) {
+		t.Errorf("Expected logs-a to be deleted, got %v", destAPI.DeletedIndices)
+	}
+	if _, ok := destAPI.CreatedBodies["logs-a"]; !ok {
+		t.Error("Expected logs-a to be recreated")
+	}
+}
+
+func TestMigrateExistingDefinitions(t *testing.T) {
+	config := Config{Concurrency: 1, ScrollSize: 10, Include: []string{"logs-*", "test-*"}}
+
+	tests := []struct {
+		name    string
+		prepare func(*MockElasticsearchAPI)
+		want    string
+	}{
+		{"template", func(api *MockElasticsearchAPI) { api.ExistingTemplates = map[string]bool{"index/metrics": true} }, "index template metrics"},
+		{"pipeline", func(api *MockElasticsearchAPI) { api.Pipelines = map[string]string{"enrich": `{}`} }, "enrich"},
+		{"policy", func(api *MockElasticsearchAPI) { api.Policies = map[string]string{"metrics-policy": `{}`} }, "metrics-policy"},
+	}
+
+	// With the fail policy an existing definition stops the migration
+	// before anything is put
+	for _, tt := range tests {
+		destAPI := createMockMigrationDest()
+		tt.prepare(destAPI)
+
+		err := migrate(&Client{API: createMockMigrationSource()}, &Client{API: destAPI}, nil, config)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %s conflict, got %v", tt.name, tt.want, err)
+		}
+		if len(destAPI.PutTemplates)+len(destAPI.PutPipelines)+len(destAPI.PutPolicies)+len(destAPI.CreatedBodies) != 0 {
+			t.Errorf("%s: expected nothing to change", tt.name)
+		}
+	}
+}
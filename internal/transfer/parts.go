@@ -0,0 +1,155 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+)
+
+// documentSink receives the documents of an export
+type documentSink interface {
+	Write(doc Document) error
+	addDataStream(index string)
+}
+
+// partWriter writes documents to numbered part files, starting a new part
+// once the current one holds config.FileDocs documents or config.FileSize
+// bytes, and records the parts for the manifest. The size of compressed
+// parts is bounded before compression, since the compressor buffers what is
+// written and the compressed size is only known once the part is closed.
+type partWriter struct {
+	path   string
+	config Config
+	open   func(io.Writer) (*documentWriter, error)
+
+	file        *outputFile
+	writer      *documentWriter
+	documents   int
+	dataStreams []string
+
+	parts backup.Parts
+
+	// files are the checksums of the parts and, once closed, of the parts
+	// manifest
+	files []backup.File
+}
+
+// newPartWriter returns a partWriter for parts of path opening their
+// documentWriters with open
+func newPartWriter(path string, open func(io.Writer) (*documentWriter, error), config Config) *partWriter {
+	return &partWriter{
+		path:   path,
+		config: config,
+		open:   open,
+		parts:  backup.Parts{Data: filepath.Base(path), Format: config.Format},
+	}
+}
+
+// addDataStream records that the documents of index are written to a data
+// stream, in the current part and all following ones
+func (w *partWriter) addDataStream(index string) {
+	w.dataStreams = append(w.dataStreams, index)
+	if w.writer != nil {
+		w.writer.addDataStream(index)
+	}
+}
+
+// Write writes one document, starting a new part first if the current one
+// is full
+func (w *partWriter) Write(doc Document) error {
+	if w.writer == nil || w.full() {
+		if err := w.closePart(); err != nil {
+			return err
+		}
+		if err := w.openPart(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.writer.Write(doc); err != nil {
+		return err
+	}
+	w.documents++
+
+	return nil
+}
+
+// full reports whether the current part has reached one of its bounds
+func (w *partWriter) full() bool {
+	if w.config.FileDocs > 0 && w.documents >= w.config.FileDocs {
+		return true
+	}
+	return w.config.FileSize > 0 && w.file.Written() >= w.config.FileSize
+}
+
+// openPart creates the next part file
+func (w *partWriter) openPart() error {
+	name := backup.PartName(w.path, len(w.parts.Parts)+1)
+
+	file, err := createFile(name, w.config)
+	if err != nil {
+		return fmt.Errorf("failed to create part file: %w", err)
+	}
+
+	writer, err := w.open(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	for _, index := range w.dataStreams {
+		writer.addDataStream(index)
+	}
+
+	w.file, w.writer, w.documents = file, writer, 0
+	w.parts.Parts = append(w.parts.Parts, backup.Part{File: filepath.Base(name)})
+
+	return nil
+}
+
+// closePart completes the current part, if any, and records its size and
+// checksum
+func (w *partWriter) closePart() error {
+	if w.writer == nil {
+		return nil
+	}
+
+	if err := w.writer.Close(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to write part file: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to write part file: %w", err)
+	}
+
+	part := &w.parts.Parts[len(w.parts.Parts)-1]
+	part.Documents = w.documents
+	part.Bytes = w.file.Size()
+	w.parts.Documents += w.documents
+	w.files = append(w.files, w.file.Sum())
+	w.writer = nil
+
+	return nil
+}
+
+// Close completes the last part and writes the parts manifest. An export
+// without documents still gets one, empty, part.
+func (w *partWriter) Close() error {
+	if len(w.parts.Parts) == 0 {
+		if err := w.openPart(); err != nil {
+			return err
+		}
+	}
+	if err := w.closePart(); err != nil {
+		return err
+	}
+
+	sum, err := backup.WriteParts(backup.PartsFile(w.path), &w.parts)
+	if err != nil {
+		return fmt.Errorf("failed to write parts manifest: %w", err)
+	}
+	w.files = append(w.files, sum)
+
+	return nil
+}
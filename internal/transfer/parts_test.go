@@ -0,0 +1,176 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+)
+
+// writeParts writes count documents through a partWriter for path
+func writeParts(t *testing.T, path string, count int, config Config) *backup.Parts {
+	open := func(w io.Writer) (*documentWriter, error) {
+		return newDocumentWriter(w, config.Format, false)
+	}
+
+	writer := newPartWriter(path, open, config)
+	for i := 0; i < count; i++ {
+		doc := Document{Index: "logs", ID: fmt.Sprintf("%d", i), Source: map[string]interface{}{"n": i}}
+		if err := writer.Write(doc); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	return readParts(t, backup.PartsFile(path))
+}
+
+// readParts reads the parts manifest at path
+func readParts(t *testing.T, path string) *backup.Parts {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read parts manifest: %v", err)
+	}
+	parts, err := backup.ParseParts(path, data)
+	if err != nil {
+		t.Fatalf("ParseParts failed: %v", err)
+	}
+	return parts
+}
+
+func TestPartWriterFileDocs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.ndjson")
+
+	parts := writeParts(t, path, 5, Config{Format: "ndjson", FileDocs: 2})
+
+	if parts.Data != "logs.ndjson" || parts.Format != "ndjson" || parts.Documents != 5 {
+		t.Errorf("Unexpected manifest: %+v", parts)
+	}
+
+	want := []backup.Part{
+		{File: "logs.part-0001.ndjson", Documents: 2},
+		{File: "logs.part-0002.ndjson", Documents: 2},
+		{File: "logs.part-0003.ndjson", Documents: 1},
+	}
+	if len(parts.Parts) != len(want) {
+		t.Fatalf("Expected %d parts, got %+v", len(want), parts.Parts)
+	}
+	for i, part := range parts.Parts {
+		if part.File != want[i].File || part.Documents != want[i].Documents {
+			t.Errorf("Part %d = %+v, want %+v", i, part, want[i])
+		}
+
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), part.File))
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		if int64(len(data)) != part.Bytes {
+			t.Errorf("Part %s has %d bytes, manifest says %d", part.File, len(data), part.Bytes)
+		}
+		if lines := strings.Count(string(data), "\n"); lines != part.Documents {
+			t.Errorf("Part %s has %d lines, want %d", part.File, lines, part.Documents)
+		}
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no unsplit data file, got %v", err)
+	}
+}
+
+func TestPartWriterFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.json")
+
+	// Every document is about 40 bytes, so a part holds three of them
+	parts := writeParts(t, path, 7, Config{Format: "json", FileSize: 100})
+
+	if len(parts.Parts) != 3 {
+		t.Fatalf("Expected 3 parts, got %+v", parts.Parts)
+	}
+
+	// Every part is a complete JSON array
+	for _, part := range parts.Parts {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), part.File))
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		var docs []Document
+		if err := json.Unmarshal(data, &docs); err != nil {
+			t.Errorf("Part %s is not a JSON array: %v", part.File, err)
+		}
+		if len(docs) != part.Documents {
+			t.Errorf("Part %s has %d documents, manifest says %d", part.File, len(docs), part.Documents)
+		}
+	}
+}
+
+func TestPartWriterFileSizeCompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.json.gz")
+
+	// The size is bounded before compression, so the parts split as they
+	// would uncompressed although the compressor has not written anything
+	// out yet
+	parts := writeParts(t, path, 7, Config{Format: "json", FileSize: 100})
+
+	if len(parts.Parts) != 3 {
+		t.Fatalf("Expected 3 parts, got %+v", parts.Parts)
+	}
+}
+
+func TestPartWriterEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.json")
+
+	parts := writeParts(t, path, 0, Config{Format: "json", FileDocs: 10})
+
+	if len(parts.Parts) != 1 || parts.Parts[0].Documents != 0 {
+		t.Fatalf("Expected one empty part, got %+v", parts.Parts)
+	}
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(path), parts.Parts[0].File))
+	if err != nil {
+		t.Fatalf("Failed to read part: %v", err)
+	}
+	if string(data) != "[]\n" {
+		t.Errorf("Expected an empty array, got %q", data)
+	}
+}
+
+func TestExportToFileParts(t *testing.T) {
+	client := &Client{API: &MockElasticsearchAPI{}, URL: "http://mock:9200"}
+	output := filepath.Join(t.TempDir(), "data.ndjson.gz")
+
+	config := Config{Output: output, Format: "ndjson", ScrollSize: 10, Verbose: true, FileDocs: 1}
+	if err := exportToFile(client, []dataSource{{Name: "test-index"}}, config); err != nil {
+		t.Fatalf("exportToFile failed: %v", err)
+	}
+
+	parts := readParts(t, backup.PartsFile(output))
+	if len(parts.Parts) != 1 || parts.Parts[0].File != "data.part-0001.ndjson.gz" {
+		t.Fatalf("Unexpected parts: %+v", parts.Parts)
+	}
+
+	// Parts are compressed like the file they were split from, and the
+	// mapping stays next to the manifest under the unsplit name
+	part := filepath.Join(filepath.Dir(output), parts.Parts[0].File)
+	if got := readGzipFile(t, part); !strings.Contains(got, `"_index":"test-index"`) {
+		t.Errorf("Expected the compressed documents, got %q", got)
+	}
+	if _, err := os.Stat(backup.MappingFile(output)); err != nil {
+		t.Errorf("Expected the mapping file: %v", err)
+	}
+}
+
+func TestRunRejectsPartsOutsideDataExports(t *testing.T) {
+	config := Config{Input: "http://localhost:9200/logs", Output: "out", Type: "all", FileDocs: 10}
+	if err := Run(config); err == nil || !strings.Contains(err.Error(), "only supported for data exports") {
+		t.Errorf("Expected parts to be rejected with --type=all, got %v", err)
+	}
+}
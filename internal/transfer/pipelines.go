@@ -0,0 +1,214 @@
+package transfer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/ingest"
+)
+
+// transferPipelines copies the ingest pipelines whose ids match the input URL
+// path (all pipelines for a bare cluster URL), together with the pipelines
+// they call through pipeline processors. A file receives them in the format
+// of the get pipeline API.
+func transferPipelines(sourceClient *Client, config Config) error {
+	pattern := extractIndex(config.Input)
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	pipelines, err := ingest.GetPipelines(sourceClient.API, pattern)
+	if err != nil {
+		return fmt.Errorf("failed to get ingest pipelines: %w", err)
+	}
+
+	if !config.IncludeSystem {
+		for id, pipeline := range pipelines {
+			if ingest.IsSystem(id, pipeline) {
+				delete(pipelines, id)
+			}
+		}
+	}
+
+	if err := addNestedPipelines(sourceClient, pipelines, config); err != nil {
+		return err
+	}
+
+	if len(pipelines) == 0 {
+		return fmt.Errorf("no ingest pipelines match %s", pattern)
+	}
+
+	if isFile(config.Output) {
+		return writeFileBackup(sourceClient, pipelines, nil, config)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("pipelines type requires a cluster URL without path as output")
+	}
+
+	destURL := getBaseURL(config.Output)
+	destClient, err := createClient(destURL, config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return ingest.PutPipelines(destClient.API, pipelines, putOptions(config))
+}
+
+// copyReferencedPipelines copies the ingest pipelines that the settings of
+// transferred indices refer to. Unless IncludePipelines is set, it only
+// reports them. Pipelines that already exist on the destination are kept
+// unless Existing is "overwrite".
+func copyReferencedPipelines(sourceClient, destClient *Client, names []string, config Config) error {
+	pipelines, err := referencedPipelines(sourceClient, names, config)
+	if err != nil || len(pipelines) == 0 {
+		return err
+	}
+
+	pipelineOptions := putOptions(config)
+	if pipelineOptions.Existing != "overwrite" {
+		pipelineOptions.Existing = "skip"
+	}
+
+	return ingest.PutPipelines(destClient.API, pipelines, pipelineOptions)
+}
+
+// writeReferencedPipelines writes the ingest pipelines that the settings of
+// backed up indices refer to to path. Unless IncludePipelines is set, it only
+// reports them. It returns the checksum of the file, if written.
+func writeReferencedPipelines(sourceClient *Client, path string, settings []map[string]interface{}, config Config) ([]backup.File, error) {
+	pipelines, err := referencedPipelines(sourceClient, settingsPipelines(settings...), config)
+	if err != nil || len(pipelines) == 0 {
+		return nil, err
+	}
+
+	file, err := writeToFile(path, pipelines, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write ingest pipelines file: %w", err)
+	}
+
+	return []backup.File{file}, nil
+}
+
+// writePipelinesSidecar writes the ingest pipelines that the settings of
+// the indices backed up to config.Output refer to next to it, unless the
+// output is standard output, and returns the checksums of the files written
+func writePipelinesSidecar(sourceClient *Client, settings []map[string]interface{}, config Config) ([]backup.File, error) {
+	if config.Output == backup.Stdio {
+		return nil, nil
+	}
+
+	return writeReferencedPipelines(sourceClient, backup.PipelinesFile(config.Output), settings, config)
+}
+
+// referencedPipelines returns the named ingest pipelines together with the
+// pipelines they call. Unless IncludePipelines is set, it only reports the
+// names and returns no pipelines.
+func referencedPipelines(sourceClient *Client, names []string, config Config) (map[string]interface{}, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	if !config.IncludePipelines {
+		logf(config, "Index settings reference ingest pipelines %s; use --includePipelines to include them\n",
+			strings.Join(names, ", "))
+		return nil, nil
+	}
+
+	pipelines, err := ingest.GetPipelines(sourceClient.API, strings.Join(names, ","))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest pipelines: %w", err)
+	}
+
+	if err := addNestedPipelines(sourceClient, pipelines, config); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if _, ok := pipelines[name]; !ok {
+			logf(config, "Warning: ingest pipeline %s does not exist on the source\n", name)
+		}
+	}
+
+	return pipelines, nil
+}
+
+// settingsPipelines returns the sorted, deduplicated ingest pipelines
+// referenced by per-index settings
+func settingsPipelines(settings ...map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, s := range settings {
+		for _, name := range indexdef.Pipelines(s) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// addNestedPipelines adds the pipelines called through pipeline processors
+// of the given pipelines, recursively
+func addNestedPipelines(client *Client, pipelines map[string]interface{}, config Config) error {
+	unavailable := make(map[string]bool)
+
+	for {
+		var missing []string
+		for _, pipeline := range pipelines {
+			for _, name := range nestedPipelines(pipeline) {
+				if _, ok := pipelines[name]; !ok && !unavailable[name] {
+					missing = append(missing, name)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+
+		found, err := ingest.GetPipelines(client.API, strings.Join(missing, ","))
+		if err != nil {
+			return fmt.Errorf("failed to get nested ingest pipelines: %w", err)
+		}
+
+		for _, name := range missing {
+			if pipeline, ok := found[name]; ok {
+				pipelines[name] = pipeline
+			} else if !unavailable[name] {
+				unavailable[name] = true
+				logf(config, "Warning: ingest pipeline %s is called but does not exist on the source\n", name)
+			}
+		}
+	}
+}
+
+// nestedPipelines returns the names of the pipelines a pipeline definition
+// calls through pipeline processors, including those nested in on_failure
+// and foreach processors. Templated names cannot be resolved and are skipped.
+func nestedPipelines(definition interface{}) []string {
+	var names []string
+
+	switch v := definition.(type) {
+	case map[string]interface{}:
+		if processor, ok := v["pipeline"].(map[string]interface{}); ok {
+			if name, ok := processor["name"].(string); ok && !strings.Contains(name, "{{") {
+				names = append(names, name)
+			}
+		}
+		for _, child := range v {
+			names = append(names, nestedPipelines(child)...)
+		}
+	case []interface{}:
+		for _, child := range v {
+			names = append(names, nestedPipelines(child)...)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,174 @@
+package transfer
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/ingest"
+)
+
+func createMockPipelinesAPI() *MockElasticsearchAPI {
+	return &MockElasticsearchAPI{
+		Pipelines: map[string]string{
+			"logs-enrich": `{"processors": [{"set": {"field": "env", "value": "prod"}}, {"pipeline": {"name": "geoip"}}]}`,
+			"geoip":       `{"processors": [{"geoip": {"field": "ip"}}], "on_failure": [{"pipeline": {"name": "failures"}}]}`,
+			"failures":    `{"processors": [{"set": {"field": "error", "value": true}}]}`,
+			"logs-audit":  `{"processors": [{"pipeline": {"name": "{{ target }}"}}]}`,
+			"managed":     `{"processors": [], "_meta": {"managed": true}}`,
+		},
+	}
+}
+
+func TestNestedPipelines(t *testing.T) {
+	var pipeline interface{}
+	body := `{"processors": [
+		{"foreach": {"field": "items", "processor": {"pipeline": {"name": "item"}}}},
+		{"pipeline": {"name": "{{ dynamic }}"}}
+	], "on_failure": [{"pipeline": {"name": "failures"}}]}`
+	if err := json.Unmarshal([]byte(body), &pipeline); err != nil {
+		t.Fatalf("Failed to parse pipeline: %v", err)
+	}
+
+	names := nestedPipelines(pipeline)
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"failures", "item"}) {
+		t.Errorf("nestedPipelines() = %v, want [failures item]", names)
+	}
+}
+
+func TestTransferPipelinesToFile(t *testing.T) {
+	client := &Client{API: createMockPipelinesAPI(), URL: "http://mock:9200"}
+
+	output := t.TempDir() + "/pipelines.json"
+	config := Config{
+		Input:  "http://localhost:9200/logs-*",
+		Output: output,
+		Type:   "pipelines",
+	}
+
+	if err := transferPipelines(client, config); err != nil {
+		t.Fatalf("transferPipelines failed: %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var pipelines map[string]interface{}
+	if err := json.Unmarshal(content, &pipelines); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	var ids []string
+	for id := range pipelines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	expected := []string{"failures", "geoip", "logs-audit", "logs-enrich"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected pipelines %v, got %v", expected, ids)
+	}
+}
+
+func TestCopyReferencedPipelines(t *testing.T) {
+	source := &Client{API: createMockPipelinesAPI(), URL: "http://source:9200"}
+	settings := map[string]interface{}{"index": map[string]interface{}{"default_pipeline": "geoip"}}
+	names := settingsPipelines(settings, nil)
+
+	// Without IncludePipelines the pipelines are only reported
+	api := &MockElasticsearchAPI{}
+	dest := &Client{API: api, URL: "http://dest:9200"}
+	if err := copyReferencedPipelines(source, dest, names, Config{Output: "http://dest:9200"}); err != nil {
+		t.Fatalf("copyReferencedPipelines failed: %v", err)
+	}
+	if len(api.PutPipelines) != 0 {
+		t.Errorf("Expected no pipelines to be put, got %v", api.PutPipelines)
+	}
+
+	// Existing pipelines are kept even with the default fail policy
+	api = &MockElasticsearchAPI{Pipelines: map[string]string{"failures": `{"processors": []}`}}
+	dest = &Client{API: api, URL: "http://dest:9200"}
+	config := Config{Output: "http://dest:9200", IncludePipelines: true, Existing: "fail"}
+	if err := copyReferencedPipelines(source, dest, names, config); err != nil {
+		t.Fatalf("copyReferencedPipelines failed: %v", err)
+	}
+	if !reflect.DeepEqual(api.PutPipelines, []string{"geoip"}) {
+		t.Errorf("Expected geoip to be put, got %v", api.PutPipelines)
+	}
+}
+
+func TestPutPipelinesExisting(t *testing.T) {
+	pipelines := map[string]interface{}{
+		"geoip":  map[string]interface{}{"processors": []interface{}{}},
+		"enrich": map[string]interface{}{"processors": []interface{}{}},
+	}
+
+	api := &MockElasticsearchAPI{Pipelines: map[string]string{"geoip": `{"processors": []}`}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := ingest.PutPipelines(client.API, pipelines, putOptions(Config{Existing: "fail"})); err == nil {
+		t.Fatal("Expected error for existing pipeline")
+	}
+	if len(api.PutPipelines) != 0 {
+		t.Errorf("Expected nothing to be put, got %v", api.PutPipelines)
+	}
+
+	if err := ingest.PutPipelines(client.API, pipelines, putOptions(Config{Existing: "overwrite"})); err != nil {
+		t.Fatalf("PutPipelines failed: %v", err)
+	}
+	if !reflect.DeepEqual(api.PutPipelines, []string{"enrich", "geoip"}) {
+		t.Errorf("Expected enrich and geoip to be put, got %v", api.PutPipelines)
+	}
+}
+
+func TestWriteReferencedPipelines(t *testing.T) {
+	client := &Client{API: createMockPipelinesAPI(), URL: "http://mock:9200"}
+	settings := []map[string]interface{}{
+		{"index": map[string]interface{}{"default_pipeline": "logs-enrich"}},
+	}
+	path := t.TempDir() + "/logs.pipelines.json"
+
+	// Without IncludePipelines the pipelines are only reported
+	written, err := writeReferencedPipelines(client, path, settings, Config{})
+	if err != nil {
+		t.Fatalf("writeReferencedPipelines failed: %v", err)
+	}
+	if len(written) != 0 {
+		t.Error("Expected no pipelines file without IncludePipelines")
+	}
+
+	written, err = writeReferencedPipelines(client, path, settings, Config{IncludePipelines: true})
+	if err != nil {
+		t.Fatalf("writeReferencedPipelines failed: %v", err)
+	}
+	if len(written) != 1 || written[0].Name != "logs.pipelines.json" {
+		t.Fatalf("Expected a pipelines file, got %+v", written)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read pipelines file: %v", err)
+	}
+
+	var pipelines map[string]interface{}
+	if err := json.Unmarshal(content, &pipelines); err != nil {
+		t.Fatalf("Pipelines file is not valid JSON: %v", err)
+	}
+
+	var ids []string
+	for id := range pipelines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Nested pipelines are written along with the referenced one
+	expected := []string{"failures", "geoip", "logs-enrich"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected pipelines %v, got %v", expected, ids)
+	}
+}
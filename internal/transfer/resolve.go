@@ -0,0 +1,163 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// resolveIndexResponse mirrors the body returned by the _resolve/index API
+type resolveIndexResponse struct {
+	Indices []resolvedIndex `json:"indices"`
+	Aliases []struct {
+		Name    string   `json:"name"`
+		Indices []string `json:"indices"`
+	} `json:"aliases"`
+	DataStreams []struct {
+		Name           string   `json:"name"`
+		BackingIndices []string `json:"backing_indices"`
+		TimestampField string   `json:"timestamp_field"`
+	} `json:"data_streams"`
+}
+
+// resolvedIndex is an index of the _resolve/index API response; DataStream
+// is set for backing indices
+type resolvedIndex struct {
+	Name       string   `json:"name"`
+	Aliases    []string `json:"aliases,omitempty"`
+	DataStream string   `json:"data_stream,omitempty"`
+}
+
+// dataSource is an index or a data stream that documents are read from
+type dataSource struct {
+	Name       string
+	DataStream bool
+}
+
+// resolveIndices expands an index expression (a single name, a comma-separated
+// list, a wildcard pattern or an alias) into the sorted list of concrete
+// indices it refers to on the cluster. Data streams are left out with a
+// message, since recreating their backing indices literally would not give
+// a data stream.
+func resolveIndices(client *Client, expression string, config Config) ([]string, error) {
+	sources, err := resolveSources(client, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	var indices []string
+	for _, source := range sources {
+		if source.DataStream {
+			logf(config, "Skipping data stream %s: use --type=data to copy it through its index template\n", source.Name)
+			continue
+		}
+		indices = append(indices, source.Name)
+	}
+
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("%s only matches data streams", expression)
+	}
+
+	return indices, nil
+}
+
+// resolveSources expands an index expression into the sorted indices and
+// data streams it refers to. Data streams are kept whole: their backing
+// indices are replaced by the data stream, whose documents are read through
+// its name.
+func resolveSources(client *Client, expression string, o ...func(*esapi.IndicesResolveIndexRequest)) ([]dataSource, error) {
+	result, err := resolveExpression(client, expression, o...)
+	if err != nil {
+		return nil, err
+	}
+
+	streamOf := make(map[string]string)
+	for _, index := range result.Indices {
+		if index.DataStream != "" {
+			streamOf[index.Name] = index.DataStream
+		}
+	}
+	for _, stream := range result.DataStreams {
+		for _, name := range stream.BackingIndices {
+			streamOf[name] = stream.Name
+		}
+	}
+
+	seen := make(map[string]bool)
+	var sources []dataSource
+	add := func(name string) {
+		source := dataSource{Name: name}
+		if stream, ok := streamOf[name]; ok {
+			source = dataSource{Name: stream, DataStream: true}
+		}
+		if source.Name != "" && !seen[source.Name] {
+			seen[source.Name] = true
+			sources = append(sources, source)
+		}
+	}
+
+	for _, index := range result.Indices {
+		add(index.Name)
+	}
+	for _, alias := range result.Aliases {
+		for _, name := range alias.Indices {
+			add(name)
+		}
+	}
+	for _, stream := range result.DataStreams {
+		if !seen[stream.Name] {
+			seen[stream.Name] = true
+			sources = append(sources, dataSource{Name: stream.Name, DataStream: true})
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no indices match %s", expression)
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Name < sources[j].Name
+	})
+	return sources, nil
+}
+
+// resolveExpression calls the _resolve/index API for an index expression
+func resolveExpression(client *Client, expression string, o ...func(*esapi.IndicesResolveIndexRequest)) (*resolveIndexResponse, error) {
+	res, err := client.API.IndicesResolveIndex([]string{expression}, o...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		// Clusters older than 7.9 have no _resolve/index endpoint; a plain
+		// index name can still be used as is. Any other failure, such as
+		// missing credentials, is reported here rather than on every later
+		// request.
+		if isUnsupported(res.StatusCode) && !isIndexPattern(expression) {
+			return &resolveIndexResponse{Indices: []resolvedIndex{{Name: expression}}}, nil
+		}
+		return nil, fmt.Errorf("resolve index failed: %s", res.String())
+	}
+
+	var result resolveIndexResponse
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// isUnsupported reports whether an error status means the cluster does not
+// know the endpoint
+func isUnsupported(status int) bool {
+	return status == 400 || status == 404 || status == 405
+}
+
+// isIndexPattern reports whether the expression may match more than one index
+func isIndexPattern(expression string) bool {
+	return strings.ContainsAny(expression, "*,")
+}
@@ -0,0 +1,155 @@
+package transfer
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+func TestResolveIndices(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		response   *esapi.Response
+		expected   []string
+		wantErr    bool
+	}{
+		{
+			name:       "single index",
+			expression: "test-index",
+			response:   createMockResolveResponse(),
+			expected:   []string{"test-index"},
+		},
+		{
+			name:       "wildcard with aliases and data streams",
+			expression: "logs-*",
+			response: &esapi.Response{
+				StatusCode: 200,
+				Body: io.NopCloser(strings.NewReader(`{
+					"indices": [{"name": "logs-b"}, {"name": "logs-a"}],
+					"aliases": [{"name": "logs-all", "indices": ["logs-a", "logs-c"]}],
+					"data_streams": [{"name": "logs-ds", "backing_indices": [".ds-logs-ds-000001"]}]
+				}`)),
+			},
+			expected: []string{"logs-a", "logs-b", "logs-c"},
+		},
+		{
+			name:       "only data streams",
+			expression: "logs-ds",
+			response: &esapi.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"data_streams": [{"name": "logs-ds", "backing_indices": [".ds-logs-ds-000001"]}]}`)),
+			},
+			wantErr: true,
+		},
+		{
+			name:       "no matches",
+			expression: "missing-*",
+			response: &esapi.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"indices": [], "aliases": [], "data_streams": []}`)),
+			},
+			wantErr: true,
+		},
+		{
+			name:       "plain name on cluster without resolve API",
+			expression: "legacy",
+			response:   &esapi.Response{StatusCode: 400, Body: io.NopCloser(strings.NewReader(`{}`))},
+			expected:   []string{"legacy"},
+		},
+		{
+			name:       "plain name without permission",
+			expression: "legacy",
+			response:   &esapi.Response{StatusCode: 403, Body: io.NopCloser(strings.NewReader(`{}`))},
+			wantErr:    true,
+		},
+		{
+			name:       "plain name on failing cluster",
+			expression: "legacy",
+			response:   &esapi.Response{StatusCode: 503, Body: io.NopCloser(strings.NewReader(`{}`))},
+			wantErr:    true,
+		},
+		{
+			name:       "pattern on cluster without resolve API",
+			expression: "a,b",
+			response:   &esapi.Response{StatusCode: 400, Body: io.NopCloser(strings.NewReader(`{}`))},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				API: &MockElasticsearchAPI{ResolveResponse: tt.response},
+				URL: "http://mock:9200",
+			}
+
+			indices, err := resolveIndices(client, tt.expression, Config{})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(indices, tt.expected) {
+				t.Errorf("resolveIndices(%q) = %v, want %v", tt.expression, indices, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRemainingLimit(t *testing.T) {
+	tests := []struct {
+		limit, done, expected int
+		ok                    bool
+	}{
+		{0, 500, 0, true},
+		{100, 40, 60, true},
+		{100, 100, 0, false},
+	}
+
+	for _, tt := range tests {
+		limit, ok := remainingLimit(tt.limit, tt.done)
+		if limit != tt.expected || ok != tt.ok {
+			t.Errorf("remainingLimit(%d, %d) = (%d, %v), want (%d, %v)",
+				tt.limit, tt.done, limit, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestExportToFileMultipleIndices(t *testing.T) {
+	client := createMockClient()
+	output := t.TempDir() + "/export.ndjson"
+
+	config := Config{
+		Output:     output,
+		Format:     "ndjson",
+		ScrollSize: 100,
+		Verbose:    true,
+	}
+
+	if err := exportToFile(client, []dataSource{{Name: "test-index"}, {Name: "other-index"}}, config); err != nil {
+		t.Fatalf("exportToFile failed: %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Errorf("Expected one document per index (2 lines), got %d", len(lines))
+	}
+
+	if _, err := os.Stat(output + ".mapping.json"); err != nil {
+		t.Errorf("Expected mapping sidecar file to be written: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package transfer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/storedscripts"
+)
+
+// transferScripts copies the stored scripts and search templates whose ids
+// match the input URL path (all of them for a bare cluster URL). A file
+// receives them keyed by id as put script request bodies.
+func transferScripts(sourceClient *Client, config Config) error {
+	pattern := extractIndex(config.Input)
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	all, err := storedscripts.GetScripts(sourceClient.API)
+	if err != nil {
+		return fmt.Errorf("failed to get stored scripts: %w", err)
+	}
+
+	scripts := make(map[string]interface{})
+	for id, script := range all {
+		if cluster.MatchesAny(id, strings.Split(pattern, ",")) {
+			scripts[id] = script
+		}
+	}
+
+	if len(scripts) == 0 {
+		return fmt.Errorf("no stored scripts match %s", pattern)
+	}
+
+	if isFile(config.Output) {
+		return writeFileBackup(sourceClient, scripts, nil, config)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("scripts type requires a cluster URL without path as output")
+	}
+
+	destURL := getBaseURL(config.Output)
+	destClient, err := createClient(destURL, config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return storedscripts.PutScripts(destClient.API, scripts, putOptions(config))
+}
@@ -0,0 +1,86 @@
+package transfer
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/storedscripts"
+)
+
+func createMockScriptsAPI() *MockElasticsearchAPI {
+	return &MockElasticsearchAPI{
+		Scripts: map[string]string{
+			"search-products": `{"lang": "mustache", "source": "{\"query\":{\"match\":{\"name\":\"{{q}}\"}}}", "options": {"content_type": "application/json;charset=utf-8"}}`,
+			"search-users":    `{"lang": "mustache", "source": "{\"query\":{\"match_all\":{}}}"}`,
+			"boost":           `{"lang": "painless", "source": "doc['rank'].value * params.factor"}`,
+		},
+	}
+}
+
+func TestTransferScriptsToFile(t *testing.T) {
+	client := &Client{API: createMockScriptsAPI(), URL: "http://mock:9200"}
+
+	output := t.TempDir() + "/scripts.json"
+	config := Config{
+		Input:  "http://localhost:9200/search-*",
+		Output: output,
+		Type:   "scripts",
+	}
+
+	if err := transferScripts(client, config); err != nil {
+		t.Fatalf("transferScripts failed: %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var scripts map[string]map[string]map[string]interface{}
+	if err := json.Unmarshal(content, &scripts); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	if len(scripts) != 2 {
+		t.Errorf("Expected 2 search templates, got %v", scripts)
+	}
+	if scripts["search-products"]["script"]["lang"] != "mustache" {
+		t.Errorf("Expected put script body for search-products, got %v", scripts["search-products"])
+	}
+}
+
+func TestTransferScriptsNoMatch(t *testing.T) {
+	client := &Client{API: createMockScriptsAPI(), URL: "http://mock:9200"}
+
+	config := Config{Input: "http://localhost:9200/missing-*", Output: t.TempDir() + "/scripts.json"}
+	if err := transferScripts(client, config); err == nil {
+		t.Error("Expected error when no script matches")
+	}
+}
+
+func TestPutScriptsExisting(t *testing.T) {
+	source := &Client{API: createMockScriptsAPI(), URL: "http://source:9200"}
+	scripts, err := storedscripts.GetScripts(source.API)
+	if err != nil {
+		t.Fatalf("GetScripts failed: %v", err)
+	}
+
+	api := &MockElasticsearchAPI{Scripts: map[string]string{"boost": `{"lang": "painless", "source": "1"}`}}
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	if err := storedscripts.PutScripts(client.API, scripts, putOptions(Config{Existing: "fail"})); err == nil {
+		t.Fatal("Expected error for existing script")
+	}
+	if len(api.PutScripts) != 0 {
+		t.Errorf("Expected nothing to be put, got %v", api.PutScripts)
+	}
+
+	if err := storedscripts.PutScripts(client.API, scripts, putOptions(Config{Existing: "skip"})); err != nil {
+		t.Fatalf("PutScripts failed: %v", err)
+	}
+	if expected := []string{"search-products", "search-users"}; !reflect.DeepEqual(api.PutScripts, expected) {
+		t.Errorf("Expected %v to be put, got %v", expected, api.PutScripts)
+	}
+}
@@ -0,0 +1,89 @@
+package transfer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/security"
+)
+
+// transferSecurity copies the roles and role mappings whose names match the
+// input URL path (all of them for a bare cluster URL). Reserved roles and
+// role mappings are built into every cluster and are never copied. A file
+// receives them as a security.Export.
+func transferSecurity(sourceClient *Client, config Config) error {
+	pattern := extractIndex(config.Input)
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	all, err := security.Get(sourceClient.API)
+	if err != nil {
+		return err
+	}
+
+	export := selectSecurity(all, strings.Split(pattern, ","), config)
+	if len(export.Roles)+len(export.RoleMappings) == 0 {
+		return fmt.Errorf("no roles or role mappings match %s", pattern)
+	}
+
+	if isFile(config.Output) {
+		return writeFileBackup(sourceClient, export, nil, config)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("security type requires a cluster URL without path as output")
+	}
+
+	destURL := getBaseURL(config.Output)
+	destClient, err := createClient(destURL, config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return security.Apply(destClient.API, export, applyOptions(config))
+}
+
+// selectSecurity returns the roles and role mappings matching patterns,
+// leaving out reserved ones
+func selectSecurity(all *security.Export, patterns []string, config Config) *security.Export {
+	selected := &security.Export{
+		Roles:        make(map[string]interface{}),
+		RoleMappings: make(map[string]interface{}),
+	}
+
+	for name, role := range all.Roles {
+		if !cluster.MatchesAny(name, patterns) {
+			continue
+		}
+		if security.IsReserved(role) {
+			if config.Verbose {
+				logf(config, "Skipping reserved role %s\n", name)
+			}
+			continue
+		}
+		selected.Roles[name] = role
+	}
+
+	for name, mapping := range all.RoleMappings {
+		if !cluster.MatchesAny(name, patterns) {
+			continue
+		}
+		if security.IsReserved(mapping) {
+			if config.Verbose {
+				logf(config, "Skipping reserved role mapping %s\n", name)
+			}
+			continue
+		}
+		selected.RoleMappings[name] = mapping
+	}
+
+	return selected
+}
+
+// applyOptions returns the options of applying roles and role mappings to the
+// destination
+func applyOptions(config Config) security.ApplyOptions {
+	return security.ApplyOptions{PutOptions: putOptions(config), DryRun: config.DryRun}
+}
@@ -0,0 +1,137 @@
+package transfer
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/security"
+)
+
+func createMockSecurityAPI() *MockElasticsearchAPI {
+	return &MockElasticsearchAPI{
+		Roles: map[string]string{
+			"logs-reader": `{"cluster": ["monitor"], "indices": [{"names": ["logs-*"], "privileges": ["read"]}], "metadata": {}, "transient_metadata": {"enabled": true}}`,
+			"logs-writer": `{"cluster": ["monitor"], "indices": [{"names": ["logs-*"], "privileges": ["write"]}], "metadata": {}, "transient_metadata": {"enabled": true}}`,
+			"superuser":   `{"cluster": ["all"], "metadata": {"_reserved": true}, "transient_metadata": {"enabled": true}}`,
+		},
+		RoleMappings: map[string]string{
+			"logs-ldap": `{"enabled": true, "roles": ["logs-reader"], "rules": {"field": {"groups": "cn=logs,dc=example,dc=com"}}, "metadata": {}}`,
+		},
+	}
+}
+
+func TestTransferSecurityToFile(t *testing.T) {
+	client := &Client{API: createMockSecurityAPI(), URL: "http://mock:9200"}
+
+	output := t.TempDir() + "/security.json"
+	config := Config{
+		Input:  "http://localhost:9200",
+		Output: output,
+		Type:   "security",
+	}
+
+	if err := transferSecurity(client, config); err != nil {
+		t.Fatalf("transferSecurity failed: %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var export security.Export
+	if err := json.Unmarshal(content, &export); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	if _, ok := export.Roles["superuser"]; ok {
+		t.Error("Expected reserved role to be skipped")
+	}
+	if len(export.Roles) != 2 || len(export.RoleMappings) != 1 {
+		t.Errorf("Expected 2 roles and 1 role mapping, got %v and %v", export.Roles, export.RoleMappings)
+	}
+	if role := export.Roles["logs-reader"].(map[string]interface{}); role["transient_metadata"] != nil {
+		t.Errorf("Expected transient_metadata to be removed, got %v", role)
+	}
+}
+
+func TestTransferSecurityPattern(t *testing.T) {
+	api := &MockElasticsearchAPI{}
+	dest := &Client{API: api, URL: "http://dest:9200"}
+
+	source, err := security.Get(createMockSecurityAPI())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	export := selectSecurity(source, []string{"*-reader"}, Config{})
+	if err := security.Apply(dest.API, export, applyOptions(Config{Output: "http://dest:9200"})); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(api.PutRoles, []string{"logs-reader"}) {
+		t.Errorf("Expected only logs-reader to be put, got %v", api.PutRoles)
+	}
+	if len(api.PutRoleMappings) != 0 {
+		t.Errorf("Expected no role mapping to be put, got %v", api.PutRoleMappings)
+	}
+}
+
+func TestApplySecurityExisting(t *testing.T) {
+	source, err := security.Get(createMockSecurityAPI())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	export := selectSecurity(source, []string{"*"}, Config{})
+
+	// logs-reader is identical on the destination, logs-writer differs
+	api := &MockElasticsearchAPI{
+		Roles: map[string]string{
+			"logs-reader": `{"cluster": ["monitor"], "indices": [{"names": ["logs-*"], "privileges": ["read"]}], "metadata": {}, "transient_metadata": {"enabled": true}}`,
+			"logs-writer": `{"cluster": ["monitor"], "indices": [{"names": ["logs-*"], "privileges": ["read"]}], "metadata": {}}`,
+		},
+	}
+	dest := &Client{API: api, URL: "http://dest:9200"}
+
+	if err := security.Apply(dest.API, export, applyOptions(Config{Existing: "fail"})); err == nil {
+		t.Fatal("Expected error for differing role")
+	}
+	if len(api.PutRoles)+len(api.PutRoleMappings) != 0 {
+		t.Errorf("Expected nothing to be put, got %v and %v", api.PutRoles, api.PutRoleMappings)
+	}
+
+	if err := security.Apply(dest.API, export, applyOptions(Config{Existing: "skip"})); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(api.PutRoles) != 0 || !reflect.DeepEqual(api.PutRoleMappings, []string{"logs-ldap"}) {
+		t.Errorf("Expected only the new role mapping to be put, got %v and %v", api.PutRoles, api.PutRoleMappings)
+	}
+
+	api.PutRoleMappings = nil
+	if err := security.Apply(dest.API, export, applyOptions(Config{Existing: "overwrite"})); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !reflect.DeepEqual(api.PutRoles, []string{"logs-writer"}) {
+		t.Errorf("Expected only the differing role to be put, got %v", api.PutRoles)
+	}
+}
+
+func TestApplySecurityDryRun(t *testing.T) {
+	source, err := security.Get(createMockSecurityAPI())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	export := selectSecurity(source, []string{"*"}, Config{})
+
+	api := &MockElasticsearchAPI{}
+	dest := &Client{API: api, URL: "http://dest:9200"}
+
+	if err := security.Apply(dest.API, export, applyOptions(Config{DryRun: true})); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(api.PutRoles)+len(api.PutRoleMappings) != 0 {
+		t.Errorf("Expected dry run to put nothing, got %v and %v", api.PutRoles, api.PutRoleMappings)
+	}
+}
@@ -0,0 +1,131 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/templates"
+)
+
+// transferTemplates copies the component, composable index and legacy
+// templates whose names match the input URL path (all templates for a bare
+// cluster URL). Component templates referenced by a selected index template
+// are copied along with it, and are put first on the destination.
+func transferTemplates(sourceClient *Client, config Config) error {
+	pattern := extractIndex(config.Input)
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	set, err := getTemplates(sourceClient, strings.Split(pattern, ","))
+	if err != nil {
+		return fmt.Errorf("failed to get templates: %w", err)
+	}
+
+	if !config.IncludeSystem {
+		set.RemoveSystem()
+	}
+
+	// Pull in the component templates the selected index templates need
+	if missing := set.MissingComponents(); len(missing) > 0 {
+		components, err := getTemplates(sourceClient, missing, templates.Component)
+		if err != nil {
+			return fmt.Errorf("failed to get component templates: %w", err)
+		}
+		set.Add(components)
+
+		for _, name := range set.MissingComponents() {
+			logf(config, "Warning: component template %s does not exist on the source\n", name)
+		}
+	}
+
+	if set.Len() == 0 {
+		return fmt.Errorf("no templates match %s", pattern)
+	}
+
+	if isFile(config.Output) {
+		return writeFileBackup(sourceClient, set, nil, config)
+	}
+
+	if extractIndex(config.Output) != "" {
+		return fmt.Errorf("templates type requires a cluster URL without path as output")
+	}
+
+	destURL := getBaseURL(config.Output)
+	destClient, err := createClient(destURL, config.Username, config.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return templates.Put(destClient.API, set, putOptions(config))
+}
+
+// getTemplates gets the templates of the given kinds (all kinds if none are
+// given) whose names match one of the patterns
+func getTemplates(client *Client, patterns []string, kinds ...string) (*templates.Set, error) {
+	if len(kinds) == 0 {
+		kinds = []string{templates.Component, templates.Index, templates.Legacy}
+	}
+
+	set := &templates.Set{}
+	for _, kind := range kinds {
+		for _, pattern := range patterns {
+			found, err := getTemplatesOfKind(client, kind, pattern)
+			if err != nil {
+				return nil, err
+			}
+			set.Add(found)
+		}
+	}
+
+	return set, nil
+}
+
+// getTemplatesOfKind gets the templates of one kind matching a name pattern.
+// No match is not an error.
+func getTemplatesOfKind(client *Client, kind, pattern string) (*templates.Set, error) {
+	var res *esapi.Response
+	var err error
+
+	switch kind {
+	case templates.Component:
+		res, err = client.API.ClusterGetComponentTemplate(func(r *esapi.ClusterGetComponentTemplateRequest) {
+			r.Name = []string{pattern}
+		})
+	case templates.Index:
+		res, err = client.API.IndicesGetIndexTemplate(func(r *esapi.IndicesGetIndexTemplateRequest) {
+			r.Name = pattern
+		})
+	case templates.Legacy:
+		res, err = client.API.IndicesGetTemplate(func(r *esapi.IndicesGetTemplateRequest) {
+			r.Name = []string{pattern}
+		})
+	default:
+		return nil, fmt.Errorf("unknown template kind: %s", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	set := &templates.Set{}
+	if res.StatusCode == 404 {
+		return set, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get %s templates failed: %s", kind, res.String())
+	}
+
+	if kind == templates.Legacy {
+		err = json.NewDecoder(res.Body).Decode(&set.LegacyTemplates)
+	} else {
+		err = json.NewDecoder(res.Body).Decode(set)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
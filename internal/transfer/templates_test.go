@@ -0,0 +1,140 @@
+package transfer
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lilmonk/elasticdump/internal/templates"
+)
+
+func createMockTemplatesAPI() *MockElasticsearchAPI {
+	return &MockElasticsearchAPI{
+		ComponentTemplates: map[string]string{
+			"logs-settings": `{"template": {"settings": {"number_of_shards": 1}}}`,
+			"ecs-mappings":  `{"template": {"mappings": {}}}`,
+			"unused":        `{"template": {}}`,
+		},
+		IndexTemplates: map[string]string{
+			"logs":    `{"index_patterns": ["logs-*"], "composed_of": ["logs-settings", "ecs-mappings"]}`,
+			"metrics": `{"index_patterns": ["metrics-*"], "_meta": {"managed": true}}`,
+		},
+		LegacyTemplates: map[string]string{
+			"logs-legacy": `{"index_patterns": ["old-logs-*"], "order": 0}`,
+		},
+	}
+}
+
+func TestTransferTemplatesToFile(t *testing.T) {
+	client := &Client{API: createMockTemplatesAPI(), URL: "http://mock:9200"}
+
+	output := t.TempDir() + "/templates.json"
+	config := Config{
+		Input:  "http://localhost:9200/logs*",
+		Output: output,
+		Type:   "templates",
+	}
+
+	if err := transferTemplates(client, config); err != nil {
+		t.Fatalf("transferTemplates failed: %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var set templates.Set
+	if err := json.Unmarshal(content, &set); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	var names []string
+	for _, template := range set.Ordered() {
+		names = append(names, template.Kind+"/"+template.Name)
+	}
+
+	expected := []string{"component/ecs-mappings", "component/logs-settings", "index/logs", "legacy/logs-legacy"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected templates %v, got %v", expected, names)
+	}
+}
+
+func TestTransferTemplatesToStdout(t *testing.T) {
+	api := createMockTemplatesAPI()
+	delete(api.ComponentTemplates, "ecs-mappings")
+	client := &Client{API: api, URL: "http://mock:9200"}
+
+	config := Config{Input: "http://localhost:9200/logs", Output: "-", Type: "templates"}
+	var transferErr error
+	out, errOut := captureOutput(t, func() {
+		transferErr = transferTemplates(client, config)
+	})
+	if transferErr != nil {
+		t.Fatalf("transferTemplates failed: %v", transferErr)
+	}
+
+	// The warning goes to standard error, leaving standard output to the JSON
+	var set templates.Set
+	if err := json.Unmarshal([]byte(out), &set); err != nil {
+		t.Errorf("Standard output is not valid JSON: %v\n%s", err, out)
+	}
+	if !strings.Contains(errOut, "component template ecs-mappings does not exist") {
+		t.Errorf("Expected the warning on standard error, got %q", errOut)
+	}
+}
+
+func TestGetTemplatesSkipsSystem(t *testing.T) {
+	client := &Client{API: createMockTemplatesAPI(), URL: "http://mock:9200"}
+
+	set, err := getTemplates(client, []string{"*"})
+	if err != nil {
+		t.Fatalf("getTemplates failed: %v", err)
+	}
+	if len(set.IndexTemplates) != 2 {
+		t.Fatalf("Expected 2 index templates before filtering, got %d", len(set.IndexTemplates))
+	}
+
+	set.RemoveSystem()
+	if len(set.IndexTemplates) != 1 || set.IndexTemplates[0].Name != "logs" {
+		t.Errorf("Expected managed metrics template to be removed, got %+v", set.IndexTemplates)
+	}
+}
+
+func TestPutTemplates(t *testing.T) {
+	source := &Client{API: createMockTemplatesAPI(), URL: "http://mock:9200"}
+	set, err := getTemplates(source, []string{"logs*", "ecs-mappings"})
+	if err != nil {
+		t.Fatalf("getTemplates failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		existing string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "fail on existing template", existing: "fail", wantErr: true},
+		{name: "skip existing template", existing: "skip", expected: []string{"component/ecs-mappings", "component/logs-settings", "legacy/logs-legacy"}},
+		{name: "overwrite existing template", existing: "overwrite", expected: []string{"component/ecs-mappings", "component/logs-settings", "index/logs", "legacy/logs-legacy"}},
+		{name: "invalid policy", existing: "merge", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &MockElasticsearchAPI{ExistingTemplates: map[string]bool{"index/logs": true}}
+			dest := &Client{API: api, URL: "http://dest:9200"}
+
+			err := templates.Put(dest.API, set, putOptions(Config{Output: "http://dest:9200", Existing: tt.existing}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Put() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(api.PutTemplates, tt.expected) {
+				t.Errorf("Expected put order %v, got %v", tt.expected, api.PutTemplates)
+			}
+		})
+	}
+}
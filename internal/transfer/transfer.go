@@ -7,10 +7,15 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/backup"
+	"github.com/lilmonk/elasticdump/internal/cluster"
+	"github.com/lilmonk/elasticdump/internal/indexdef"
+	"github.com/lilmonk/elasticdump/internal/rename"
 	"github.com/schollz/progressbar/v3"
 )
 
@@ -24,6 +29,36 @@ type ElasticsearchAPI interface {
 	IndicesPutMapping(indices []string, body io.Reader, o ...func(*esapi.IndicesPutMappingRequest)) (*esapi.Response, error)
 	IndicesGetSettings(o ...func(*esapi.IndicesGetSettingsRequest)) (*esapi.Response, error)
 	IndicesPutSettings(body io.Reader, o ...func(*esapi.IndicesPutSettingsRequest)) (*esapi.Response, error)
+	IndicesResolveIndex(name []string, o ...func(*esapi.IndicesResolveIndexRequest)) (*esapi.Response, error)
+	IndicesGetAlias(o ...func(*esapi.IndicesGetAliasRequest)) (*esapi.Response, error)
+	IndicesExists(index []string, o ...func(*esapi.IndicesExistsRequest)) (*esapi.Response, error)
+	IndicesCreate(index string, o ...func(*esapi.IndicesCreateRequest)) (*esapi.Response, error)
+	IndicesUpdateAliases(body io.Reader, o ...func(*esapi.IndicesUpdateAliasesRequest)) (*esapi.Response, error)
+	ClusterGetComponentTemplate(o ...func(*esapi.ClusterGetComponentTemplateRequest)) (*esapi.Response, error)
+	ClusterPutComponentTemplate(name string, body io.Reader, o ...func(*esapi.ClusterPutComponentTemplateRequest)) (*esapi.Response, error)
+	ClusterExistsComponentTemplate(name string, o ...func(*esapi.ClusterExistsComponentTemplateRequest)) (*esapi.Response, error)
+	IndicesGetIndexTemplate(o ...func(*esapi.IndicesGetIndexTemplateRequest)) (*esapi.Response, error)
+	IndicesPutIndexTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutIndexTemplateRequest)) (*esapi.Response, error)
+	IndicesExistsIndexTemplate(name string, o ...func(*esapi.IndicesExistsIndexTemplateRequest)) (*esapi.Response, error)
+	IndicesGetTemplate(o ...func(*esapi.IndicesGetTemplateRequest)) (*esapi.Response, error)
+	IndicesPutTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutTemplateRequest)) (*esapi.Response, error)
+	IndicesExistsTemplate(name []string, o ...func(*esapi.IndicesExistsTemplateRequest)) (*esapi.Response, error)
+	IngestGetPipeline(o ...func(*esapi.IngestGetPipelineRequest)) (*esapi.Response, error)
+	IngestPutPipeline(id string, body io.Reader, o ...func(*esapi.IngestPutPipelineRequest)) (*esapi.Response, error)
+	ILMGetLifecycle(o ...func(*esapi.ILMGetLifecycleRequest)) (*esapi.Response, error)
+	ILMPutLifecycle(policy string, o ...func(*esapi.ILMPutLifecycleRequest)) (*esapi.Response, error)
+	ClusterState(o ...func(*esapi.ClusterStateRequest)) (*esapi.Response, error)
+	PutScript(id string, body io.Reader, o ...func(*esapi.PutScriptRequest)) (*esapi.Response, error)
+	SecurityGetRole(o ...func(*esapi.SecurityGetRoleRequest)) (*esapi.Response, error)
+	SecurityPutRole(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleRequest)) (*esapi.Response, error)
+	SecurityGetRoleMapping(o ...func(*esapi.SecurityGetRoleMappingRequest)) (*esapi.Response, error)
+	SecurityPutRoleMapping(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleMappingRequest)) (*esapi.Response, error)
+	IndicesGetDataStream(o ...func(*esapi.IndicesGetDataStreamRequest)) (*esapi.Response, error)
+	IndicesCreateDataStream(name string, o ...func(*esapi.IndicesCreateDataStreamRequest)) (*esapi.Response, error)
+	IndicesDelete(index []string, o ...func(*esapi.IndicesDeleteRequest)) (*esapi.Response, error)
+	IndicesDeleteDataStream(name []string, o ...func(*esapi.IndicesDeleteDataStreamRequest)) (*esapi.Response, error)
+	IndicesRefresh(o ...func(*esapi.IndicesRefreshRequest)) (*esapi.Response, error)
+	Info(o ...func(*esapi.InfoRequest)) (*esapi.Response, error)
 }
 
 // ElasticsearchClientWrapper wraps the actual Elasticsearch client to implement our interface
@@ -76,6 +111,156 @@ func (w *ElasticsearchClientWrapper) IndicesPutSettings(body io.Reader, o ...fun
 	return w.client.Indices.PutSettings(body, o...)
 }
 
+// IndicesResolveIndex implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesResolveIndex(name []string, o ...func(*esapi.IndicesResolveIndexRequest)) (*esapi.Response, error) {
+	return w.client.Indices.ResolveIndex(name, o...)
+}
+
+// IndicesGetAlias implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesGetAlias(o ...func(*esapi.IndicesGetAliasRequest)) (*esapi.Response, error) {
+	return w.client.Indices.GetAlias(o...)
+}
+
+// IndicesExists implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesExists(index []string, o ...func(*esapi.IndicesExistsRequest)) (*esapi.Response, error) {
+	return w.client.Indices.Exists(index, o...)
+}
+
+// IndicesCreate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesCreate(index string, o ...func(*esapi.IndicesCreateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.Create(index, o...)
+}
+
+// IndicesUpdateAliases implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesUpdateAliases(body io.Reader, o ...func(*esapi.IndicesUpdateAliasesRequest)) (*esapi.Response, error) {
+	return w.client.Indices.UpdateAliases(body, o...)
+}
+
+// ClusterGetComponentTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ClusterGetComponentTemplate(o ...func(*esapi.ClusterGetComponentTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Cluster.GetComponentTemplate(o...)
+}
+
+// ClusterPutComponentTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ClusterPutComponentTemplate(name string, body io.Reader, o ...func(*esapi.ClusterPutComponentTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Cluster.PutComponentTemplate(name, body, o...)
+}
+
+// ClusterExistsComponentTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ClusterExistsComponentTemplate(name string, o ...func(*esapi.ClusterExistsComponentTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Cluster.ExistsComponentTemplate(name, o...)
+}
+
+// IndicesGetIndexTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesGetIndexTemplate(o ...func(*esapi.IndicesGetIndexTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.GetIndexTemplate(o...)
+}
+
+// IndicesPutIndexTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesPutIndexTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutIndexTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.PutIndexTemplate(name, body, o...)
+}
+
+// IndicesExistsIndexTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesExistsIndexTemplate(name string, o ...func(*esapi.IndicesExistsIndexTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.ExistsIndexTemplate(name, o...)
+}
+
+// IndicesGetTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesGetTemplate(o ...func(*esapi.IndicesGetTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.GetTemplate(o...)
+}
+
+// IndicesPutTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesPutTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.PutTemplate(name, body, o...)
+}
+
+// IndicesExistsTemplate implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesExistsTemplate(name []string, o ...func(*esapi.IndicesExistsTemplateRequest)) (*esapi.Response, error) {
+	return w.client.Indices.ExistsTemplate(name, o...)
+}
+
+// IngestGetPipeline implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IngestGetPipeline(o ...func(*esapi.IngestGetPipelineRequest)) (*esapi.Response, error) {
+	return w.client.Ingest.GetPipeline(o...)
+}
+
+// IngestPutPipeline implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IngestPutPipeline(id string, body io.Reader, o ...func(*esapi.IngestPutPipelineRequest)) (*esapi.Response, error) {
+	return w.client.Ingest.PutPipeline(id, body, o...)
+}
+
+// ILMGetLifecycle implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ILMGetLifecycle(o ...func(*esapi.ILMGetLifecycleRequest)) (*esapi.Response, error) {
+	return w.client.ILM.GetLifecycle(o...)
+}
+
+// ILMPutLifecycle implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ILMPutLifecycle(policy string, o ...func(*esapi.ILMPutLifecycleRequest)) (*esapi.Response, error) {
+	return w.client.ILM.PutLifecycle(policy, o...)
+}
+
+// ClusterState implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) ClusterState(o ...func(*esapi.ClusterStateRequest)) (*esapi.Response, error) {
+	return w.client.Cluster.State(o...)
+}
+
+// PutScript implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) PutScript(id string, body io.Reader, o ...func(*esapi.PutScriptRequest)) (*esapi.Response, error) {
+	return w.client.PutScript(id, body, o...)
+}
+
+// SecurityGetRole implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) SecurityGetRole(o ...func(*esapi.SecurityGetRoleRequest)) (*esapi.Response, error) {
+	return w.client.Security.GetRole(o...)
+}
+
+// SecurityPutRole implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) SecurityPutRole(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleRequest)) (*esapi.Response, error) {
+	return w.client.Security.PutRole(name, body, o...)
+}
+
+// SecurityGetRoleMapping implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) SecurityGetRoleMapping(o ...func(*esapi.SecurityGetRoleMappingRequest)) (*esapi.Response, error) {
+	return w.client.Security.GetRoleMapping(o...)
+}
+
+// SecurityPutRoleMapping implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) SecurityPutRoleMapping(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleMappingRequest)) (*esapi.Response, error) {
+	return w.client.Security.PutRoleMapping(name, body, o...)
+}
+
+// IndicesGetDataStream implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesGetDataStream(o ...func(*esapi.IndicesGetDataStreamRequest)) (*esapi.Response, error) {
+	return w.client.Indices.GetDataStream(o...)
+}
+
+// IndicesCreateDataStream implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesCreateDataStream(name string, o ...func(*esapi.IndicesCreateDataStreamRequest)) (*esapi.Response, error) {
+	return w.client.Indices.CreateDataStream(name, o...)
+}
+
+// IndicesDelete implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesDelete(index []string, o ...func(*esapi.IndicesDeleteRequest)) (*esapi.Response, error) {
+	return w.client.Indices.Delete(index, o...)
+}
+
+// IndicesDeleteDataStream implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesDeleteDataStream(name []string, o ...func(*esapi.IndicesDeleteDataStreamRequest)) (*esapi.Response, error) {
+	return w.client.Indices.DeleteDataStream(name, o...)
+}
+
+// Info implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) Info(o ...func(*esapi.InfoRequest)) (*esapi.Response, error) {
+	return w.client.Info(o...)
+}
+
+// IndicesRefresh implements ElasticsearchAPI
+func (w *ElasticsearchClientWrapper) IndicesRefresh(o ...func(*esapi.IndicesRefreshRequest)) (*esapi.Response, error) {
+	return w.client.Indices.Refresh(o...)
+}
+
 // Config holds the configuration for transfer operations
 type Config struct {
 	Input       string
@@ -84,10 +269,71 @@ type Config struct {
 	Limit       int
 	Concurrency int
 	Format      string
+	Pretty      bool
 	ScrollSize  int
 	Verbose     bool
 	Username    string
 	Password    string
+
+	// Compress is the compression of output files ("none" or "gzip"); when
+	// empty, files whose name ends in .gz are compressed with gzip.
+	// CompressionLevel is the gzip level, 0 for the default.
+	Compress         string
+	CompressionLevel int
+
+	// Fields selects the columns of the csv format, as dot paths into the
+	// document source; ArraySeparator joins the values of arrays in a cell
+	Fields         []string
+	ArraySeparator string
+
+	// FileSize and FileDocs split the output file of a data export into
+	// numbered parts of at most about FileSize bytes (before compression) or
+	// FileDocs documents, listed in a parts manifest; 0 leaves the part
+	// unbounded
+	FileSize int64
+	FileDocs int
+
+	// OutputIndexTemplate and RenameMap derive destination index names from
+	// source index names when the output URL has no explicit index
+	OutputIndexTemplate string
+	RenameMap           string
+
+	// All backs up every index selected by Include, Exclude and
+	// IncludeSystem into a directory, one subdirectory per index
+	All           bool
+	Include       []string
+	Exclude       []string
+	IncludeSystem bool
+
+	// Existing decides what happens to templates, ingest pipelines,
+	// lifecycle policies, stored scripts, roles and role mappings that
+	// already exist on the destination ("fail", "skip" or "overwrite")
+	Existing string
+
+	// OpenSearch makes the lifecycle type copy the ISM policies of
+	// OpenSearch clusters instead of ILM policies
+	OpenSearch bool
+
+	// IncludePipelines copies the ingest pipelines referenced by the
+	// default_pipeline and final_pipeline settings of transferred indices,
+	// or writes them next to a backup of the settings, index and all types
+	IncludePipelines bool
+
+	// DryRun shows the changes the security type would make on the
+	// destination without applying them
+	DryRun bool
+
+	// Parallel is the number of indices whose documents are copied at the
+	// same time by Migrate
+	Parallel int
+
+	// Shards and Replicas override the source values when creating an
+	// index with the "index" type; nil keeps the source value
+	Shards   *int
+	Replicas *int
+
+	// Version is the elasticdump version recorded in backup manifests
+	Version string
 }
 
 // Client wraps Elasticsearch client with additional functionality
@@ -107,29 +353,68 @@ type Document struct {
 // Run executes the transfer operation
 func Run(config Config) error {
 	if config.Verbose {
-		fmt.Printf("Starting transfer from %s to %s\n", config.Input, config.Output)
-		fmt.Printf("Type: %s, Concurrency: %d, ScrollSize: %d\n",
+		logf(config, "Starting transfer from %s to %s\n", config.Input, config.Output)
+		logf(config, "Type: %s, Concurrency: %d, ScrollSize: %d\n",
 			config.Type, config.Concurrency, config.ScrollSize)
 	}
 
+	renamer, err := rename.New(config.OutputIndexTemplate, config.RenameMap)
+	if err != nil {
+		return err
+	}
+
 	sourceURL := getBaseURL(config.Input)
 	sourceClient, err := createClient(sourceURL, config.Username, config.Password)
 	if err != nil {
 		return fmt.Errorf("failed to create source client: %w", err)
 	}
 
+	if config.OpenSearch && (config.All || config.Type != "lifecycle") {
+		return fmt.Errorf("--opensearch is only supported with --type=lifecycle")
+	}
+
+	if (config.FileSize > 0 || config.FileDocs > 0) && (config.All || config.Type != "data") {
+		return fmt.Errorf("splitting output into parts is only supported for data exports to a file")
+	}
+
+	if config.All {
+		return backupCluster(sourceClient, config)
+	}
+
 	switch config.Type {
 	case "data":
-		return transferData(sourceClient, config)
+		return transferData(sourceClient, renamer, config)
+	case "all":
+		return transferAll(sourceClient, renamer, config)
 	case "mapping":
-		return transferMapping(sourceClient, config)
+		return transferMapping(sourceClient, renamer, config)
 	case "settings":
-		return transferSettings(sourceClient, config)
+		return transferSettings(sourceClient, renamer, config)
+	case "index":
+		return transferIndex(sourceClient, renamer, config)
+	case "aliases":
+		return transferAliases(sourceClient, renamer, config)
+	case "templates":
+		return transferTemplates(sourceClient, config)
+	case "pipelines":
+		return transferPipelines(sourceClient, config)
+	case "lifecycle":
+		return transferLifecycle(sourceClient, config)
+	case "scripts":
+		return transferScripts(sourceClient, config)
+	case "security":
+		return transferSecurity(sourceClient, config)
 	default:
 		return fmt.Errorf("unsupported transfer type: %s", config.Type)
 	}
 }
 
+// putOptions returns the options of putting definitions such as templates on
+// the destination
+func putOptions(config Config) cluster.PutOptions {
+	return cluster.PutOptions{Existing: config.Existing, Verbose: config.Verbose, Destination: config.Output}
+}
+
 // getBaseURL extracts the base URL from the input string
 func getBaseURL(s string) string {
 	// Remove any index name from the URL
@@ -158,16 +443,26 @@ func createClient(url, username, password string) (*Client, error) {
 }
 
 // transferData transfers documents between clusters
-func transferData(sourceClient *Client, config Config) error {
+func transferData(sourceClient *Client, renamer *rename.Renamer, config Config) error {
 	// Parse index from input URL
-	index := extractIndex(config.Input)
-	if index == "" {
+	expression := extractIndex(config.Input)
+	if expression == "" {
 		return fmt.Errorf("could not extract index from input URL")
 	}
 
+	// Data streams are read through their name rather than index by index,
+	// so that their backing indices are never recreated literally
+	sources, err := resolveSources(sourceClient, expression)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source indices: %w", err)
+	}
+
 	// Check if output is a file or Elasticsearch URL
 	if isFile(config.Output) {
-		return exportToFile(sourceClient, index, config)
+		return exportToFile(sourceClient, sources, config)
+	}
+	if config.FileSize > 0 || config.FileDocs > 0 {
+		return fmt.Errorf("splitting output into parts is only supported for data exports to a file")
 	}
 
 	// Transfer to another Elasticsearch cluster
@@ -177,89 +472,304 @@ func transferData(sourceClient *Client, config Config) error {
 		return fmt.Errorf("failed to create destination client: %w", err)
 	}
 
-	return transferBetweenClusters(sourceClient, destClient, index, config)
+	// Renamed data streams are checked before anything is created
+	for _, source := range sources {
+		if source.DataStream {
+			destIndex := destinationIndex(config, renamer, source.Name)
+			if err := checkDataStreamRename(sourceClient, destClient, source.Name, destIndex); err != nil {
+				return err
+			}
+		}
+	}
+
+	transferred := 0
+	for _, source := range sources {
+		limit, ok := remainingLimit(config.Limit, transferred)
+		if !ok {
+			break
+		}
+
+		indexConfig := config
+		indexConfig.Limit = limit
+
+		if source.DataStream {
+			destIndex := destinationIndex(config, renamer, source.Name)
+			if err := ensureDataStream(sourceClient, destClient, source.Name, destIndex, config); err != nil {
+				return err
+			}
+		}
+
+		count, err := transferBetweenClusters(sourceClient, destClient, source.Name, renamer, indexConfig)
+		if err != nil {
+			return fmt.Errorf("failed to transfer index %s: %w", source.Name, err)
+		}
+		transferred += count
+
+		if len(sources) > 1 {
+			fmt.Printf("Transferred %d documents from %s\n", count, source.Name)
+		}
+	}
+
+	if config.Verbose {
+		fmt.Printf("Transfer completed to %s (%d documents from %d indices)\n",
+			config.Output, transferred, len(sources))
+	}
+
+	return nil
+}
+
+// remainingLimit returns the limit left for the next index once done documents
+// have been processed, and false when the overall limit is already reached
+func remainingLimit(limit, done int) (int, bool) {
+	if limit == 0 {
+		return 0, true
+	}
+	if done >= limit {
+		return 0, false
+	}
+	return limit - done, true
 }
 
-// exportToFile exports data to a file
-func exportToFile(client *Client, index string, config Config) error {
-	file, err := os.Create(config.Output)
+// exportToFile exports data from one or more indices and data streams to a
+// single file, or to numbered parts of it when config.FileSize or
+// config.FileDocs is set
+func exportToFile(client *Client, sources []dataSource, config Config) error {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name
+	}
+
+	open, err := documentWriterFactory(client, names, config)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return err
+	}
+
+	var writer interface {
+		documentSink
+		Close() error
+	}
+	var file *outputFile
+	if config.FileSize > 0 || config.FileDocs > 0 {
+		if config.Output == backup.Stdio {
+			return fmt.Errorf("standard output cannot be split into parts")
+		}
+		writer = newPartWriter(config.Output, open, config)
+	} else {
+		file, err = createFile(config.Output, config)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+
+		writer, err = open(file)
+		if err != nil {
+			return err
+		}
 	}
-	defer file.Close()
 
+	exported := 0
+	for _, source := range sources {
+		limit, ok := remainingLimit(config.Limit, exported)
+		if !ok {
+			break
+		}
+
+		count, err := exportIndex(client, writer, source.Name, limit, source.DataStream, config)
+		if err != nil {
+			return fmt.Errorf("failed to export index %s: %w", source.Name, err)
+		}
+		exported += count
+
+		if len(sources) > 1 {
+			logf(config, "Exported %d documents from %s\n", count, source.Name)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+	}
+
+	// Sidecars and the manifest cannot go along with data written to standard
+	// output
+	if config.Output != backup.Stdio {
+		var files []backup.File
+		if parts, ok := writer.(*partWriter); ok {
+			files = parts.files
+		} else {
+			files = []backup.File{file.Sum()}
+		}
+
+		sidecars, err := writeSidecars(client, sources, config)
+		if err != nil {
+			return err
+		}
+
+		manifest, err := newFileManifest(client, config)
+		if err != nil {
+			return err
+		}
+		manifest.Indices = names
+		manifest.Query = matchAllQuery
+		manifest.Limit = config.Limit
+		manifest.Documents = exported
+		manifest.Format = config.Format
+		if err := writeFileManifest(manifest, append(files, sidecars...), config); err != nil {
+			return err
+		}
+	}
+
+	if config.Verbose {
+		logf(config, "Exported %d documents to %s\n", exported, config.Output)
+	}
+
+	return nil
+}
+
+// writeSidecars writes the mapping of the exported indices and the
+// definition of the exported data streams next to the output file, and
+// returns the checksums of the files written
+func writeSidecars(client *Client, sources []dataSource, config Config) ([]backup.File, error) {
+	var indices []string
+	var streams []*backup.DataStream
+	for _, source := range sources {
+		if !source.DataStream {
+			indices = append(indices, source.Name)
+			continue
+		}
+
+		stream, err := getDataStreamBackup(client, source.Name)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, stream)
+	}
+
+	var written []backup.File
+
+	// Keep the mappings next to the data so a restore into a bare cluster URL
+	// can create the indices before loading documents
+	if len(indices) > 0 {
+		mapping, err := getMapping(client, strings.Join(indices, ","))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get mapping: %w", err)
+		}
+		file, err := writeToFile(backup.MappingFile(config.Output), mapping, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write mapping file: %w", err)
+		}
+		written = append(written, file)
+	}
+
+	// Data streams are recreated from their templates instead
+	if len(streams) > 0 {
+		file, err := writeToFile(backup.DataStreamsFile(config.Output), streams, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write data streams file: %w", err)
+		}
+		written = append(written, file)
+	}
+
+	return written, nil
+}
+
+// exportIndex writes up to limit documents of a single index or data stream
+// to writer and returns the number of documents written. Documents of a data
+// stream are recorded under its name rather than their backing index.
+func exportIndex(client *Client, writer documentSink, index string, limit int, dataStream bool, config Config) (int, error) {
 	// Get total count for progress bar
 	total, err := getDocumentCount(client, index)
 	if err != nil {
-		return fmt.Errorf("failed to get document count: %w", err)
+		return 0, fmt.Errorf("failed to get document count: %w", err)
 	}
 
-	if config.Limit > 0 && config.Limit < total {
-		total = config.Limit
+	if limit > 0 && limit < total {
+		total = limit
 	}
 
 	var bar *progressbar.ProgressBar
 	if !config.Verbose {
-		bar = progressbar.DefaultBytes(int64(total), "Exporting documents")
+		bar = progressbar.DefaultBytes(int64(total), fmt.Sprintf("Exporting %s", index))
+	}
+
+	if dataStream {
+		writer.addDataStream(index)
+	}
+
+	exported, err := scrollDocuments(client, index, limit, min(config.ScrollSize, total), func(doc Document) error {
+		if dataStream {
+			doc.Index = index
+		}
+		if err := writer.Write(doc); err != nil {
+			return fmt.Errorf("failed to write document: %w", err)
+		}
+
+		if bar != nil {
+			bar.Add(1)
+		}
+		return nil
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	if bar != nil {
+		bar.Finish()
 	}
 
+	return exported, nil
+}
+
+// scrollDocuments scrolls through up to limit documents of an index (0 = no
+// limit) and calls fn for each of them. It returns the number of documents
+// passed to fn.
+func scrollDocuments(client *Client, index string, limit, scrollSize int, fn func(Document) error) (int, error) {
 	// Start scrolling
-	scrollSize := min(config.ScrollSize, total)
 	scrollID, docs, err := startScroll(client, index, scrollSize)
 	if err != nil {
-		return fmt.Errorf("failed to start scroll: %w", err)
+		return 0, fmt.Errorf("failed to start scroll: %w", err)
 	}
 
-	exported := 0
-	for len(docs) > 0 && (config.Limit == 0 || exported < config.Limit) {
+	processed := 0
+	for len(docs) > 0 && (limit == 0 || processed < limit) {
 		for _, doc := range docs {
-			if config.Limit > 0 && exported >= config.Limit {
+			if limit > 0 && processed >= limit {
 				break
 			}
 
-			if err := writeDocument(file, doc, config.Format); err != nil {
-				return fmt.Errorf("failed to write document: %w", err)
-			}
-
-			exported++
-			if bar != nil {
-				bar.Add(1)
+			if err := fn(doc); err != nil {
+				return processed, err
 			}
+			processed++
 		}
 
-		if config.Limit > 0 && exported >= config.Limit {
+		if limit > 0 && processed >= limit {
 			break
 		}
 
 		// Continue scrolling
 		scrollID, docs, err = continueScroll(client, scrollID)
 		if err != nil {
-			return fmt.Errorf("failed to continue scroll: %w", err)
+			return processed, fmt.Errorf("failed to continue scroll: %w", err)
 		}
 	}
 
-	if bar != nil {
-		bar.Finish()
-	}
-
-	if config.Verbose {
-		fmt.Printf("Exported %d documents to %s\n", exported, config.Output)
-	}
-
-	return nil
+	return processed, nil
 }
 
-// transferBetweenClusters transfers data between two Elasticsearch clusters
-func transferBetweenClusters(sourceClient, destClient *Client, index string, config Config) error {
-	destIndex := extractIndex(config.Output)
-	if destIndex == "" {
-		destIndex = index
-	}
+// transferBetweenClusters transfers data of a single index between two
+// Elasticsearch clusters and returns the number of documents indexed
+func transferBetweenClusters(sourceClient, destClient *Client, index string, renamer *rename.Renamer, config Config) (int, error) {
+	destIndex := destinationIndex(config, renamer, index)
 
 	// Get total count for progress bar
 	total, err := getDocumentCount(sourceClient, index)
 	if err != nil {
-		return fmt.Errorf("failed to get document count: %w", err)
+		return 0, fmt.Errorf("failed to get document count: %w", err)
 	}
 
 	if config.Limit > 0 && config.Limit < total {
@@ -268,21 +778,56 @@ func transferBetweenClusters(sourceClient, destClient *Client, index string, con
 
 	var bar *progressbar.ProgressBar
 	if !config.Verbose {
-		bar = progressbar.DefaultBytes(int64(total), "Transferring documents")
+		bar = progressbar.DefaultBytes(int64(total), fmt.Sprintf("Transferring %s", index))
+	}
+
+	indexed, err := copyDocuments(sourceClient, destClient, index, destIndex, bar, config)
+	if err != nil {
+		return indexed, err
 	}
 
-	// Create worker pool
-	docChan := make(chan Document, config.Concurrency*2)
+	if bar != nil {
+		bar.Finish()
+	}
+
+	return indexed, nil
+}
+
+// copyDocuments copies up to config.Limit documents of index into destIndex
+// using config.Concurrency workers, advancing bar (if any) for every document,
+// and returns the number of documents indexed
+func copyDocuments(sourceClient, destClient *Client, index, destIndex string, bar *progressbar.ProgressBar, config Config) (int, error) {
+	// Create worker pool; documents are produced on this goroutine, so at
+	// least one worker is needed to consume them
+	workers := max(config.Concurrency, 1)
+	docChan := make(chan Document, workers*2)
 	var wg sync.WaitGroup
+	var indexed atomic.Int64
+
+	// Data streams only accept documents with create semantics and an
+	// @timestamp field
+	write := indexDocument
+	dataStream, err := cluster.IsDataStream(destClient.API, destIndex)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check data stream %s: %w", destIndex, err)
+	}
+	if dataStream {
+		write = createDocument
+	}
+	var missing cluster.MissingTimestamps
 
 	// Start workers
-	for i := 0; i < config.Concurrency; i++ {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for doc := range docChan {
-				if err := indexDocument(destClient, destIndex, doc); err != nil {
+				if _, ok := doc.Source[cluster.TimestampField]; dataStream && !ok {
+					missing.Report(destIndex)
+				} else if err := write(destClient, destIndex, doc); err != nil {
 					fmt.Printf("Error indexing document %s: %v\n", doc.ID, err)
+				} else {
+					indexed.Add(1)
 				}
 				if bar != nil {
 					bar.Add(1)
@@ -291,67 +836,37 @@ func transferBetweenClusters(sourceClient, destClient *Client, index string, con
 		}()
 	}
 
-	// Start scrolling and send documents to workers
-	go func() {
-		defer close(docChan)
-
-		scrollID, docs, err := startScroll(sourceClient, index, config.ScrollSize)
-		if err != nil {
-			fmt.Printf("Failed to start scroll: %v\n", err)
-			return
-		}
-
-		transferred := 0
-		for len(docs) > 0 && (config.Limit == 0 || transferred < config.Limit) {
-			for _, doc := range docs {
-				if config.Limit > 0 && transferred >= config.Limit {
-					return
-				}
-
-				docChan <- doc
-				transferred++
-			}
-
-			if config.Limit > 0 && transferred >= config.Limit {
-				return
-			}
-
-			// Continue scrolling
-			scrollID, docs, err = continueScroll(sourceClient, scrollID)
-			if err != nil {
-				fmt.Printf("Failed to continue scroll: %v\n", err)
-				return
-			}
-		}
-	}()
+	// Scroll through the source and send documents to workers
+	_, scrollErr := scrollDocuments(sourceClient, index, config.Limit, config.ScrollSize, func(doc Document) error {
+		docChan <- doc
+		return nil
+	})
+	close(docChan)
 
 	wg.Wait()
 
-	if bar != nil {
-		bar.Finish()
-	}
-
-	if config.Verbose {
-		fmt.Printf("Transfer completed to %s\n", config.Output)
-	}
-
-	return nil
+	return int(indexed.Load()), scrollErr
 }
 
 // transferMapping transfers index mapping
-func transferMapping(sourceClient *Client, config Config) error {
-	index := extractIndex(config.Input)
-	if index == "" {
+func transferMapping(sourceClient *Client, renamer *rename.Renamer, config Config) error {
+	expression := extractIndex(config.Input)
+	if expression == "" {
 		return fmt.Errorf("could not extract index from input URL")
 	}
 
-	mapping, err := getMapping(sourceClient, index)
+	indices, err := resolveIndices(sourceClient, expression, config)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source indices: %w", err)
+	}
+
+	mapping, err := getMapping(sourceClient, strings.Join(indices, ","))
 	if err != nil {
 		return fmt.Errorf("failed to get mapping: %w", err)
 	}
 
 	if isFile(config.Output) {
-		return writeToFile(config.Output, mapping)
+		return writeFileBackup(sourceClient, mapping, nil, config)
 	}
 
 	destURL := getBaseURL(config.Output)
@@ -360,28 +875,45 @@ func transferMapping(sourceClient *Client, config Config) error {
 		return fmt.Errorf("failed to create destination client: %w", err)
 	}
 
-	destIndex := extractIndex(config.Output)
-	if destIndex == "" {
-		destIndex = index
+	for _, index := range indices {
+		destIndex := destinationIndex(config, renamer, index)
+
+		if err := putMapping(destClient, destIndex, forIndex(mapping, index, destIndex)); err != nil {
+			return fmt.Errorf("failed to put mapping for %s: %w", destIndex, err)
+		}
 	}
 
-	return putMapping(destClient, destIndex, mapping)
+	return nil
 }
 
 // transferSettings transfers index settings
-func transferSettings(sourceClient *Client, config Config) error {
-	index := extractIndex(config.Input)
-	if index == "" {
+func transferSettings(sourceClient *Client, renamer *rename.Renamer, config Config) error {
+	expression := extractIndex(config.Input)
+	if expression == "" {
 		return fmt.Errorf("could not extract index from input URL")
 	}
 
-	settings, err := getSettings(sourceClient, index)
+	indices, err := resolveIndices(sourceClient, expression, config)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source indices: %w", err)
+	}
+
+	settings, err := getSettings(sourceClient, strings.Join(indices, ","))
 	if err != nil {
 		return fmt.Errorf("failed to get settings: %w", err)
 	}
 
+	var indexSettings []map[string]interface{}
+	for _, index := range indices {
+		indexSettings = append(indexSettings, indexdef.Entry(settings, index, "settings"))
+	}
+
 	if isFile(config.Output) {
-		return writeToFile(config.Output, settings)
+		sidecars, err := writePipelinesSidecar(sourceClient, indexSettings, config)
+		if err != nil {
+			return err
+		}
+		return writeFileBackup(sourceClient, settings, sidecars, config)
 	}
 
 	destURL := getBaseURL(config.Output)
@@ -390,12 +922,19 @@ func transferSettings(sourceClient *Client, config Config) error {
 		return fmt.Errorf("failed to create destination client: %w", err)
 	}
 
-	destIndex := extractIndex(config.Output)
-	if destIndex == "" {
-		destIndex = index
+	if err := copyIndexDependencies(sourceClient, destClient, indexSettings, config); err != nil {
+		return err
 	}
 
-	return putSettings(destClient, destIndex, settings)
+	for _, index := range indices {
+		destIndex := destinationIndex(config, renamer, index)
+
+		if err := putSettings(destClient, destIndex, forIndex(settings, index, destIndex)); err != nil {
+			return fmt.Errorf("failed to put settings for %s: %w", destIndex, err)
+		}
+	}
+
+	return nil
 }
 
 // Helper functions
@@ -409,6 +948,35 @@ func extractIndex(url string) string {
 	return ""
 }
 
+// destinationIndex returns the index that data of the source index is written
+// to: the index in the output URL if there is one, otherwise the source name
+// passed through renamer
+func destinationIndex(config Config, renamer *rename.Renamer, index string) string {
+	if destIndex := extractIndex(config.Output); destIndex != "" {
+		return destIndex
+	}
+
+	return renamer.Apply(index)
+}
+
+// forIndex returns the entry of a per-index response body (as returned by the
+// get mapping and get settings APIs) for source, keyed under dest instead
+func forIndex(body map[string]interface{}, source, dest string) map[string]interface{} {
+	return map[string]interface{}{dest: body[source]}
+}
+
+// logf prints a progress message, to standard error when standard output
+// carries the exported data
+func logf(config Config, format string, a ...interface{}) {
+	var writer io.Writer = os.Stdout
+	if config.Output == backup.Stdio {
+		writer = os.Stderr
+	}
+	fmt.Fprintf(writer, format, a...)
+}
+
+// isFile reports whether path is a file rather than an Elasticsearch URL;
+// backup.Stdio is a file standing for standard output
 func isFile(path string) bool {
 	return !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://")
 }
@@ -524,10 +1092,17 @@ func parseScrollResponse(body io.Reader) (string, []Document, error) {
 	return scrollID, docs, nil
 }
 
+// writeDocument encodes a single document: a JSON value for the json format,
+// to be framed as an array element by documentWriter, or a line for ndjson
 func writeDocument(writer io.Writer, doc Document, format string) error {
 	switch format {
 	case "json":
-		return json.NewEncoder(writer).Encode(doc)
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(data)
+		return err
 	case "ndjson":
 		data, err := json.Marshal(doc)
 		if err != nil {
@@ -540,7 +1115,20 @@ func writeDocument(writer io.Writer, doc Document, format string) error {
 	}
 }
 
+// indexDocument indexes doc into index, replacing a document with the same id
 func indexDocument(client *Client, index string, doc Document) error {
+	return putDocument(client, index, doc, "")
+}
+
+// createDocument indexes doc into index with create semantics, as required
+// by data streams
+func createDocument(client *Client, index string, doc Document) error {
+	return putDocument(client, index, doc, "create")
+}
+
+// putDocument indexes doc into index with the given op_type, or the default
+// one when empty
+func putDocument(client *Client, index string, doc Document, opType string) error {
 	data, err := json.Marshal(doc.Source)
 	if err != nil {
 		return err
@@ -552,6 +1140,7 @@ func indexDocument(client *Client, index string, doc Document) error {
 		strings.NewReader(string(data)),
 		func(r *esapi.IndexRequest) {
 			r.DocumentID = doc.ID
+			r.OpType = opType
 			r.Refresh = "false"
 		},
 	)
@@ -579,6 +1168,10 @@ func getMapping(client *Client, index string) (map[string]interface{}, error) {
 	}
 	defer res.Body.Close()
 
+	if res.IsError() {
+		return nil, fmt.Errorf("get mapping failed: %s", res.String())
+	}
+
 	var result map[string]interface{}
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
 		return nil, err
@@ -631,6 +1224,10 @@ func getSettings(client *Client, index string) (map[string]interface{}, error) {
 	}
 	defer res.Body.Close()
 
+	if res.IsError() {
+		return nil, fmt.Errorf("get settings failed: %s", res.String())
+	}
+
 	var result map[string]interface{}
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
 		return nil, err
@@ -674,12 +1271,21 @@ func putSettings(client *Client, index string, settings map[string]interface{})
 	return nil
 }
 
-func writeToFile(filename string, data interface{}) error {
-	file, err := os.Create(filename)
+// writeToFile writes data as JSON to a file, compressed as configured, and
+// returns its checksum
+func writeToFile(filename string, data interface{}, config Config) (backup.File, error) {
+	file, err := createFile(filename, config)
 	if err != nil {
-		return err
+		return backup.File{}, err
 	}
 	defer file.Close()
 
-	return json.NewEncoder(file).Encode(data)
+	if err := json.NewEncoder(file).Encode(data); err != nil {
+		return backup.File{}, err
+	}
+
+	if err := file.Close(); err != nil {
+		return backup.File{}, err
+	}
+	return file.Sum(), nil
 }
@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/lilmonk/elasticdump/internal/rename"
 )
 
 // MockElasticsearchAPI implements ElasticsearchAPI for testing
@@ -19,10 +23,81 @@ type MockElasticsearchAPI struct {
 	IndexResponse    *esapi.Response
 	MappingResponse  *esapi.Response
 	SettingsResponse *esapi.Response
+	ResolveResponse  *esapi.Response
+	AliasResponse    *esapi.Response
+
+	// ExistingIndices lists the indices reported as existing
+	ExistingIndices map[string]bool
+	// CreatedBodies records the create index request bodies by index
+	CreatedBodies map[string]string
+	// AliasRequests records the bodies sent to IndicesUpdateAliases
+	AliasRequests []string
+
+	// ComponentTemplates, IndexTemplates and LegacyTemplates hold the
+	// template bodies returned by the get template APIs by name
+	ComponentTemplates map[string]string
+	IndexTemplates     map[string]string
+	LegacyTemplates    map[string]string
+	// ExistingTemplates lists the templates reported as existing, as kind/name
+	ExistingTemplates map[string]bool
+	// PutTemplates records the templates put, as kind/name, in order
+	PutTemplates []string
+
+	// Pipelines holds the ingest pipeline bodies returned by IngestGetPipeline by id
+	Pipelines map[string]string
+	// PutPipelines records the ids of the ingest pipelines put, in order
+	PutPipelines []string
+
+	// Policies holds the ILM policies returned by ILMGetLifecycle by name
+	Policies map[string]string
+	// PutPolicies records the names of the ILM policies put, in order
+	PutPolicies []string
+
+	// Scripts holds the stored scripts of the cluster state by id
+	Scripts map[string]string
+	// PutScripts records the ids of the stored scripts put, in order
+	PutScripts []string
+
+	// Roles and RoleMappings hold the bodies returned by the get role and get
+	// role mapping APIs by name
+	Roles        map[string]string
+	RoleMappings map[string]string
+	// PutRoles and PutRoleMappings record the names put, in order
+	PutRoles        []string
+	PutRoleMappings []string
+
+	// DataStreams holds the data streams reported by IndicesGetDataStream,
+	// mapped to the name of their index template
+	DataStreams map[string]string
+	// CreatedDataStreams records the data streams created, in order
+	CreatedDataStreams []string
+	// OpTypes records the op_type of the last document written to each index
+	OpTypes map[string]string
+
+	// Counts holds the document counts returned by Count by index, taking
+	// precedence over CountResponse
+	Counts map[string]int
+	// Sources holds the _source of the one document returned by Search by
+	// index, taking precedence over the default search response
+	Sources map[string]string
+	// DeletedIndices and DeletedDataStreams record the names deleted
+	DeletedIndices     []string
+	DeletedDataStreams []string
+	// Refreshed records the indices refreshed
+	Refreshed []string
+
+	mu sync.Mutex
 }
 
 // Count implements ElasticsearchAPI for testing
 func (m *MockElasticsearchAPI) Count(o ...func(*esapi.CountRequest)) (*esapi.Response, error) {
+	if m.Counts != nil {
+		req := &esapi.CountRequest{}
+		for _, f := range o {
+			f(req)
+		}
+		return createMockCountResponse(m.Counts[strings.Join(req.Index, ",")], false), nil
+	}
 	if m.CountResponse != nil {
 		return m.CountResponse, nil
 	}
@@ -31,6 +106,14 @@ func (m *MockElasticsearchAPI) Count(o ...func(*esapi.CountRequest)) (*esapi.Res
 
 // Search implements ElasticsearchAPI for testing
 func (m *MockElasticsearchAPI) Search(o ...func(*esapi.SearchRequest)) (*esapi.Response, error) {
+	req := &esapi.SearchRequest{}
+	for _, f := range o {
+		f(req)
+	}
+	if source, ok := m.Sources[strings.Join(req.Index, ",")]; ok {
+		body := fmt.Sprintf(`{"_scroll_id": "test-scroll-id", "hits": {"hits": [{"_index": %q, "_id": "1", "_source": %s}]}}`, strings.Join(req.Index, ","), source)
+		return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
 	if m.SearchResponse != nil {
 		return m.SearchResponse, nil
 	}
@@ -47,6 +130,7 @@ func (m *MockElasticsearchAPI) Scroll(o ...func(*esapi.ScrollRequest)) (*esapi.R
 
 // Index implements ElasticsearchAPI for testing
 func (m *MockElasticsearchAPI) Index(index string, body io.Reader, o ...func(*esapi.IndexRequest)) (*esapi.Response, error) {
+	m.recordOpType(index, o)
 	if m.IndexResponse != nil {
 		return m.IndexResponse, nil
 	}
@@ -79,6 +163,312 @@ func (m *MockElasticsearchAPI) IndicesPutSettings(body io.Reader, o ...func(*esa
 	return createMockSuccessResponse(), nil
 }
 
+// IndicesResolveIndex implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesResolveIndex(name []string, o ...func(*esapi.IndicesResolveIndexRequest)) (*esapi.Response, error) {
+	if m.ResolveResponse != nil {
+		return m.ResolveResponse, nil
+	}
+	return createMockResolveResponse(), nil
+}
+
+// IndicesGetAlias implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesGetAlias(o ...func(*esapi.IndicesGetAliasRequest)) (*esapi.Response, error) {
+	if m.AliasResponse != nil {
+		return m.AliasResponse, nil
+	}
+	return createMockAliasResponse(), nil
+}
+
+// IndicesExists implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesExists(index []string, o ...func(*esapi.IndicesExistsRequest)) (*esapi.Response, error) {
+	for _, name := range index {
+		if !m.ExistingIndices[name] {
+			return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+	}
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+// IndicesCreate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesCreate(index string, o ...func(*esapi.IndicesCreateRequest)) (*esapi.Response, error) {
+	req := &esapi.IndicesCreateRequest{Index: index}
+	for _, f := range o {
+		f(req)
+	}
+
+	if m.CreatedBodies == nil {
+		m.CreatedBodies = make(map[string]string)
+	}
+	body := ""
+	if req.Body != nil {
+		data, _ := io.ReadAll(req.Body)
+		body = string(data)
+	}
+	m.CreatedBodies[index] = body
+
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesUpdateAliases implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesUpdateAliases(body io.Reader, o ...func(*esapi.IndicesUpdateAliasesRequest)) (*esapi.Response, error) {
+	data, _ := io.ReadAll(body)
+	m.AliasRequests = append(m.AliasRequests, string(data))
+	return createMockSuccessResponse(), nil
+}
+
+// ClusterGetComponentTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ClusterGetComponentTemplate(o ...func(*esapi.ClusterGetComponentTemplateRequest)) (*esapi.Response, error) {
+	req := &esapi.ClusterGetComponentTemplateRequest{}
+	for _, f := range o {
+		f(req)
+	}
+	return createMockTemplatesResponse(m.ComponentTemplates, strings.Join(req.Name, ","), func(name, body string) string {
+		return fmt.Sprintf(`{"name": %q, "component_template": %s}`, name, body)
+	}, `{"component_templates": [%s]}`), nil
+}
+
+// ClusterPutComponentTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ClusterPutComponentTemplate(name string, body io.Reader, o ...func(*esapi.ClusterPutComponentTemplateRequest)) (*esapi.Response, error) {
+	m.PutTemplates = append(m.PutTemplates, "component/"+name)
+	return createMockSuccessResponse(), nil
+}
+
+// ClusterExistsComponentTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ClusterExistsComponentTemplate(name string, o ...func(*esapi.ClusterExistsComponentTemplateRequest)) (*esapi.Response, error) {
+	return createMockExistsResponse(m.ExistingTemplates["component/"+name]), nil
+}
+
+// IndicesGetIndexTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesGetIndexTemplate(o ...func(*esapi.IndicesGetIndexTemplateRequest)) (*esapi.Response, error) {
+	req := &esapi.IndicesGetIndexTemplateRequest{}
+	for _, f := range o {
+		f(req)
+	}
+	return createMockTemplatesResponse(m.IndexTemplates, req.Name, func(name, body string) string {
+		return fmt.Sprintf(`{"name": %q, "index_template": %s}`, name, body)
+	}, `{"index_templates": [%s]}`), nil
+}
+
+// IndicesPutIndexTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesPutIndexTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutIndexTemplateRequest)) (*esapi.Response, error) {
+	m.PutTemplates = append(m.PutTemplates, "index/"+name)
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesExistsIndexTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesExistsIndexTemplate(name string, o ...func(*esapi.IndicesExistsIndexTemplateRequest)) (*esapi.Response, error) {
+	return createMockExistsResponse(m.ExistingTemplates["index/"+name]), nil
+}
+
+// IndicesGetTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesGetTemplate(o ...func(*esapi.IndicesGetTemplateRequest)) (*esapi.Response, error) {
+	req := &esapi.IndicesGetTemplateRequest{}
+	for _, f := range o {
+		f(req)
+	}
+	return createMockTemplatesResponse(m.LegacyTemplates, strings.Join(req.Name, ","), func(name, body string) string {
+		return fmt.Sprintf(`%q: %s`, name, body)
+	}, `{%s}`), nil
+}
+
+// IndicesPutTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesPutTemplate(name string, body io.Reader, o ...func(*esapi.IndicesPutTemplateRequest)) (*esapi.Response, error) {
+	m.PutTemplates = append(m.PutTemplates, "legacy/"+name)
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesExistsTemplate implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesExistsTemplate(name []string, o ...func(*esapi.IndicesExistsTemplateRequest)) (*esapi.Response, error) {
+	return createMockExistsResponse(m.ExistingTemplates["legacy/"+strings.Join(name, ",")]), nil
+}
+
+// IngestGetPipeline implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IngestGetPipeline(o ...func(*esapi.IngestGetPipelineRequest)) (*esapi.Response, error) {
+	req := &esapi.IngestGetPipelineRequest{}
+	for _, f := range o {
+		f(req)
+	}
+
+	var entries []string
+	for _, pattern := range strings.Split(req.PipelineID, ",") {
+		for id, body := range m.Pipelines {
+			if matched, _ := path.Match(pattern, id); matched {
+				entries = append(entries, fmt.Sprintf("%q: %s", id, body))
+			}
+		}
+	}
+
+	if len(entries) == 0 && !strings.Contains(req.PipelineID, "*") {
+		return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+	}
+
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader("{" + strings.Join(entries, ",") + "}")),
+	}, nil
+}
+
+// IngestPutPipeline implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IngestPutPipeline(id string, body io.Reader, o ...func(*esapi.IngestPutPipelineRequest)) (*esapi.Response, error) {
+	m.PutPipelines = append(m.PutPipelines, id)
+	return createMockSuccessResponse(), nil
+}
+
+// ILMGetLifecycle implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ILMGetLifecycle(o ...func(*esapi.ILMGetLifecycleRequest)) (*esapi.Response, error) {
+	var entries []string
+	for name, policy := range m.Policies {
+		entries = append(entries, fmt.Sprintf(`%q: {"version": 1, "modified_date": "2024-01-01T00:00:00Z", "policy": %s, "in_use_by": {"indices": []}}`, name, policy))
+	}
+
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader("{" + strings.Join(entries, ",") + "}")),
+	}, nil
+}
+
+// ILMPutLifecycle implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ILMPutLifecycle(policy string, o ...func(*esapi.ILMPutLifecycleRequest)) (*esapi.Response, error) {
+	m.PutPolicies = append(m.PutPolicies, policy)
+	return createMockSuccessResponse(), nil
+}
+
+// ClusterState implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) ClusterState(o ...func(*esapi.ClusterStateRequest)) (*esapi.Response, error) {
+	body := `{}`
+	if len(m.Scripts) > 0 {
+		var entries []string
+		for id, script := range m.Scripts {
+			entries = append(entries, fmt.Sprintf("%q: %s", id, script))
+		}
+		body = `{"metadata": {"stored_scripts": {` + strings.Join(entries, ",") + `}}}`
+	}
+
+	return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+// PutScript implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) PutScript(id string, body io.Reader, o ...func(*esapi.PutScriptRequest)) (*esapi.Response, error) {
+	m.PutScripts = append(m.PutScripts, id)
+	return createMockSuccessResponse(), nil
+}
+
+// SecurityGetRole implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) SecurityGetRole(o ...func(*esapi.SecurityGetRoleRequest)) (*esapi.Response, error) {
+	return createMockObjectsResponse(m.Roles), nil
+}
+
+// SecurityPutRole implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) SecurityPutRole(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleRequest)) (*esapi.Response, error) {
+	m.PutRoles = append(m.PutRoles, name)
+	return createMockSuccessResponse(), nil
+}
+
+// SecurityGetRoleMapping implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) SecurityGetRoleMapping(o ...func(*esapi.SecurityGetRoleMappingRequest)) (*esapi.Response, error) {
+	return createMockObjectsResponse(m.RoleMappings), nil
+}
+
+// SecurityPutRoleMapping implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) SecurityPutRoleMapping(name string, body io.Reader, o ...func(*esapi.SecurityPutRoleMappingRequest)) (*esapi.Response, error) {
+	m.PutRoleMappings = append(m.PutRoleMappings, name)
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesGetDataStream implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesGetDataStream(o ...func(*esapi.IndicesGetDataStreamRequest)) (*esapi.Response, error) {
+	req := &esapi.IndicesGetDataStreamRequest{}
+	for _, f := range o {
+		f(req)
+	}
+
+	var entries []string
+	for _, name := range req.Name {
+		if template, ok := m.DataStreams[name]; ok {
+			entries = append(entries, fmt.Sprintf(`{"name": %q, "template": %q, "timestamp_field": {"name": "@timestamp"}}`, name, template))
+		}
+	}
+	if len(entries) == 0 {
+		return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(`{"error": "no such index"}`))}, nil
+	}
+
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(`{"data_streams": [` + strings.Join(entries, ",") + `]}`)),
+	}, nil
+}
+
+// IndicesCreateDataStream implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesCreateDataStream(name string, o ...func(*esapi.IndicesCreateDataStreamRequest)) (*esapi.Response, error) {
+	if m.DataStreams == nil {
+		m.DataStreams = make(map[string]string)
+	}
+	m.DataStreams[name] = ""
+	m.CreatedDataStreams = append(m.CreatedDataStreams, name)
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesDelete implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesDelete(index []string, o ...func(*esapi.IndicesDeleteRequest)) (*esapi.Response, error) {
+	m.DeletedIndices = append(m.DeletedIndices, index...)
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesDeleteDataStream implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesDeleteDataStream(name []string, o ...func(*esapi.IndicesDeleteDataStreamRequest)) (*esapi.Response, error) {
+	for _, n := range name {
+		delete(m.DataStreams, n)
+	}
+	m.DeletedDataStreams = append(m.DeletedDataStreams, name...)
+	return createMockSuccessResponse(), nil
+}
+
+// IndicesRefresh implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) IndicesRefresh(o ...func(*esapi.IndicesRefreshRequest)) (*esapi.Response, error) {
+	req := &esapi.IndicesRefreshRequest{}
+	for _, f := range o {
+		f(req)
+	}
+	m.Refreshed = append(m.Refreshed, req.Index...)
+	return createMockSuccessResponse(), nil
+}
+
+// Info implements ElasticsearchAPI for testing
+func (m *MockElasticsearchAPI) Info(o ...func(*esapi.InfoRequest)) (*esapi.Response, error) {
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(`{"cluster_name": "test-cluster", "version": {"number": "8.19.0"}}`)),
+	}, nil
+}
+
+// recordOpType records the op_type of an index request
+func (m *MockElasticsearchAPI) recordOpType(index string, o []func(*esapi.IndexRequest)) {
+	req := &esapi.IndexRequest{}
+	for _, f := range o {
+		f(req)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.OpTypes == nil {
+		m.OpTypes = make(map[string]string)
+	}
+	m.OpTypes[index] = req.OpType
+}
+
+// createMockObjectsResponse returns a JSON object of the given bodies by name
+func createMockObjectsResponse(objects map[string]string) *esapi.Response {
+	var entries []string
+	for name, body := range objects {
+		entries = append(entries, fmt.Sprintf("%q: %s", name, body))
+	}
+
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader("{" + strings.Join(entries, ",") + "}")),
+	}
+}
+
 // Helper functions to create mock responses
 func createMockCountResponse(count int, hasError bool) *esapi.Response {
 	var body io.ReadCloser
@@ -183,6 +573,70 @@ func createMockSettingsResponse() *esapi.Response {
 	}
 }
 
+func createMockResolveResponse() *esapi.Response {
+	responseBody := `{
+		"indices": [
+			{
+				"name": "test-index",
+				"attributes": ["open"]
+			}
+		],
+		"aliases": [],
+		"data_streams": []
+	}`
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(responseBody)),
+	}
+}
+
+func createMockAliasResponse() *esapi.Response {
+	responseBody := `{
+		"test-index": {
+			"aliases": {
+				"test-alias": {}
+			}
+		}
+	}`
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(responseBody)),
+	}
+}
+
+// createMockTemplatesResponse renders the templates whose names match pattern
+// like a get template API, answering 404 when an exact name is missing
+func createMockTemplatesResponse(bodies map[string]string, pattern string, entry func(name, body string) string, wrapper string) *esapi.Response {
+	var names []string
+	for name := range bodies {
+		if matched, _ := path.Match(pattern, name); matched {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 && !strings.Contains(pattern, "*") {
+		return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(`{}`))}
+	}
+
+	entries := make([]string, len(names))
+	for i, name := range names {
+		entries[i] = entry(name, bodies[name])
+	}
+
+	return &esapi.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(wrapper, strings.Join(entries, ",")))),
+	}
+}
+
+func createMockExistsResponse(exists bool) *esapi.Response {
+	if exists {
+		return &esapi.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}
+	}
+	return &esapi.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader(""))}
+}
+
 func createMockSuccessResponse() *esapi.Response {
 	responseBody := `{"acknowledged": true}`
 	return &esapi.Response{
@@ -247,6 +701,62 @@ func TestExtractIndex(t *testing.T) {
 	}
 }
 
+func TestDestinationIndex(t *testing.T) {
+	renameMap := t.TempDir() + "/rename.json"
+	if err := os.WriteFile(renameMap, []byte(`{"prod-*": "staging-*"}`), 0644); err != nil {
+		t.Fatalf("Failed to write rename map: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		index    string
+		expected string
+	}{
+		{
+			name:     "explicit output index",
+			config:   Config{Output: "http://localhost:9200/dest", OutputIndexTemplate: "{index}-v2"},
+			index:    "source",
+			expected: "dest",
+		},
+		{
+			name:     "same name without output index",
+			config:   Config{Output: "http://localhost:9200"},
+			index:    "source",
+			expected: "source",
+		},
+		{
+			name:     "output index template",
+			config:   Config{Output: "http://localhost:9200", OutputIndexTemplate: "{index}-v2"},
+			index:    "source",
+			expected: "source-v2",
+		},
+		{
+			name:     "rename map",
+			config:   Config{Output: "http://localhost:9200", RenameMap: renameMap},
+			index:    "prod-orders",
+			expected: "staging-orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renamer, err := rename.New(tt.config.OutputIndexTemplate, tt.config.RenameMap)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result := destinationIndex(tt.config, renamer, tt.index); result != tt.expected {
+				t.Errorf("destinationIndex(%q) = %q, want %q", tt.index, result, tt.expected)
+			}
+		})
+	}
+
+	// The rename map is read once, before connecting to any cluster
+	if err := Run(Config{Input: "http://localhost:9200/source", Output: "http://localhost:9200", Type: "data", RenameMap: "/nonexistent.json"}); err == nil {
+		t.Error("Expected error for missing rename map")
+	}
+}
+
 func TestIsFile(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -582,7 +1092,7 @@ func TestTypeValidation(t *testing.T) {
 }
 
 func TestFormatValidation(t *testing.T) {
-	validFormats := []string{"json", "ndjson"}
+	validFormats := []string{"json", "ndjson", "bulk"}
 
 	for _, format := range validFormats {
 		t.Run("format_"+format, func(t *testing.T) {
@@ -744,7 +1254,7 @@ func TestWriteToFile(t *testing.T) {
 		"number": 42,
 	}
 
-	err := writeToFile(tempFile, data)
+	_, err := writeToFile(tempFile, data, Config{})
 	if err != nil {
 		t.Errorf("writeToFile failed: %v", err)
 	}